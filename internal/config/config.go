@@ -1,8 +1,12 @@
 package config
 
 import (
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
-	"strconv"
+	"slices"
+	"sort"
 	"time"
 )
 
@@ -11,77 +15,602 @@ type Config struct {
 	Storage   StorageConfig
 	Ingestion IngestionConfig
 	Server    ServerConfig
+
+	// MigrateTarget is the destination of the migrate command, which copies
+	// every post out of Storage. Settings without a MIGRATE_TARGET_*
+	// override are shared with Storage.
+	MigrateTarget    StorageConfig
+	MigrateBatchSize int
+
+	// Warnings lists malformed environment values that fell back to defaults
+	Warnings []error
 }
 
 // StorageConfig holds storage-related configuration
 type StorageConfig struct {
-	Type        string // "dynamodb", "mongodb", "postgresql"
+	Type        string // "dynamodb", "mongodb", "postgresql", "stdout"
 	Region      string // For AWS DynamoDB
 	TableName   string
 	Endpoint    string // Custom endpoint for local testing
 	MongoDBURI  string
 	PostgresURI string
+	SoftDelete  bool // Mark posts as deleted instead of removing them
+
+	// CompositeKey keys DynamoDB posts by source (partition) and id (sort),
+	// so sources with overlapping IDs don't collide. Lookups and deletes by
+	// ID must then name the source.
+	CompositeKey bool
+
+	// VersionPosts increments a post's version on each store and rejects
+	// writes based on a stale version
+	VersionPosts bool
+
+	// ScanSegments scans the table for GetPosts and exports as this many
+	// parallel DynamoDB Scan segments (1 scans sequentially)
+	ScanSegments int
+
+	// SourceTables routes posts to a table per source name; sources not
+	// listed are stored in TableName
+	SourceTables map[string]string
+
+	// Large body offloading (DynamoDB items are capped at 400KB)
+	OffloadLargeBodies bool
+	OffloadThreshold   int // Body size in bytes above which bodies go to S3
+	OffloadBucket      string
+
+	// Body compression for large, repetitive bodies
+	CompressBodies    bool
+	CompressThreshold int // Body size in bytes above which bodies are compressed
+
+	// Bound concurrent storage operations (0 disables). Writes may hold at
+	// most WriteWeight/(ReadWeight+WriteWeight) of the slots so ingestion
+	// bursts don't starve API reads.
+	MaxConcurrency int
+	ReadWeight     int
+	WriteWeight    int
+
+	// Audit every write to AuditSink: "file" appends JSON lines to
+	// AuditFile, "dynamodb" writes to AuditTable (empty disables)
+	AuditSink  string
+	AuditFile  string
+	AuditTable string
+
+	// SinkFormat "cloudevents" wraps each line the stdout sink writes in a
+	// CloudEvents 1.0 envelope with source EventSource and type
+	// PostEventType or CommentEventType (empty writes bare records)
+	SinkFormat       string
+	EventSource      string
+	PostEventType    string
+	CommentEventType string
+
+	// Buffer stored posts, flushing once WriteBufferSize are buffered or
+	// every WriteBufferInterval, whichever comes first (0 size disables)
+	WriteBufferSize     int
+	WriteBufferInterval time.Duration
+
+	// Startup retries while the backend is briefly unavailable
+	InitRetries int
+	InitBackoff time.Duration // Delay before the first retry; doubles each attempt
+
+	// Retries of API reads failing with a transient error such as
+	// throttling (0 disables)
+	ReadRetryCount   int
+	ReadRetryBackoff time.Duration // Delay before the first retry; doubles each attempt
+
+	// Archive each ingestion run's posts as a Parquet file under ArchivePrefix
+	// in ArchiveBucket, partitioned by date (empty bucket disables)
+	ArchiveBucket string
+	ArchivePrefix string
+}
+
+// WeightedEndpoint is an upstream mirror and its share of fetches
+type WeightedEndpoint struct {
+	URL    string
+	Weight int
 }
 
 // IngestionConfig holds ingestion-related configuration
 type IngestionConfig struct {
 	APIEndpoint string
 	Interval    time.Duration
+	MaxCycles   int // Stop after this many cycles, e.g. 1 for a one-shot job (0 = run until stopped)
 	Timeout     time.Duration
 	RetryCount  int
+	QueryParams map[string]string // Static query parameters appended to every request
+	JSONPath    string            // Selects the posts array within the response, e.g. "$.result.items[*]"
+
+	// APIEndpoints lists equivalent mirrors to spread fetches across by
+	// weight. When set it replaces APIEndpoint as the primary upstream.
+	APIEndpoints []WeightedEndpoint
+
+	// EndpointProbeInterval demotes a failing mirror behind the healthy ones
+	// for this long before probing it again (0 disables)
+	EndpointProbeInterval time.Duration
+
+	// FallbackAPIEndpoint is tried when the primary upstream fails all retries
+	FallbackAPIEndpoint string
+
+	// Fallback fetch settings; 0 uses Timeout and RetryCount
+	FallbackTimeout    time.Duration
+	FallbackRetryCount int
+
+	// Source names recorded on posts and on metrics and log lines, for the
+	// primary upstream (with its mirrors) and the fallback
+	SourceName         string
+	FallbackSourceName string
+
+	// RefuseRedirectDowngrade stops upstream redirects from https to http
+	RefuseRedirectDowngrade bool
+
+	// CertPins are hex SHA-256 fingerprints of upstream certificates. When
+	// set, TLS connections to servers whose chain has none of them are
+	// refused, even if the chain is otherwise valid.
+	CertPins []string
+
+	// StartupHealthCheck probes the upstream before the first cycle, failing
+	// Start if it's unreachable
+	StartupHealthCheck bool
+
+	// SlowFetchThreshold flags successful fetches slower than this (0 disables)
+	SlowFetchThreshold time.Duration
+
+	// MaxRunDuration bounds a whole run, including retries (0 disables)
+	MaxRunDuration time.Duration
+
+	// MaxStalePeriod is a dead-man switch: the process exits non-zero once
+	// no cycle has succeeded for this long, so an orchestrator restarts it
+	// (0 disables)
+	MaxStalePeriod time.Duration
+
+	// IngestedAtOverride, when set, is recorded as every post's IngestedAt
+	// instead of the current time, e.g. for reproducible replays
+	IngestedAtOverride *time.Time
+
+	// Response headers reporting the upstream's rate-limit budget (empty disables)
+	RateLimitRemainingHeader string
+	RateLimitLimitHeader     string
+
+	// Fetch the ID space [ShardIDStart, ShardIDEnd] as ShardCount ranges in
+	// parallel, bounding each with the ShardStartParam/ShardEndParam query
+	// parameters (inclusive). Disabled unless ShardCount > 1 and ShardIDEnd is set.
+	ShardCount      int
+	ShardIDStart    int
+	ShardIDEnd      int
+	ShardStartParam string
+	ShardEndParam   string
+
+	// GlobalFetchConcurrency caps the upstream requests in flight at once,
+	// across shards, sources and comment fetches (0 is unlimited)
+	GlobalFetchConcurrency int
+
+	StoreRetryCount  int  // Attempts at storing a batch before giving up
+	MaxBatchPerCycle int  // Store a cycle's posts in chunks of this size (0 = all at once)
+	SortByID         bool // Store posts in ID order so batch boundaries are reproducible
+
+	// CycleRetryCount retries storing a cycle's fetched posts, this many
+	// times per cycle, once a batch has used up its StoreRetryCount attempts,
+	// rather than leaving them to be refetched next cycle (0 disables)
+	CycleRetryCount int
+
+	// StampRunID tags every post with a UUID shared by the run that stored it
+	StampRunID bool
+
+	// IngestComments also fetches each stored post's comments from
+	// {APIEndpoint}/{id}/comments
+	IngestComments bool
+
+	// UserLookupEndpoint enriches each post with its author's record, fetched
+	// from this URL with "{userId}" replaced (appended when absent) and
+	// cached per userId for UserLookupCacheTTL. UserLookupFields selects the
+	// fields kept, all when empty. Enrichment is disabled when empty.
+	UserLookupEndpoint string
+	UserLookupFields   []string
+	UserLookupCacheTTL time.Duration
+
+	// ForwardHeaders lists request headers propagated to the upstream on
+	// API-triggered ingestion. A trailing "*" matches by prefix.
+	ForwardHeaders []string
+
+	// RedactHeaders lists upstream response headers whose values are hidden
+	// in the recorded last fetch
+	RedactHeaders []string
+
+	// AuthType selects how upstream requests are authenticated: "" for
+	// none or "awssigv4" to sign them for API Gateway with credentials from
+	// the default AWS chain
+	AuthType     string
+	SigV4Region  string
+	SigV4Service string
+
+	// After DegradedThreshold consecutive fetch failures the next cycle
+	// waits DegradedInterval instead of Interval (0 disables)
+	DegradedThreshold int
+	DegradedInterval  time.Duration
+
+	// After EmptyCycleThreshold consecutive cycles with no records the
+	// interval doubles each cycle, up to MaxInterval (0 disables)
+	EmptyCycleThreshold int
+	MaxInterval         time.Duration
+
+	// Skip posts already stored, tracked in a bloom filter persisted at
+	// DedupFilterPath (empty disables)
+	DedupFilterPath        string
+	DedupExpectedItems     int
+	DedupFalsePositiveRate float64
+
+	// CompositeKey mirrors the storage setting of the same name: post IDs
+	// are only unique per source, so the dedup filter keys them by both
+	CompositeKey bool
+
+	// After ReadOnlyThreshold consecutive failed stores, cycles are skipped
+	// until a probe write succeeds (0 disables)
+	ReadOnlyThreshold int
+
+	// MaxHeapBytes skips cycles while the Go heap holds more than this
+	// many bytes, rather than risking an OOM kill (0 disables)
+	MaxHeapBytes int
+
+	// Every ReconcileInterval (0 disables), re-fetch a ReconcileSampleRate
+	// fraction of stored posts and report drift, re-storing drifted posts
+	// if ReconcileReingest is set
+	ReconcileInterval   time.Duration
+	ReconcileSampleRate float64
+	ReconcileReingest   bool
+
+	// ErrorHistorySize is how many recent ingestion errors are kept (0 disables)
+	ErrorHistorySize int
+
+	// MetricsHistory persists a metrics snapshot of every cycle to storage
+	MetricsHistory bool
+
+	// MetricsExemplars attaches the trace ID of a triggering request's
+	// traceparent header to the fetch and store latency observations
+	MetricsExemplars bool
+
+	// Push the metrics to a Pushgateway at PushgatewayURL when a batch run
+	// (MaxCycles set) completes, grouped by job and instance (the hostname
+	// when empty). Disabled when the URL is empty.
+	PushgatewayURL      string
+	PushgatewayJob      string
+	PushgatewayInstance string
+
+	// HashAlgorithm selects the content hash: "fnv", "sha256" or "xxhash"
+	HashAlgorithm string
+
+	// CreatedAtField is the upstream JSON field holding the post's creation time
+	CreatedAtField string
+	MinPostAge     time.Duration // Skip posts newer than this (0 disables)
+	MaxPostAge     time.Duration // Skip posts older than this (0 disables)
+
+	// Title normalization: trim and collapse whitespace, optionally lowercase
+	NormalizeTitles bool
+	LowercaseTitles bool
+
+	// CategoryKeywords maps title keywords to a category; posts matching none
+	// get DefaultCategory. Categorization is disabled when empty.
+	CategoryKeywords map[string]string
+	DefaultCategory  string
+
+	// TransformScript is an ojg asm script file run on every post after the
+	// other transformers, which may modify or drop it. Startup fails if it
+	// can't be loaded.
+	TransformScript string
 }
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port int
+	Port   int
+	APIKey string // Required by write endpoints; they are disabled when empty
+
+	AccessLogLevel slog.Level    // Level at which each request is logged
+	ConnMaxAge     time.Duration // Keep-alive connections older than this are closed; 0 disables
+
+	// /health reports unhealthy when the last successful ingestion is older
+	// than HealthMaxStaleness (0 disables), except within HealthStartupGrace
+	// of startup
+	HealthMaxStaleness time.Duration
+	HealthStartupGrace time.Duration
+
+	// UpstreamProbeTimeout bounds the probes of /health/upstream
+	UpstreamProbeTimeout time.Duration
+
+	// Reject imported posts with longer fields, in bytes (0 is unlimited)
+	MaxTitleLength int
+	MaxBodyLength  int
+
+	// MaxPageLimit caps the limit a GET /posts request may ask for, so one
+	// request can't scan the whole table (0 is unlimited)
+	MaxPageLimit int
+
+	// DefaultLimit is the GET /posts limit when the request sets none, still
+	// capped by MaxPageLimit
+	DefaultLimit int
+
+	// MaxOffset rejects GET /posts page offsets beyond it, since reaching an
+	// offset scans every post before it (0 is unlimited)
+	MaxOffset int
+
+	// FieldNaming is the key convention of posts in responses: "camelCase",
+	// "snake_case", or "" for each field's own key
+	FieldNaming string
+
+	// Cache GET /posts responses for CacheTTL (0 disables)
+	CacheTTL        time.Duration
+	CacheMaxEntries int
+
+	// CoalesceReads shares one storage read between concurrent identical
+	// GET /posts requests
+	CoalesceReads bool
+
+	// DebugVars serves expvar counters on /debug/vars
+	DebugVars bool
+
+	// DebugLastFetch serves the latest upstream response's status and
+	// headers on /debug/last-fetch
+	DebugLastFetch bool
+
+	// OpenMetrics serves /metrics in the OpenMetrics format to scrapers
+	// asking for it, which is required to expose exemplars
+	OpenMetrics bool
 }
 
-// Load loads configuration from environment variables with defaults
+// Load loads configuration from environment variables, the file named by
+// CONFIG_FILE and defaults
 func Load() (*Config, error) {
+	return LoadArgs(nil)
+}
+
+// LoadArgs loads configuration from, highest precedence first:
+//
+//  1. command-line flags in args, one per setting, named after its
+//     environment variable, e.g. -table-name for TABLE_NAME
+//  2. environment variables
+//  3. the KEY=VALUE file named by the -config flag or CONFIG_FILE
+//  4. built-in defaults
+//
+// Each setting is resolved on its own, so a file can supply most settings
+// while the environment or a flag overrides one of them.
+func LoadArgs(args []string) (*Config, error) {
+	// A dry run against no sources finds every setting's key to define its flag
+	dryRun := &envParser{}
+	build(dryRun)
+
+	fs := flag.NewFlagSet("data-ingestion-service", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "KEY=VALUE `file` of settings")
+	flags := make(map[string]string)
+	for _, key := range dryRun.keys {
+		key := key
+		fs.Func(flagName(key), "overrides "+key, func(value string) error {
+			flags[key] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	var file map[string]string
+	if *configFile != "" {
+		var err error
+		if file, err = readConfigFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return build(&envParser{flags: flags, file: file}), nil
+}
+
+// build resolves every setting through env
+func build(env *envParser) *Config {
 	cfg := &Config{
 		Storage: StorageConfig{
-			Type:        getEnv("STORAGE_TYPE", "dynamodb"),
-			Region:      getEnv("AWS_REGION", "us-west-2"),
-			TableName:   getEnv("TABLE_NAME", "ingested_data"),
-			Endpoint:    getEnv("DYNAMODB_ENDPOINT", ""), // For local DynamoDB
-			MongoDBURI:  getEnv("MONGODB_URI", ""),
-			PostgresURI: getEnv("POSTGRES_URI", ""),
+			Type:        env.String("STORAGE_TYPE", "dynamodb"),
+			Region:      env.String("AWS_REGION", "us-west-2"),
+			TableName:   env.String("TABLE_NAME", "ingested_data"),
+			Endpoint:    env.String("DYNAMODB_ENDPOINT", ""), // For local DynamoDB
+			MongoDBURI:  env.String("MONGODB_URI", ""),
+			PostgresURI: env.String("POSTGRES_URI", ""),
+			SoftDelete:  env.Bool("SOFT_DELETE", false),
+
+			CompositeKey: env.Bool("DYNAMODB_COMPOSITE_KEY", false),
+
+			VersionPosts: env.Bool("VERSION_POSTS", false),
+			ScanSegments: env.Int("DYNAMODB_SCAN_SEGMENTS", 1),
+
+			SourceTables: env.Map("SOURCE_TABLES"),
+
+			OffloadLargeBodies: env.Bool("OFFLOAD_LARGE_BODIES", false),
+			OffloadThreshold:   env.Int("OFFLOAD_THRESHOLD_BYTES", 300*1024),
+			OffloadBucket:      env.String("OFFLOAD_BUCKET", ""),
+
+			CompressBodies:    env.Bool("COMPRESS_BODIES", false),
+			CompressThreshold: env.Int("COMPRESS_THRESHOLD_BYTES", 1024),
+
+			MaxConcurrency: env.Int("STORAGE_MAX_CONCURRENCY", 0),
+			ReadWeight:     env.Int("STORAGE_READ_WEIGHT", 1),
+			WriteWeight:    env.Int("STORAGE_WRITE_WEIGHT", 1),
+
+			AuditSink:  env.String("AUDIT_SINK", ""),
+			AuditFile:  env.String("AUDIT_FILE", ""),
+			AuditTable: env.String("AUDIT_TABLE", env.String("TABLE_NAME", "ingested_data")+"_audit"),
+
+			SinkFormat:       env.String("SINK_FORMAT", ""),
+			EventSource:      env.String("CLOUDEVENTS_SOURCE", "/data-ingestion-service"),
+			PostEventType:    env.String("CLOUDEVENTS_POST_TYPE", "com.cyderes.ingestion.post"),
+			CommentEventType: env.String("CLOUDEVENTS_COMMENT_TYPE", "com.cyderes.ingestion.comment"),
+
+			WriteBufferSize:     env.Int("WRITE_BUFFER_SIZE", 0),
+			WriteBufferInterval: env.Duration("WRITE_BUFFER_FLUSH_INTERVAL", 5*time.Second),
+
+			InitRetries: env.Int("STORAGE_INIT_RETRIES", 5),
+			InitBackoff: env.Duration("STORAGE_INIT_BACKOFF", time.Second),
+
+			ReadRetryCount:   env.Int("STORAGE_READ_RETRY_COUNT", 0),
+			ReadRetryBackoff: env.Duration("STORAGE_READ_RETRY_BACKOFF", 100*time.Millisecond),
+
+			ArchiveBucket: env.String("ARCHIVE_S3_BUCKET", ""),
+			ArchivePrefix: env.String("ARCHIVE_S3_PREFIX", "posts"),
 		},
 		Ingestion: IngestionConfig{
-			APIEndpoint: getEnv("API_ENDPOINT", "https://jsonplaceholder.typicode.com/posts"),
-			Interval:    getEnvDuration("INGESTION_INTERVAL", 5*time.Minute),
-			Timeout:     getEnvDuration("API_TIMEOUT", 30*time.Second),
-			RetryCount:  getEnvInt("RETRY_COUNT", 3),
+			APIEndpoint: env.String("API_ENDPOINT", "https://jsonplaceholder.typicode.com/posts"),
+			Interval:    env.Duration("INGESTION_INTERVAL", 5*time.Minute),
+			MaxCycles:   env.Int("MAX_CYCLES", 0),
+			Timeout:     env.Duration("API_TIMEOUT", 30*time.Second),
+			RetryCount:  env.Int("RETRY_COUNT", 3),
+			QueryParams: env.Map("API_QUERY_PARAMS"),
+			JSONPath:    env.String("API_JSONPATH", ""),
+
+			APIEndpoints:        env.WeightedEndpoints("API_ENDPOINTS"),
+			FallbackAPIEndpoint: env.String("FALLBACK_API_ENDPOINT", ""),
+
+			FallbackTimeout:    env.Duration("FALLBACK_API_TIMEOUT", 0),
+			FallbackRetryCount: env.Int("FALLBACK_RETRY_COUNT", 0),
+
+			SourceName:         env.String("SOURCE_NAME", "placeholder_api"),
+			FallbackSourceName: env.String("FALLBACK_SOURCE_NAME", "fallback_api"),
+
+			EndpointProbeInterval: env.Duration("ENDPOINT_PROBE_INTERVAL", time.Minute),
+
+			RefuseRedirectDowngrade: env.Bool("REFUSE_REDIRECT_DOWNGRADE", false),
+
+			CertPins: env.List("UPSTREAM_CERT_PINS", nil),
+
+			StartupHealthCheck: env.Bool("STARTUP_HEALTH_CHECK", false),
+
+			SlowFetchThreshold: env.Duration("SLOW_FETCH_THRESHOLD", 0),
+
+			MaxRunDuration: env.Duration("MAX_RUN_DURATION", 0),
+			MaxStalePeriod: env.Duration("MAX_STALE_PERIOD", 0),
+
+			IngestedAtOverride: env.Time("INGESTED_AT_OVERRIDE"),
+
+			RateLimitRemainingHeader: env.String("RATE_LIMIT_REMAINING_HEADER", "X-RateLimit-Remaining"),
+			RateLimitLimitHeader:     env.String("RATE_LIMIT_LIMIT_HEADER", "X-RateLimit-Limit"),
+
+			ShardCount:      env.Int("SHARD_COUNT", 1),
+			ShardIDStart:    env.Int("SHARD_ID_START", 1),
+			ShardIDEnd:      env.Int("SHARD_ID_END", 0),
+			ShardStartParam: env.String("SHARD_START_PARAM", "id_gte"),
+			ShardEndParam:   env.String("SHARD_END_PARAM", "id_lte"),
+
+			GlobalFetchConcurrency: env.Int("GLOBAL_FETCH_CONCURRENCY", 0),
+
+			StoreRetryCount:  env.Int("STORE_RETRY_COUNT", 3),
+			CycleRetryCount:  env.Int("CYCLE_RETRY_COUNT", 0),
+			MaxBatchPerCycle: env.Int("MAX_BATCH_PER_CYCLE", 0),
+			SortByID:         env.Bool("SORT_POSTS_BY_ID", false),
+			StampRunID:       env.Bool("STAMP_RUN_ID", false),
+			IngestComments:   env.Bool("INGEST_COMMENTS", false),
+			ForwardHeaders:   env.List("FORWARD_HEADERS", []string{"traceparent", "tracestate", "x-b3-*"}),
+			RedactHeaders:    env.List("REDACT_HEADERS", []string{"Set-Cookie", "Authorization", "Proxy-Authenticate", "WWW-Authenticate"}),
+
+			UserLookupEndpoint: env.String("USER_LOOKUP_ENDPOINT", ""),
+			UserLookupFields:   env.List("USER_LOOKUP_FIELDS", nil),
+			UserLookupCacheTTL: env.Duration("USER_LOOKUP_CACHE_TTL", time.Hour),
+
+			AuthType:     env.String("AUTH_TYPE", ""),
+			SigV4Region:  env.String("SIGV4_REGION", env.String("AWS_REGION", "us-west-2")),
+			SigV4Service: env.String("SIGV4_SERVICE", "execute-api"),
+
+			DegradedThreshold: env.Int("DEGRADED_THRESHOLD", 0),
+			DegradedInterval:  env.Duration("DEGRADED_INTERVAL", 30*time.Minute),
+
+			EmptyCycleThreshold: env.Int("EMPTY_CYCLE_THRESHOLD", 0),
+			MaxInterval:         env.Duration("MAX_INGESTION_INTERVAL", time.Hour),
+
+			DedupFilterPath:        env.String("DEDUP_FILTER_PATH", ""),
+			DedupExpectedItems:     env.Int("DEDUP_EXPECTED_ITEMS", 100000),
+			DedupFalsePositiveRate: env.Float("DEDUP_FALSE_POSITIVE_RATE", 0.01),
+			CompositeKey:           env.Bool("DYNAMODB_COMPOSITE_KEY", false),
+
+			HashAlgorithm: env.String("HASH_ALGORITHM", "fnv"),
+
+			ReadOnlyThreshold: env.Int("READ_ONLY_THRESHOLD", 0),
+			MaxHeapBytes:      env.Int("MAX_HEAP_BYTES", 0),
+			ErrorHistorySize:  env.Int("ERROR_HISTORY_SIZE", 20),
+			MetricsHistory:    env.Bool("METRICS_HISTORY", false),
+
+			MetricsExemplars: env.Bool("METRICS_EXEMPLARS", false),
+
+			PushgatewayURL:      env.String("PUSHGATEWAY_URL", ""),
+			PushgatewayJob:      env.String("PUSHGATEWAY_JOB", "data-ingestion-service"),
+			PushgatewayInstance: env.String("PUSHGATEWAY_INSTANCE", ""),
+
+			ReconcileInterval:   env.Duration("RECONCILE_INTERVAL", 0),
+			ReconcileSampleRate: env.Float("RECONCILE_SAMPLE_RATE", 0.1),
+			ReconcileReingest:   env.Bool("RECONCILE_REINGEST", false),
+
+			CreatedAtField: env.String("CREATED_AT_FIELD", "createdAt"),
+			MinPostAge:     env.Duration("MIN_POST_AGE", 0),
+			MaxPostAge:     env.Duration("MAX_POST_AGE", 0),
+
+			NormalizeTitles: env.Bool("NORMALIZE_TITLES", false),
+			LowercaseTitles: env.Bool("LOWERCASE_TITLES", false),
+
+			CategoryKeywords: env.Map("CATEGORY_KEYWORDS"),
+			DefaultCategory:  env.String("DEFAULT_CATEGORY", "uncategorized"),
+
+			TransformScript: env.String("TRANSFORM_SCRIPT", ""),
 		},
 		Server: ServerConfig{
-			Port: getEnvInt("SERVER_PORT", 8080),
+			Port:   env.Int("SERVER_PORT", 8080),
+			APIKey: env.String("API_KEY", ""),
+
+			AccessLogLevel: env.LogLevel("ACCESS_LOG_LEVEL", slog.LevelInfo),
+			ConnMaxAge:     env.Duration("SERVER_CONN_MAX_AGE", 0),
+
+			HealthMaxStaleness: env.Duration("HEALTH_MAX_STALENESS", 0),
+			HealthStartupGrace: env.Duration("HEALTH_STARTUP_GRACE", 10*time.Minute),
+
+			UpstreamProbeTimeout: env.Duration("UPSTREAM_PROBE_TIMEOUT", 2*time.Second),
+
+			MaxTitleLength: env.Int("MAX_TITLE_LENGTH", 0),
+			MaxBodyLength:  env.Int("MAX_BODY_LENGTH", 0),
+
+			MaxPageLimit: env.Int("MAX_PAGE_LIMIT", 0),
+			DefaultLimit: env.Int("DEFAULT_PAGE_LIMIT", 10),
+			MaxOffset:    env.Int("MAX_OFFSET", 0),
+			FieldNaming:  env.String("JSON_FIELD_NAMING", ""),
+
+			CacheTTL:        env.Duration("POSTS_CACHE_TTL", 0),
+			CacheMaxEntries: env.Int("POSTS_CACHE_MAX_ENTRIES", 1000),
+
+			CoalesceReads: env.Bool("COALESCE_READS", false),
+
+			DebugVars:      env.Bool("DEBUG_VARS_ENABLED", false),
+			DebugLastFetch: env.Bool("DEBUG_LAST_FETCH_ENABLED", false),
+
+			OpenMetrics: env.Bool("METRICS_OPENMETRICS", env.Bool("METRICS_EXEMPLARS", false)),
 		},
 	}
 
-	return cfg, nil
-}
+	cfg.MigrateTarget = cfg.Storage
+	cfg.MigrateTarget.Type = env.String("MIGRATE_TARGET_STORAGE_TYPE", cfg.Storage.Type)
+	cfg.MigrateTarget.TableName = env.String("MIGRATE_TARGET_TABLE_NAME", cfg.Storage.TableName)
+	cfg.MigrateTarget.Endpoint = env.String("MIGRATE_TARGET_DYNAMODB_ENDPOINT", cfg.Storage.Endpoint)
+	cfg.MigrateTarget.MongoDBURI = env.String("MIGRATE_TARGET_MONGODB_URI", cfg.Storage.MongoDBURI)
+	cfg.MigrateTarget.PostgresURI = env.String("MIGRATE_TARGET_POSTGRES_URI", cfg.Storage.PostgresURI)
+	// Not shared: the source's per-source tables would route posts back into the source
+	cfg.MigrateTarget.SourceTables = env.Map("MIGRATE_TARGET_SOURCE_TABLES")
+	cfg.MigrateBatchSize = env.Int("MIGRATE_BATCH_SIZE", 100)
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+	cfg.Warnings = env.errors
+	return cfg
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intVal, err := strconv.Atoi(value); err == nil {
-			return intVal
-		}
+// Categories returns the distinct categories posts can be assigned, sorted,
+// or nil when categorization is disabled
+func (c IngestionConfig) Categories() []string {
+	if len(c.CategoryKeywords) == 0 {
+		return nil
 	}
-	return defaultValue
-}
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+	categories := []string{c.DefaultCategory}
+	for _, category := range c.CategoryKeywords {
+		if !slices.Contains(categories, category) {
+			categories = append(categories, category)
 		}
 	}
-	return defaultValue
+	sort.Strings(categories)
+	return categories
 }
\ No newline at end of file
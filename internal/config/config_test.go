@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load()
+
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Warnings)
+	assert.Equal(t, 3, cfg.Ingestion.RetryCount)
+	assert.Equal(t, 5*time.Minute, cfg.Ingestion.Interval)
+}
+
+func TestLoad_MalformedInt(t *testing.T) {
+	t.Setenv("RETRY_COUNT", "three")
+
+	cfg, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.Ingestion.RetryCount, "malformed value should fall back to the default")
+	if assert.Len(t, cfg.Warnings, 1) {
+		assert.Contains(t, cfg.Warnings[0].Error(), "RETRY_COUNT")
+		assert.Contains(t, cfg.Warnings[0].Error(), `"three"`)
+	}
+}
+
+func TestLoad_MalformedDuration(t *testing.T) {
+	t.Setenv("INGESTION_INTERVAL", "5 minutes")
+	t.Setenv("API_TIMEOUT", "10s")
+
+	cfg, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.Ingestion.Interval)
+	assert.Equal(t, 10*time.Second, cfg.Ingestion.Timeout)
+	if assert.Len(t, cfg.Warnings, 1) {
+		assert.Contains(t, cfg.Warnings[0].Error(), "INGESTION_INTERVAL")
+	}
+}
+
+func TestIngestionConfig_Categories(t *testing.T) {
+	cfg := IngestionConfig{
+		CategoryKeywords: map[string]string{"go": "programming", "rust": "programming", "recipe": "food"},
+		DefaultCategory:  "uncategorized",
+	}
+
+	assert.Equal(t, []string{"food", "programming", "uncategorized"}, cfg.Categories())
+	assert.Nil(t, IngestionConfig{DefaultCategory: "uncategorized"}.Categories())
+}
+
+func TestLoad_WeightedEndpoints(t *testing.T) {
+	t.Setenv("API_ENDPOINTS", "https://a.example/posts|3, https://b.example/posts?v=2 ,https://c.example/posts|zero,https://d.example/posts|0")
+
+	cfg, err := Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []WeightedEndpoint{
+		{URL: "https://a.example/posts", Weight: 3},
+		{URL: "https://b.example/posts?v=2", Weight: 1},
+	}, cfg.Ingestion.APIEndpoints)
+	assert.Len(t, cfg.Warnings, 2, "malformed weights should be reported")
+}
+
+func TestLoad_MigrateTarget(t *testing.T) {
+	t.Setenv("STORAGE_TYPE", "dynamodb")
+	t.Setenv("TABLE_NAME", "posts")
+	t.Setenv("SOURCE_TABLES", "fallback_api=fallback_posts")
+	t.Setenv("MIGRATE_TARGET_STORAGE_TYPE", "stdout")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "stdout", cfg.MigrateTarget.Type)
+	assert.Equal(t, "posts", cfg.MigrateTarget.TableName, "unset overrides should be shared with the source")
+	assert.Equal(t, cfg.Storage.Region, cfg.MigrateTarget.Region)
+	assert.Empty(t, cfg.MigrateTarget.SourceTables)
+	assert.Equal(t, 100, cfg.MigrateBatchSize)
+}
+
+func TestLoad_OpenMetrics(t *testing.T) {
+	// Test OpenMetrics follows exemplars unless set on its own
+	t.Setenv("METRICS_EXEMPLARS", "true")
+	cfg, err := Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Ingestion.MetricsExemplars)
+	assert.True(t, cfg.Server.OpenMetrics)
+
+	t.Setenv("METRICS_OPENMETRICS", "false")
+	cfg, err = Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Ingestion.MetricsExemplars)
+	assert.False(t, cfg.Server.OpenMetrics)
+}
+
+func TestLoad_IngestedAtOverride(t *testing.T) {
+	t.Setenv("INGESTED_AT_OVERRIDE", "2024-01-15T10:30:00+02:00")
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	if assert.NotNil(t, cfg.Ingestion.IngestedAtOverride) {
+		assert.True(t, time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC).Equal(*cfg.Ingestion.IngestedAtOverride))
+	}
+
+	// Test a malformed timestamp is reported and ignored
+	t.Setenv("INGESTED_AT_OVERRIDE", "2024-01-15 10:30")
+
+	cfg, err = Load()
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.Ingestion.IngestedAtOverride)
+	if assert.Len(t, cfg.Warnings, 1) {
+		assert.Contains(t, cfg.Warnings[0].Error(), "INGESTED_AT_OVERRIDE")
+	}
+}
+
+func TestLoadArgs_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.env")
+	require.NoError(t, os.WriteFile(path, []byte(`# Settings for the test
+TABLE_NAME=from_file
+AWS_REGION=eu-west-1
+RETRY_COUNT="7"
+API_TIMEOUT = 45s
+`), 0o600))
+	t.Setenv("TABLE_NAME", "from_env")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	cfg, err := LoadArgs([]string{"-config", path, "-table-name", "from_flag"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "from_flag", cfg.Storage.TableName, "a flag overrides the environment")
+	assert.Equal(t, "us-east-1", cfg.Storage.Region, "the environment overrides the file")
+	assert.Equal(t, 7, cfg.Ingestion.RetryCount, "the file overrides the default")
+	assert.Equal(t, 45*time.Second, cfg.Ingestion.Timeout)
+	assert.Equal(t, 5*time.Minute, cfg.Ingestion.Interval, "unset everywhere keeps the default")
+}
+
+func TestLoadArgs_ConfigFileFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.env")
+	require.NoError(t, os.WriteFile(path, []byte("TABLE_NAME=from_file\n"), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load()
+
+	require.NoError(t, err)
+	assert.Equal(t, "from_file", cfg.Storage.TableName)
+}
+
+func TestLoadArgs_Errors(t *testing.T) {
+	malformed := filepath.Join(t.TempDir(), "service.env")
+	require.NoError(t, os.WriteFile(malformed, []byte("TABLE_NAME\n"), 0o600))
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown flag", []string{"-no-such-setting", "x"}, "failed to parse flags"},
+		{"missing file", []string{"-config", filepath.Join(t.TempDir(), "missing.env")}, "failed to open config file"},
+		{"malformed file", []string{"-config", malformed}, "line 1 is not KEY=VALUE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadArgs(tt.args)
+
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envParser reads typed settings keyed by environment variable name, taking
+// each from a flag, the environment or the config file, in that order.
+// Absent settings yield the default; malformed ones also yield the default
+// but are recorded so they can be reported instead of silently ignored.
+type envParser struct {
+	errors []error
+
+	flags map[string]string // Set on the command line
+	file  map[string]string // From the config file
+
+	keys []string // Every key looked up, in first lookup order
+	seen map[string]bool
+}
+
+// lookup returns the setting's value from the highest precedence source
+// that sets it, or "" if none does
+func (e *envParser) lookup(key string) string {
+	if !e.seen[key] {
+		if e.seen == nil {
+			e.seen = make(map[string]bool)
+		}
+		e.seen[key] = true
+		e.keys = append(e.keys, key)
+	}
+
+	if value, ok := e.flags[key]; ok {
+		return value
+	}
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return e.file[key]
+}
+
+func (e *envParser) fail(key, value, kind string, err error) {
+	e.errors = append(e.errors, fmt.Errorf("invalid %s value %q for %s: %w", kind, value, key, err))
+}
+
+// String returns the variable's value, or defaultValue if unset
+func (e *envParser) String(key, defaultValue string) string {
+	if value := e.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Int parses the variable as an integer
+func (e *envParser) Int(key string, defaultValue int) int {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	intVal, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(key, value, "integer", err)
+		return defaultValue
+	}
+	return intVal
+}
+
+// Bool parses the variable as a boolean
+func (e *envParser) Bool(key string, defaultValue bool) bool {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	boolVal, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(key, value, "boolean", err)
+		return defaultValue
+	}
+	return boolVal
+}
+
+// Float parses the variable as a float64
+func (e *envParser) Float(key string, defaultValue float64) float64 {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatVal, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.fail(key, value, "float", err)
+		return defaultValue
+	}
+	return floatVal
+}
+
+// Duration parses the variable as a time.Duration, e.g. "30s"
+func (e *envParser) Duration(key string, defaultValue time.Duration) time.Duration {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(key, value, "duration", err)
+		return defaultValue
+	}
+	return duration
+}
+
+// Time parses the variable as an RFC 3339 timestamp, returning nil if unset
+// or malformed
+func (e *envParser) Time(key string) *time.Time {
+	value := e.lookup(key)
+	if value == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		e.fail(key, value, "RFC 3339 timestamp", err)
+		return nil
+	}
+	return &t
+}
+
+// LogLevel parses the variable as a slog level, e.g. "debug" or "warn"
+func (e *envParser) LogLevel(key string, defaultValue slog.Level) slog.Level {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		e.fail(key, value, "log level", err)
+		return defaultValue
+	}
+	return level
+}
+
+// List parses a comma-separated list, e.g. "a,b,c"
+func (e *envParser) List(key string, defaultValue []string) []string {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Map parses a comma-separated list of key=value pairs,
+// e.g. "version=2,format=json". Malformed pairs are skipped.
+func (e *envParser) Map(key string) map[string]string {
+	value := e.lookup(key)
+	if value == "" {
+		return nil
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			e.fail(key, pair, "key=value", fmt.Errorf("missing key or '='"))
+			continue
+		}
+		result[k] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// WeightedEndpoints parses a comma-separated list of url|weight entries,
+// e.g. "https://a.example/posts|3,https://b.example/posts|1". The weight
+// defaults to 1 when omitted. Malformed entries are skipped.
+func (e *envParser) WeightedEndpoints(key string) []WeightedEndpoint {
+	var endpoints []WeightedEndpoint
+	for _, item := range e.List(key, nil) {
+		rawURL, rawWeight, hasWeight := strings.Cut(item, "|")
+		endpoint := WeightedEndpoint{URL: strings.TrimSpace(rawURL), Weight: 1}
+		if endpoint.URL == "" {
+			e.fail(key, item, "url|weight", fmt.Errorf("missing url"))
+			continue
+		}
+		if hasWeight {
+			weight, err := strconv.Atoi(strings.TrimSpace(rawWeight))
+			if err == nil && weight <= 0 {
+				err = fmt.Errorf("weight must be positive")
+			}
+			if err != nil {
+				e.fail(key, item, "url|weight", err)
+				continue
+			}
+			endpoint.Weight = weight
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
@@ -0,0 +1,203 @@
+package dedup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Magic numbers identifying persisted filter files. Version 1 files predate
+// configurable hashing and always use FNV.
+const (
+	bloomMagicV1 uint32 = 0x626c6d31 // "blm1"
+	bloomMagic   uint32 = 0x626c6d32 // "blm2"
+)
+
+// BloomFilter is a probabilistic set of post IDs. Contains never reports a
+// false negative, but may report a false positive at roughly the configured rate.
+type BloomFilter struct {
+	mu   sync.RWMutex
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint32 // number of hash functions
+
+	hasher *Hasher
+}
+
+// NewBloomFilter sizes a filter for the expected number of items and false-positive rate
+func NewBloomFilter(expectedItems int, falsePositiveRate float64) *BloomFilter {
+	return NewBloomFilterWithHasher(expectedItems, falsePositiveRate, DefaultHasher())
+}
+
+// NewBloomFilterWithHasher is like NewBloomFilter but hashes with the given hasher
+func NewBloomFilterWithHasher(expectedItems int, falsePositiveRate float64, hasher *Hasher) *BloomFilter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01
+	}
+
+	n := float64(expectedItems)
+	m := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+
+	words := (uint64(m) + 63) / 64
+	return &BloomFilter{
+		bits:   make([]uint64, words),
+		m:      words * 64,
+		k:      uint32(k),
+		hasher: hasher,
+	}
+}
+
+// Hasher returns the hasher the filter was built with
+func (b *BloomFilter) Hasher() *Hasher {
+	return b.hasher
+}
+
+// Add records an ID in the filter
+func (b *BloomFilter) Add(id int) {
+	b.add(b.hashID(id))
+}
+
+// AddSourceID records an ID scoped to a source, for posts whose IDs are
+// only unique within their source
+func (b *BloomFilter) AddSourceID(source string, id int) {
+	b.add(b.hashKey(sourceKey(source, id)))
+}
+
+func (b *BloomFilter) add(h1, h2 uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := uint32(0); i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether the ID has possibly been added
+func (b *BloomFilter) Contains(id int) bool {
+	return b.contains(b.hashID(id))
+}
+
+// ContainsSourceID reports whether the source's ID has possibly been added
+// with AddSourceID
+func (b *BloomFilter) ContainsSourceID(source string, id int) bool {
+	return b.contains(b.hashKey(sourceKey(source, id)))
+}
+
+func (b *BloomFilter) contains(h1, h2 uint64) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for i := uint32(0); i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashID derives the two base hashes used for double hashing
+func (b *BloomFilter) hashID(id int) (uint64, uint64) {
+	return b.hashKey(binary.LittleEndian.AppendUint64(nil, uint64(id)))
+}
+
+// hashKey derives the base hashes of an arbitrary key: the key, then the
+// key repeated
+func (b *BloomFilter) hashKey(key []byte) (uint64, uint64) {
+	h1 := b.hasher.Sum64(key)
+	h2 := b.hasher.Sum64(append(key, key...)) | 1 // Odd, so it cycles through all bit positions
+
+	return h1, h2
+}
+
+// sourceKey encodes a source and ID as the source, a zero separator, and
+// the ID, so no source's keys collide with another's
+func sourceKey(source string, id int) []byte {
+	key := append([]byte(source), 0)
+	return binary.LittleEndian.AppendUint64(key, uint64(id))
+}
+
+// Save writes the filter to path atomically
+func (b *BloomFilter) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create filter file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := b.write(w); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (b *BloomFilter) write(w io.Writer) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	header := []any{bloomMagic, b.m, b.k, b.hasher.id}
+	for _, field := range header {
+		if err := binary.Write(w, binary.LittleEndian, field); err != nil {
+			return err
+		}
+	}
+	return binary.Write(w, binary.LittleEndian, b.bits)
+}
+
+// LoadBloomFilter reads a filter previously written with Save
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var magic uint32
+	b := &BloomFilter{}
+	for _, field := range []any{&magic, &b.m, &b.k} {
+		if err := binary.Read(r, binary.LittleEndian, field); err != nil {
+			return nil, fmt.Errorf("failed to read filter header: %w", err)
+		}
+	}
+	if (magic != bloomMagic && magic != bloomMagicV1) || b.m == 0 || b.m%64 != 0 || b.k == 0 {
+		return nil, errors.New("invalid filter file")
+	}
+
+	b.hasher = DefaultHasher()
+	if magic == bloomMagic {
+		var id uint8
+		if err := binary.Read(r, binary.LittleEndian, &id); err != nil {
+			return nil, fmt.Errorf("failed to read filter header: %w", err)
+		}
+		if b.hasher, err = hasherByID(id); err != nil {
+			return nil, err
+		}
+	}
+
+	b.bits = make([]uint64, b.m/64)
+	if err := binary.Read(r, binary.LittleEndian, b.bits); err != nil {
+		return nil, fmt.Errorf("failed to read filter: %w", err)
+	}
+
+	return b, nil
+}
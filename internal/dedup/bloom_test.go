@@ -0,0 +1,70 @@
+package dedup
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter_AddContains(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+
+	for id := 0; id < 1000; id += 2 {
+		filter.Add(id)
+	}
+
+	falsePositives := 0
+	for id := 0; id < 1000; id++ {
+		if id%2 == 0 {
+			assert.True(t, filter.Contains(id), "added ID %d must be reported", id)
+		} else if filter.Contains(id) {
+			falsePositives++
+		}
+	}
+
+	// 500 absent IDs at a 1% target rate; allow generous slack
+	assert.Less(t, falsePositives, 25)
+}
+
+func TestBloomFilter_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+
+	filter := NewBloomFilter(100, 0.01)
+	filter.Add(7)
+	filter.Add(42)
+	assert.NoError(t, filter.Save(path))
+
+	loaded, err := LoadBloomFilter(path)
+
+	assert.NoError(t, err)
+	assert.True(t, loaded.Contains(7))
+	assert.True(t, loaded.Contains(42))
+	assert.False(t, loaded.Contains(8))
+}
+
+func TestBloomFilter_SaveLoad_Hasher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.bloom")
+
+	hasher, err := NewHasher(HashXXHash)
+	assert.NoError(t, err)
+	filter := NewBloomFilterWithHasher(100, 0.01, hasher)
+	filter.Add(7)
+	assert.NoError(t, filter.Save(path))
+
+	loaded, err := LoadBloomFilter(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, hasher, loaded.Hasher())
+	assert.True(t, loaded.Contains(7))
+}
+
+func TestBloomFilter_SourceID(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.001)
+	filter.AddSourceID("placeholder_api", 1)
+
+	// Test an ID is only seen under the source it was added with
+	assert.True(t, filter.ContainsSourceID("placeholder_api", 1))
+	assert.False(t, filter.ContainsSourceID("other_api", 1))
+	assert.False(t, filter.Contains(1))
+}
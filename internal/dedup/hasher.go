@@ -0,0 +1,79 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Supported hash algorithms
+const (
+	HashFNV    = "fnv"
+	HashSHA256 = "sha256"
+	HashXXHash = "xxhash"
+)
+
+// Hasher computes 64-bit content hashes with a selectable algorithm, so every
+// consumer of content hashes agrees on how they are derived
+type Hasher struct {
+	name string
+	id   uint8 // Stable identifier recorded in persisted files
+	sum  func(data []byte) uint64
+}
+
+var hashers = []*Hasher{
+	{name: HashFNV, id: 1, sum: sumFNV},
+	{name: HashSHA256, id: 2, sum: sumSHA256},
+	{name: HashXXHash, id: 3, sum: xxhash.Sum64},
+}
+
+// DefaultHasher returns the fast, non-cryptographic default (FNV-1a)
+func DefaultHasher() *Hasher {
+	return hashers[0]
+}
+
+// NewHasher returns the hasher for algorithm; an empty name selects the default
+func NewHasher(algorithm string) (*Hasher, error) {
+	if algorithm == "" {
+		return DefaultHasher(), nil
+	}
+	for _, h := range hashers {
+		if h.name == algorithm {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+}
+
+func hasherByID(id uint8) (*Hasher, error) {
+	for _, h := range hashers {
+		if h.id == id {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown hash algorithm id: %d", id)
+}
+
+// Name returns the algorithm name
+func (h *Hasher) Name() string {
+	return h.name
+}
+
+// Sum64 hashes data
+func (h *Hasher) Sum64(data []byte) uint64 {
+	return h.sum(data)
+}
+
+func sumFNV(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+func sumSHA256(data []byte) uint64 {
+	sum := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(sum[:8])
+}
@@ -0,0 +1,47 @@
+package dedup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasher_Algorithms(t *testing.T) {
+	for _, algorithm := range []string{HashFNV, HashSHA256, HashXXHash} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewHasher(algorithm)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, algorithm, hasher.Name())
+
+			first := hasher.Sum64([]byte("post body"))
+			assert.Equal(t, first, hasher.Sum64([]byte("post body")), "hashes must be stable")
+			assert.NotEqual(t, first, hasher.Sum64([]byte("post body!")), "distinct content must hash differently")
+		})
+	}
+}
+
+func TestHasher_KnownValues(t *testing.T) {
+	// Pinned so persisted hashes stay valid across releases
+	tests := map[string]uint64{
+		HashFNV:    0xa430d84680aabd0b,
+		HashSHA256: 0x2cf24dba5fb0a30e,
+		HashXXHash: 0x26c7827d889f6da3,
+	}
+
+	for algorithm, want := range tests {
+		hasher, err := NewHasher(algorithm)
+		assert.NoError(t, err)
+		assert.Equal(t, want, hasher.Sum64([]byte("hello")), algorithm)
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	hasher, err := NewHasher("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultHasher(), hasher)
+
+	_, err = NewHasher("md5")
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// RunArchiver keeps a copy of each ingestion run's stored posts, e.g. in a
+// data lake, alongside the operational store
+type RunArchiver interface {
+	ArchiveRun(ctx context.Context, runID string, posts []models.TransformedPost) error
+}
+
+// WithRunArchiver archives the posts each run stores
+func WithRunArchiver(archiver RunArchiver) Option {
+	return func(s *Service) {
+		s.archiver = archiver
+	}
+}
+
+// archiveRun archives the posts a run stored. A failure is logged and
+// recorded but doesn't fail the run, since the posts are already stored.
+func (s *Service) archiveRun(ctx context.Context, runID string, posts []models.TransformedPost) {
+	if s.archiver == nil || len(posts) == 0 {
+		return
+	}
+	if err := s.archiver.ArchiveRun(ctx, runID, posts); err != nil {
+		err = fmt.Errorf("failed to archive run %s: %w", runID, err)
+		s.logger.Error("Archive error", "run_id", runID, "error", err)
+		s.recordError(err)
+	}
+}
@@ -0,0 +1,84 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+type archivedRun struct {
+	runID string
+	posts []models.TransformedPost
+}
+
+type recordingArchiver struct {
+	runs []archivedRun
+	err  error
+}
+
+func (a *recordingArchiver) ArchiveRun(ctx context.Context, runID string, posts []models.TransformedPost) error {
+	a.runs = append(a.runs, archivedRun{runID: runID, posts: posts})
+	return a.err
+}
+
+func TestService_IngestData_ArchivesRuns(t *testing.T) {
+	cycle := 0
+
+	// Create mock server returning a different page of posts each cycle
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cycle++
+		posts := []models.Post{{UserID: 1, ID: cycle * 10, Title: "First"}, {UserID: 1, ID: cycle*10 + 1, Title: "Second"}}
+		if cycle == 2 {
+			posts = posts[:1]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(posts)
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:      server.URL,
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		StoreRetryCount:  1,
+		StampRunID:       true,
+		ErrorHistorySize: 5,
+	}
+	archiver := &recordingArchiver{}
+	service := NewService(cfg, mockStorage, WithRunArchiver(archiver))
+
+	// Test every run is archived once, with the posts it stored
+	require.NoError(t, service.IngestData(context.Background()))
+	require.NoError(t, service.IngestData(context.Background()))
+	require.Len(t, archiver.runs, 2)
+	assert.Len(t, archiver.runs[0].posts, 2)
+	assert.Len(t, archiver.runs[1].posts, 1)
+	assert.NotEqual(t, archiver.runs[0].runID, archiver.runs[1].runID)
+	for _, run := range archiver.runs {
+		for _, post := range run.posts {
+			assert.Equal(t, run.runID, post.RunID)
+		}
+	}
+
+	// Test a failed archive is recorded without failing the run
+	archiver.err = errors.New("access denied")
+	require.NoError(t, service.IngestData(context.Background()))
+	require.Len(t, archiver.runs, 3)
+	errs := service.RecentErrors()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "failed to archive run "+archiver.runs[2].runID)
+}
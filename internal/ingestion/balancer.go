@@ -0,0 +1,135 @@
+package ingestion
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// endpointBalancer spreads fetches across equivalent upstreams using smooth
+// weighted round-robin: over any run of total-weight picks each endpoint is
+// chosen exactly weight times, with its picks interleaved with the others.
+//
+// When probeInterval is set, an endpoint that fails is demoted for that long:
+// it is only tried after every healthy endpoint has failed. Once the interval
+// passes it rejoins the rotation, and its next fetch serves as a probe that
+// either promotes it again or renews the demotion.
+type endpointBalancer struct {
+	mu            sync.Mutex
+	endpoints     []config.WeightedEndpoint
+	current       []int // Running score per endpoint
+	health        []models.EndpointHealth
+	probeInterval time.Duration
+}
+
+// newEndpointBalancer returns a balancer over endpoints, or nil if there are none
+func newEndpointBalancer(endpoints []config.WeightedEndpoint, probeInterval time.Duration) *endpointBalancer {
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	b := &endpointBalancer{
+		endpoints:     endpoints,
+		current:       make([]int, len(endpoints)),
+		health:        make([]models.EndpointHealth, len(endpoints)),
+		probeInterval: probeInterval,
+	}
+	for i, endpoint := range endpoints {
+		b.health[i] = models.EndpointHealth{URL: endpoint.URL, Healthy: true}
+	}
+	return b
+}
+
+// next selects the endpoint for a fetch and returns every endpoint in the
+// order to try them: the selected one first, then the rest in list order
+// after it so a failing endpoint is skipped rather than retried. Demoted
+// endpoints are left out of the selection and come last.
+func (b *endpointBalancer) next(now time.Time) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	eligible := make([]bool, len(b.endpoints))
+	anyEligible := false
+	for i := range b.endpoints {
+		eligible[i] = !b.demoted(i, now)
+		anyEligible = anyEligible || eligible[i]
+	}
+	if !anyEligible {
+		// Everything is demoted; rotate through all of them as usual
+		for i := range eligible {
+			eligible[i] = true
+		}
+	}
+
+	selected, total := -1, 0
+	for i, endpoint := range b.endpoints {
+		if !eligible[i] {
+			continue
+		}
+		total += endpoint.Weight
+		b.current[i] += endpoint.Weight
+		if selected < 0 || b.current[i] > b.current[selected] {
+			selected = i
+		}
+	}
+	b.current[selected] -= total
+
+	order := make([]string, 0, len(b.endpoints))
+	var demoted []string
+	for i := range b.endpoints {
+		idx := (selected + i) % len(b.endpoints)
+		if eligible[idx] {
+			order = append(order, b.endpoints[idx].URL)
+		} else {
+			demoted = append(demoted, b.endpoints[idx].URL)
+		}
+	}
+	return append(order, demoted...)
+}
+
+// demoted reports whether endpoint i failed within the probe interval;
+// callers must hold mu
+func (b *endpointBalancer) demoted(i int, now time.Time) bool {
+	until := b.health[i].DemotedUntil
+	return until != nil && now.Before(*until)
+}
+
+// record updates an endpoint's health with the outcome of a fetch from it
+func (b *endpointBalancer) record(url string, err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.health {
+		if b.health[i].URL != url {
+			continue
+		}
+		health := &b.health[i]
+		if err == nil {
+			health.Healthy = true
+			health.ConsecutiveFailures = 0
+			health.LastSuccess = &now
+			health.DemotedUntil = nil
+			return
+		}
+
+		health.Healthy = false
+		health.ConsecutiveFailures++
+		health.LastFailure = &now
+		health.LastError = err.Error()
+		if b.probeInterval > 0 {
+			until := now.Add(b.probeInterval)
+			health.DemotedUntil = &until
+		}
+		return
+	}
+}
+
+// snapshot returns a copy of every endpoint's health
+func (b *endpointBalancer) snapshot() []models.EndpointHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]models.EndpointHealth(nil), b.health...)
+}
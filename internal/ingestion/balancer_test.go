@@ -0,0 +1,210 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// newMirror starts a mock upstream that counts the fetches it serves
+func newMirror(t *testing.T, status int) (*httptest.Server, *atomic.Int64) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Mirrored"}})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestEndpointBalancer_Order(t *testing.T) {
+	balancer := newEndpointBalancer([]config.WeightedEndpoint{
+		{URL: "a", Weight: 2},
+		{URL: "b", Weight: 1},
+	}, 0)
+	now := time.Now()
+
+	// Test picks follow the weights and are interleaved
+	assert.Equal(t, []string{"a", "b"}, balancer.next(now))
+	assert.Equal(t, []string{"b", "a"}, balancer.next(now))
+	assert.Equal(t, []string{"a", "b"}, balancer.next(now))
+
+	assert.Nil(t, newEndpointBalancer(nil, 0))
+}
+
+func TestService_fetchPosts_WeightedMirrors(t *testing.T) {
+	// Create three mirrors weighted 5:3:2
+	mirrorA, callsA := newMirror(t, http.StatusOK)
+	mirrorB, callsB := newMirror(t, http.StatusOK)
+	mirrorC, callsC := newMirror(t, http.StatusOK)
+
+	cfg := config.IngestionConfig{
+		APIEndpoints: []config.WeightedEndpoint{
+			{URL: mirrorA.URL, Weight: 5},
+			{URL: mirrorB.URL, Weight: 3},
+			{URL: mirrorC.URL, Weight: 2},
+		},
+		Timeout:    30 * time.Second,
+		RetryCount: 1,
+	}
+	service := NewService(cfg, nil)
+
+	// Test fetches are spread across mirrors by weight
+	const fetches = 200
+	for i := 0; i < fetches; i++ {
+		posts, source, err := service.fetchPosts(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, posts, 1)
+		assert.Equal(t, primarySource, source)
+	}
+
+	assert.InDelta(t, 0.5*fetches, callsA.Load(), 0.05*fetches)
+	assert.InDelta(t, 0.3*fetches, callsB.Load(), 0.05*fetches)
+	assert.InDelta(t, 0.2*fetches, callsC.Load(), 0.05*fetches)
+}
+
+func TestService_fetchPosts_SkipsFailingMirror(t *testing.T) {
+	// Create a failing mirror alongside a healthy one
+	failing, failingCalls := newMirror(t, http.StatusBadGateway)
+	healthy, healthyCalls := newMirror(t, http.StatusOK)
+
+	cfg := config.IngestionConfig{
+		APIEndpoints: []config.WeightedEndpoint{
+			{URL: failing.URL, Weight: 1},
+			{URL: healthy.URL, Weight: 1},
+		},
+		Timeout:    30 * time.Second,
+		RetryCount: 1,
+	}
+	service := NewService(cfg, nil)
+
+	// Test every fetch succeeds by skipping past the failing mirror
+	for i := 0; i < 4; i++ {
+		posts, _, err := service.fetchPosts(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, posts, 1)
+	}
+
+	assert.Equal(t, int64(2), failingCalls.Load())
+	assert.Equal(t, int64(4), healthyCalls.Load())
+}
+
+func TestService_fetchPosts_AllMirrorsFail(t *testing.T) {
+	mirrorA, _ := newMirror(t, http.StatusInternalServerError)
+	mirrorB, _ := newMirror(t, http.StatusServiceUnavailable)
+
+	cfg := config.IngestionConfig{
+		APIEndpoints: []config.WeightedEndpoint{
+			{URL: mirrorA.URL, Weight: 1},
+			{URL: mirrorB.URL, Weight: 1},
+		},
+		Timeout:    30 * time.Second,
+		RetryCount: 1,
+	}
+	service := NewService(cfg, nil)
+
+	_, _, err := service.fetchPosts(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status 500")
+	assert.Contains(t, err.Error(), "status 503")
+}
+
+func TestService_fetchPosts_DemotesAndRepromotesMirror(t *testing.T) {
+	// Create a mirror whose availability can be switched, alongside a
+	// healthy one
+	var flakyDown atomic.Bool
+	var flakyCalls atomic.Int64
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flakyCalls.Add(1)
+		if flakyDown.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Flaky"}})
+	}))
+	defer flaky.Close()
+	healthy, healthyCalls := newMirror(t, http.StatusOK)
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	cfg := config.IngestionConfig{
+		APIEndpoints: []config.WeightedEndpoint{
+			{URL: flaky.URL, Weight: 1},
+			{URL: healthy.URL, Weight: 1},
+		},
+		EndpointProbeInterval: time.Minute,
+		Timeout:               30 * time.Second,
+		RetryCount:            1,
+	}
+	service := NewService(cfg, nil, WithClock(func() time.Time { return now }))
+
+	// Test a failure demotes the mirror
+	flakyDown.Store(true)
+	_, _, err := service.fetchPosts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), flakyCalls.Load())
+
+	health := service.EndpointHealth()
+	assert.False(t, health[0].Healthy)
+	assert.Equal(t, 1, health[0].ConsecutiveFailures)
+	assert.Contains(t, health[0].LastError, "status 502")
+	if assert.NotNil(t, health[0].DemotedUntil) {
+		assert.Equal(t, now.Add(time.Minute), *health[0].DemotedUntil)
+	}
+	assert.True(t, health[1].Healthy)
+
+	// Test the demoted mirror is passed over while the healthy one serves
+	for i := 0; i < 4; i++ {
+		_, _, err := service.fetchPosts(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), flakyCalls.Load())
+	assert.Equal(t, int64(5), healthyCalls.Load())
+
+	// Test the mirror is probed after the interval and re-promoted on recovery
+	flakyDown.Store(false)
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		_, _, err := service.fetchPosts(context.Background())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), flakyCalls.Load())
+
+	health = service.EndpointHealth()
+	assert.True(t, health[0].Healthy)
+	assert.Zero(t, health[0].ConsecutiveFailures)
+	assert.Nil(t, health[0].DemotedUntil)
+}
+
+func TestEndpointBalancer_AllDemoted(t *testing.T) {
+	balancer := newEndpointBalancer([]config.WeightedEndpoint{
+		{URL: "a", Weight: 1},
+		{URL: "b", Weight: 1},
+	}, time.Minute)
+	now := time.Now()
+	balancer.record("b", errors.New("down"), now)
+
+	// Test a demoted endpoint is tried last
+	assert.Equal(t, []string{"a", "b"}, balancer.next(now))
+	assert.Equal(t, []string{"a", "b"}, balancer.next(now))
+
+	// Test every endpoint is still tried when all are demoted
+	balancer.record("a", errors.New("down"), now)
+	assert.ElementsMatch(t, []string{"a", "b"}, balancer.next(now))
+}
@@ -0,0 +1,64 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// ingestComments fetches and stores the comments of each stored post. The
+// posts are already stored, so failures are logged and recorded rather than
+// failing the cycle.
+func (s *Service) ingestComments(ctx context.Context, posts []models.TransformedPost) {
+	for _, post := range posts {
+		comments, err := s.fetchComments(ctx, post.ID)
+		if err == nil && len(comments) > 0 {
+			err = s.storage.StoreComments(ctx, comments)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to ingest comments of post %d: %w", post.ID, err)
+			s.logger.Warn("Comment ingestion error", "post_id", post.ID, "error", err)
+			s.recordError(err)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// fetchComments fetches a post's comments from {APIEndpoint}/{id}/comments
+func (s *Service) fetchComments(ctx context.Context, postID int) ([]models.Comment, error) {
+	endpoint := strings.TrimSuffix(s.config.APIEndpoint, "/") + "/" + strconv.Itoa(postID) + "/comments"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	var comments []models.Comment
+	if err := json.NewDecoder(resp.Body).Decode(&comments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal comments: %w", err)
+	}
+
+	now := s.now().UTC()
+	for i := range comments {
+		comments[i].PostID = postID
+		comments[i].IngestedAt = now
+	}
+	return comments, nil
+}
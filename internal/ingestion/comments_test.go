@@ -0,0 +1,104 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// newCommentsServer serves two posts and the comments of each
+func newCommentsServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/posts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{
+			{UserID: 1, ID: 1, Title: "First"},
+			{UserID: 1, ID: 2, Title: "Second"},
+		})
+	})
+	mux.HandleFunc("/posts/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"postId": 1, "id": 1, "name": "a", "email": "a@example.com", "body": "Nice"},
+			{"postId": 1, "id": 2, "name": "b", "email": "b@example.com", "body": "Agreed"}]`))
+	})
+	mux.HandleFunc("/posts/2/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestService_fetchComments(t *testing.T) {
+	server := newCommentsServer(t)
+
+	ingestedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	cfg := config.IngestionConfig{APIEndpoint: server.URL + "/posts/", Timeout: 30 * time.Second}
+	service := NewService(cfg, nil, WithClock(func() time.Time { return ingestedAt }))
+
+	// Test fetching a post's comments
+	comments, err := service.fetchComments(context.Background(), 1)
+
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 2) {
+		assert.Equal(t, models.Comment{PostID: 1, ID: 2, Name: "b", Email: "b@example.com", Body: "Agreed", IngestedAt: ingestedAt}, comments[1])
+	}
+
+	// Test upstream errors
+	_, err = service.fetchComments(context.Background(), 2)
+
+	assert.Error(t, err)
+}
+
+func TestService_IngestData_Comments(t *testing.T) {
+	server := newCommentsServer(t)
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+	mockStorage.On("StoreComments", mock.Anything, mock.MatchedBy(func(comments []models.Comment) bool {
+		return len(comments) == 2 && comments[0].PostID == 1 && comments[1].Body == "Agreed"
+	})).Return(nil).Once()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:      server.URL + "/posts",
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		IngestComments:   true,
+		ErrorHistorySize: 5,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test comments are stored and a failing post doesn't fail the cycle
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+	if errs := service.RecentErrors(); assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Message, "comments of post 2")
+	}
+}
+
+func TestService_IngestData_CommentsDisabled(t *testing.T) {
+	server := newCommentsServer(t)
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	cfg := config.IngestionConfig{APIEndpoint: server.URL + "/posts", Timeout: 30 * time.Second, RetryCount: 1}
+	service := NewService(cfg, mockStorage)
+
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	mockStorage.AssertNotCalled(t, "StoreComments", mock.Anything, mock.Anything)
+}
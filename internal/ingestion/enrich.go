@@ -0,0 +1,118 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// userCache keeps looked-up user records across cycles, so an author is
+// fetched once per UserLookupCacheTTL rather than once per post
+type userCache struct {
+	mu      sync.Mutex
+	entries map[int]cachedUser
+}
+
+type cachedUser struct {
+	fields  map[string]any
+	fetched time.Time
+}
+
+func newUserCache() *userCache {
+	return &userCache{entries: make(map[int]cachedUser)}
+}
+
+// enrichPosts attaches each post's author record, looking up every distinct
+// userId at most once. A failed lookup is logged and recorded, leaving that
+// user's posts unenriched rather than failing the cycle.
+func (s *Service) enrichPosts(ctx context.Context, posts []models.TransformedPost) {
+	users := make(map[int]map[string]any)
+	for i := range posts {
+		userID := posts[i].UserID
+		fields, looked := users[userID]
+		if !looked {
+			var err error
+			if fields, err = s.lookupUser(ctx, userID); err != nil {
+				err = fmt.Errorf("failed to look up user %d: %w", userID, err)
+				s.logger.Warn("User enrichment error", "user_id", userID, "error", err)
+				s.recordError(err)
+				if ctx.Err() != nil {
+					return
+				}
+			}
+			users[userID] = fields
+		}
+		posts[i].User = fields
+	}
+}
+
+// lookupUser returns the selected fields of a user, from the cache while
+// it is fresh
+func (s *Service) lookupUser(ctx context.Context, userID int) (map[string]any, error) {
+	now := s.now()
+	s.users.mu.Lock()
+	cached, ok := s.users.entries[userID]
+	s.users.mu.Unlock()
+	if ok && now.Sub(cached.fetched) < s.config.UserLookupCacheTTL {
+		return cached.fields, nil
+	}
+
+	fields, err := s.fetchUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.mu.Lock()
+	s.users.entries[userID] = cachedUser{fields: fields, fetched: now}
+	s.users.mu.Unlock()
+	return fields, nil
+}
+
+// fetchUser fetches a user from UserLookupEndpoint, keeping UserLookupFields
+func (s *Service) fetchUser(ctx context.Context, userID int) (map[string]any, error) {
+	id := strconv.Itoa(userID)
+	endpoint := s.config.UserLookupEndpoint
+	if strings.Contains(endpoint, "{userId}") {
+		endpoint = strings.ReplaceAll(endpoint, "{userId}", id)
+	} else {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/" + id
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	var user map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal user: %w", err)
+	}
+
+	if len(s.config.UserLookupFields) == 0 {
+		return user, nil
+	}
+	selected := make(map[string]any, len(s.config.UserLookupFields))
+	for _, field := range s.config.UserLookupFields {
+		if value, ok := user[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,106 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// newUserAPI serves /users/{id}, counting the lookups of each user
+func newUserAPI(t *testing.T) (*httptest.Server, map[string]int) {
+	lookups := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/users/")
+		lookups[id]++
+		if id == "404" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":       id,
+			"name":     "User " + id,
+			"email":    "user" + id + "@example.com",
+			"password": "secret",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, lookups
+}
+
+func TestService_enrichPosts(t *testing.T) {
+	users, lookups := newUserAPI(t)
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	cfg := config.IngestionConfig{
+		UserLookupEndpoint: users.URL + "/users/{userId}",
+		UserLookupFields:   []string{"name", "email"},
+		UserLookupCacheTTL: time.Hour,
+		ErrorHistorySize:   5,
+	}
+	service := NewService(cfg, nil, WithClock(func() time.Time { return now }))
+
+	posts := []models.TransformedPost{
+		{Post: models.Post{UserID: 1, ID: 1}},
+		{Post: models.Post{UserID: 2, ID: 2}},
+		{Post: models.Post{UserID: 1, ID: 3}},
+		{Post: models.Post{UserID: 404, ID: 4}},
+	}
+
+	// Test posts get their author's selected fields
+	service.enrichPosts(context.Background(), posts)
+
+	assert.Equal(t, map[string]any{"name": "User 1", "email": "user1@example.com"}, posts[0].User)
+	assert.Equal(t, map[string]any{"name": "User 2", "email": "user2@example.com"}, posts[1].User)
+	assert.Equal(t, posts[0].User, posts[2].User)
+	assert.Nil(t, posts[3].User)
+	assert.Len(t, service.RecentErrors(), 1)
+
+	// Test repeated userIds, within and across cycles, hit the cache
+	service.enrichPosts(context.Background(), []models.TransformedPost{{Post: models.Post{UserID: 1, ID: 5}}})
+	assert.Equal(t, map[string]int{"1": 1, "2": 1, "404": 1}, lookups)
+
+	// Test users are looked up again once the cache expires
+	now = now.Add(2 * time.Hour)
+	service.enrichPosts(context.Background(), []models.TransformedPost{{Post: models.Post{UserID: 1, ID: 6}}})
+	assert.Equal(t, 2, lookups["1"])
+}
+
+func TestService_IngestData_UserEnrichment(t *testing.T) {
+	users, lookups := newUserAPI(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 7, ID: 1}, {UserID: 7, ID: 2}})
+	}))
+	defer upstream.Close()
+
+	// Create mock storage expecting enriched posts
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.MatchedBy(func(posts []models.TransformedPost) bool {
+		return len(posts) == 2 && posts[0].User["name"] == "User 7" && posts[1].User["name"] == "User 7"
+	})).Return(nil).Once()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:        upstream.URL,
+		Timeout:            30 * time.Second,
+		RetryCount:         1,
+		UserLookupEndpoint: users.URL + "/users",
+		UserLookupCacheTTL: time.Hour,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test the stored posts carry the whole user record, looked up once
+	assert.NoError(t, service.IngestData(context.Background()))
+	assert.Equal(t, map[string]int{"7": 1}, lookups)
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,67 @@
+package ingestion
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// FetchLimiter bounds the number of concurrent upstream requests. Services
+// given the same limiter share its slots, so together their sources never
+// exceed it.
+type FetchLimiter struct {
+	slots chan struct{}
+}
+
+// NewFetchLimiter allows maxConcurrent upstream requests at once
+func NewFetchLimiter(maxConcurrent int) *FetchLimiter {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &FetchLimiter{slots: make(chan struct{}, maxConcurrent)}
+}
+
+// WithFetchLimiter makes every upstream request take a slot of limiter,
+// taking precedence over GlobalFetchConcurrency
+func WithFetchLimiter(limiter *FetchLimiter) Option {
+	return func(s *Service) {
+		s.fetchLimiter = limiter
+	}
+}
+
+// limitedDoer holds a limiter slot from sending a request until its response
+// body is closed, so slow reads count against the limit too
+type limitedDoer struct {
+	next    Doer
+	limiter *FetchLimiter
+}
+
+func (d *limitedDoer) Do(req *http.Request) (*http.Response, error) {
+	select {
+	case d.limiter.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	var once sync.Once
+	release := func() { once.Do(func() { <-d.limiter.slots }) }
+
+	resp, err := d.next.Do(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases its request's limiter slot when closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
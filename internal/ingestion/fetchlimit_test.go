@@ -0,0 +1,81 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestFetchLimiter_SharedAcrossSources(t *testing.T) {
+	// Create mock server recording the most requests it served at once
+	var inFlight, peak, served atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		served.Add(1)
+
+		time.Sleep(20 * time.Millisecond)
+		id, _ := strconv.Atoi(r.URL.Query().Get("id_gte"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: id, Title: "Test Post"}})
+	}))
+	defer server.Close()
+
+	// Create three sources, each fetching four shards in parallel
+	limiter := NewFetchLimiter(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		cfg := config.IngestionConfig{
+			APIEndpoint:     server.URL + "/source" + strconv.Itoa(i),
+			Timeout:         30 * time.Second,
+			RetryCount:      1,
+			ShardCount:      4,
+			ShardIDStart:    1,
+			ShardIDEnd:      4,
+			ShardStartParam: "id_gte",
+			ShardEndParam:   "id_lte",
+		}
+		service := NewService(cfg, nil, WithFetchLimiter(limiter))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			posts, _, err := service.fetchPosts(context.Background())
+			assert.NoError(t, err)
+			assert.Len(t, posts, 4)
+		}()
+	}
+	wg.Wait()
+
+	// Test the sources together stayed within the shared limit
+	assert.Equal(t, int64(12), served.Load())
+	assert.Equal(t, int64(2), peak.Load())
+	assert.Empty(t, limiter.slots, "every slot should be released")
+}
+
+func TestFetchLimiter_Cancelled(t *testing.T) {
+	limiter := NewFetchLimiter(1)
+	limiter.slots <- struct{}{} // Hold the only slot
+
+	service := NewService(config.IngestionConfig{APIEndpoint: "http://upstream.invalid", RetryCount: 1}, nil, WithFetchLimiter(limiter))
+
+	// Test a request waiting for a slot gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := service.fetchPostsOnce(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,50 @@
+package ingestion
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type forwardedHeadersKey struct{}
+
+// WithForwardedHeaders returns a context carrying the headers of the request
+// that triggered an ingestion. Headers matching the configured ForwardHeaders
+// are propagated to the upstream, e.g. to keep a trace intact.
+func WithForwardedHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, forwardedHeadersKey{}, header.Clone())
+}
+
+// forwardHeaders copies the configured headers from the context onto req
+func (s *Service) forwardHeaders(ctx context.Context, req *http.Request) {
+	header, ok := ctx.Value(forwardedHeadersKey{}).(http.Header)
+	if !ok {
+		return
+	}
+
+	for name, values := range header {
+		if !matchesHeader(s.config.ForwardHeaders, name) {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
+
+// matchesHeader reports whether name matches one of the patterns,
+// case-insensitively. A pattern ending in "*" matches by prefix.
+func matchesHeader(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package ingestion
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// redactedValue replaces the values of headers listed in RedactHeaders
+const redactedValue = "[REDACTED]"
+
+// recordLastFetch keeps the status and headers of an upstream response,
+// with sensitive headers redacted
+func (s *Service) recordLastFetch(requestURL *url.URL, resp *http.Response) {
+	u := *requestURL
+	u.RawQuery = ""
+	u.User = nil
+
+	headers := resp.Header.Clone()
+	for _, name := range s.config.RedactHeaders {
+		key := http.CanonicalHeaderKey(name)
+		if _, ok := headers[key]; ok {
+			headers[key] = []string{redactedValue}
+		}
+	}
+
+	record := &models.FetchRecord{
+		Time:    s.now().UTC(),
+		URL:     u.String(),
+		Status:  resp.StatusCode,
+		Headers: headers,
+	}
+
+	s.lastFetchMu.Lock()
+	s.lastFetch = record
+	s.lastFetchMu.Unlock()
+}
+
+// LastFetch returns the most recent upstream response, or nil if none has
+// arrived. The record is shared and must not be modified.
+func (s *Service) LastFetch() *models.FetchRecord {
+	s.lastFetchMu.Lock()
+	defer s.lastFetchMu.Unlock()
+	return s.lastFetch
+}
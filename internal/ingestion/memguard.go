@@ -0,0 +1,52 @@
+package ingestion
+
+import (
+	"errors"
+	"runtime"
+)
+
+// ErrMemoryPressure is returned instead of running a cycle while heap usage
+// is above MaxHeapBytes
+var ErrMemoryPressure = errors.New("ingestion shed: heap usage is above the maximum")
+
+// HeapStats reports the bytes of allocated heap objects
+type HeapStats func() uint64
+
+// runtimeHeapStats reads the heap in use from the Go runtime
+func runtimeHeapStats() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// WithHeapStats replaces the runtime's memory stats in the memory guard,
+// e.g. to simulate memory pressure in tests
+func WithHeapStats(heapStats HeapStats) Option {
+	return func(s *Service) {
+		s.heapStats = heapStats
+	}
+}
+
+// shedLoad reports whether the next cycle should be skipped because heap
+// usage is above MaxHeapBytes, warning when it is. The guard is re-checked
+// every cycle, so ingestion resumes once memory is freed.
+func (s *Service) shedLoad() bool {
+	if s.config.MaxHeapBytes <= 0 {
+		return false
+	}
+
+	heap := s.heapStats()
+	shedding := heap > uint64(s.config.MaxHeapBytes)
+	if shedding {
+		s.logger.Warn("Skipping ingestion cycle under memory pressure", "heap_bytes", heap, "max_heap_bytes", s.config.MaxHeapBytes)
+	} else if s.shedding.Load() {
+		s.logger.Info("Memory pressure relieved, resuming ingestion", "heap_bytes", heap)
+	}
+	s.shedding.Store(shedding)
+	return shedding
+}
+
+// SheddingLoad reports whether the last cycle was skipped under memory pressure
+func (s *Service) SheddingLoad() bool {
+	return s.shedding.Load()
+}
@@ -0,0 +1,55 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestService_IngestData_MemoryGuard(t *testing.T) {
+	var fetches atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}})
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		APIEndpoint:  server.URL,
+		Timeout:      30 * time.Second,
+		RetryCount:   1,
+		MaxHeapBytes: 1 << 30,
+	}
+
+	// Simulate a heap above the maximum
+	var heap atomic.Uint64
+	heap.Store(2 << 30)
+	service := NewService(cfg, mockStorage, WithHeapStats(heap.Load))
+
+	// Test the cycle is skipped before fetching anything
+	err := service.IngestData(context.Background())
+	assert.ErrorIs(t, err, ErrMemoryPressure)
+	assert.Equal(t, int64(0), fetches.Load())
+	assert.True(t, service.SheddingLoad())
+
+	// Test the cycle runs once memory is freed
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil).Once()
+	heap.Store(512 << 20)
+
+	assert.NoError(t, service.IngestData(context.Background()))
+	assert.Equal(t, int64(1), fetches.Load())
+	assert.False(t, service.SheddingLoad())
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,105 @@
+package ingestion
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sourceLabel names the upstream a metric is attributed to, as recorded on
+// the posts fetched from it
+const sourceLabel = "source"
+
+// metrics holds the ingestion Prometheus collectors. They are always
+// usable; WithMetrics registers them so they are exported.
+type metrics struct {
+	slowFetches        *prometheus.CounterVec
+	fetchFailures      *prometheus.CounterVec
+	postsIngested      *prometheus.CounterVec
+	rateLimitRemaining *prometheus.GaugeVec
+	rateLimitLimit     *prometheus.GaugeVec
+	fetchDuration      *prometheus.HistogramVec
+	storeDuration      *prometheus.HistogramVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		slowFetches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "slow_fetches_total",
+			Help: "Upstream fetches that succeeded but took longer than the slow fetch threshold.",
+		}, []string{sourceLabel}),
+		fetchFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fetch_failures_total",
+			Help: "Upstream fetches that failed after exhausting their retries.",
+		}, []string{sourceLabel}),
+		postsIngested: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "posts_ingested_total",
+			Help: "Posts fetched and stored by scheduled ingestion.",
+		}, []string{sourceLabel}),
+		rateLimitRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upstream_rate_limit_remaining",
+			Help: "Requests left in the upstream's rate-limit window, from its latest response.",
+		}, []string{sourceLabel}),
+		rateLimitLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "upstream_rate_limit_limit",
+			Help: "Size of the upstream's rate-limit window, from its latest response.",
+		}, []string{sourceLabel}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "fetch_duration_seconds",
+			Help:    "Time taken by successful upstream fetches, from request to decoded posts.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{sourceLabel}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "store_duration_seconds",
+			Help:    "Time taken to store each batch of posts, retries included.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{sourceLabel}),
+	}
+}
+
+// collectors lists every collector for registration
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{m.slowFetches, m.fetchFailures, m.postsIngested, m.rateLimitRemaining, m.rateLimitLimit,
+		m.fetchDuration, m.storeDuration}
+}
+
+// observe records d on observer. With MetricsExemplars set it attaches the
+// trace ID of the request that triggered the ingestion, if it carried one, so
+// a slow bucket links to its trace.
+func (s *Service) observe(ctx context.Context, observer prometheus.Observer, d time.Duration) {
+	if s.config.MetricsExemplars {
+		if exemplar, ok := observer.(prometheus.ExemplarObserver); ok {
+			if traceID := traceIDFrom(ctx); traceID != "" {
+				exemplar.ObserveWithExemplar(d.Seconds(), prometheus.Labels{"trace_id": traceID})
+				return
+			}
+		}
+	}
+	observer.Observe(d.Seconds())
+}
+
+// traceIDFrom returns the trace ID of the W3C traceparent header forwarded in
+// ctx, or "" if there is none or it is malformed
+func traceIDFrom(ctx context.Context) string {
+	header, _ := ctx.Value(forwardedHeadersKey{}).(http.Header)
+	parts := strings.Split(header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+	return parts[1]
+}
+
+// WithMetrics registers the ingestion metrics with registerer
+func WithMetrics(registerer prometheus.Registerer) Option {
+	return func(s *Service) {
+		registerer.MustRegister(s.metrics.collectors()...)
+	}
+}
@@ -0,0 +1,294 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestService_fetchPostsOnce_SlowFetch(t *testing.T) {
+	var delay atomic.Int64
+	delay.Store(int64(50 * time.Millisecond))
+
+	// Create mock server that responds slowly
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(delay.Load()))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Slow"}})
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	registry := prometheus.NewRegistry()
+	cfg := config.IngestionConfig{
+		APIEndpoint:        server.URL,
+		Timeout:            30 * time.Second,
+		SlowFetchThreshold: 10 * time.Millisecond,
+	}
+	service := NewService(cfg, nil,
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithMetrics(registry),
+	)
+
+	// Test a slow fetch still succeeds but is flagged
+	posts, err := service.fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Contains(t, logs.String(), "Slow upstream fetch")
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.metrics.slowFetches.WithLabelValues(primarySource)))
+
+	// Test a fast fetch is not flagged
+	delay.Store(0)
+	logs.Reset()
+	_, err = service.fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.NotContains(t, logs.String(), "Slow upstream fetch")
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.metrics.slowFetches.WithLabelValues(primarySource)))
+
+	count, err := testutil.GatherAndCount(registry, "slow_fetches_total")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestService_fetchPostsOnce_RateLimitHeaders(t *testing.T) {
+	var remaining atomic.Int64
+	remaining.Store(59)
+
+	// Create mock server reporting its rate-limit budget
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining.Load(), 10))
+		if remaining.Load() == 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post"}})
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	registry := prometheus.NewRegistry()
+	cfg := config.IngestionConfig{
+		APIEndpoint:              server.URL,
+		Timeout:                  30 * time.Second,
+		RateLimitRemainingHeader: "X-RateLimit-Remaining",
+		RateLimitLimitHeader:     "X-RateLimit-Limit",
+	}
+	service := NewService(cfg, nil, WithMetrics(registry), WithClock(func() time.Time { return now }))
+	assert.Nil(t, service.RateLimit())
+
+	// Test the budget is recorded from a successful response
+	_, err := service.fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 59.0, testutil.ToFloat64(service.metrics.rateLimitRemaining.WithLabelValues(primarySource)))
+	assert.Equal(t, 60.0, testutil.ToFloat64(service.metrics.rateLimitLimit.WithLabelValues(primarySource)))
+	assert.Equal(t, &models.RateLimit{Remaining: 59, Limit: 60, ObservedAt: now}, service.RateLimit())
+
+	// Test the budget is recorded from a rate-limited response too
+	remaining.Store(0)
+	_, err = service.fetchPostsOnce(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0.0, testutil.ToFloat64(service.metrics.rateLimitRemaining.WithLabelValues(primarySource)))
+	assert.Equal(t, 0, service.RateLimit().Remaining)
+}
+
+func TestService_IngestData_SourceLabels(t *testing.T) {
+	// Create a failing primary and a fallback, each reporting a rate limit
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "5")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "40")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "First"}, {UserID: 1, ID: 2, Title: "Second"}})
+	}))
+	defer fallback.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	var logs bytes.Buffer
+	registry := prometheus.NewRegistry()
+	cfg := config.IngestionConfig{
+		APIEndpoint:              primary.URL,
+		FallbackAPIEndpoint:      fallback.URL,
+		SourceName:               "primary_feed",
+		FallbackSourceName:       "backup_feed",
+		Timeout:                  30 * time.Second,
+		RetryCount:               1,
+		RateLimitRemainingHeader: "X-RateLimit-Remaining",
+	}
+	service := NewService(cfg, mockStorage,
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithMetrics(registry),
+	)
+
+	// Test each source's metrics carry its own label
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(service.metrics.fetchFailures), "only the primary failed")
+	assert.Equal(t, 1.0, testutil.ToFloat64(service.metrics.fetchFailures.WithLabelValues("primary_feed")))
+	assert.Equal(t, 1, testutil.CollectAndCount(service.metrics.postsIngested), "only the fallback stored posts")
+	assert.Equal(t, 2.0, testutil.ToFloat64(service.metrics.postsIngested.WithLabelValues("backup_feed")))
+	assert.Equal(t, 5.0, testutil.ToFloat64(service.metrics.rateLimitRemaining.WithLabelValues("primary_feed")))
+	assert.Equal(t, 40.0, testutil.ToFloat64(service.metrics.rateLimitRemaining.WithLabelValues("backup_feed")))
+
+	// Test the cycle's log lines name the source the posts came from
+	assert.Contains(t, logs.String(), `msg="Successfully ingested posts" source=backup_feed count=2`)
+	assert.Contains(t, logs.String(), `msg="Primary upstream failed, trying fallback" source=primary_feed`)
+}
+
+// exemplarTraceIDs returns the trace IDs of the exemplars on the buckets of
+// the named histogram
+func exemplarTraceIDs(t *testing.T, registry *prometheus.Registry, name string) []string {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var ids []string
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				for _, label := range bucket.GetExemplar().GetLabel() {
+					if label.GetName() == "trace_id" {
+						ids = append(ids, label.GetValue())
+					}
+				}
+			}
+		}
+	}
+	return ids
+}
+
+func TestService_fetchPostsOnce_Exemplars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Traced"}})
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{APIEndpoint: server.URL, Timeout: 30 * time.Second, MetricsExemplars: true}
+	registry := prometheus.NewRegistry()
+	service := NewService(cfg, nil, WithMetrics(registry))
+
+	// Test a fetch outside a trace records no exemplar
+	_, err := service.fetchPostsOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, exemplarTraceIDs(t, registry, "fetch_duration_seconds"))
+
+	// Test a fetch within a trace records its trace ID
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, err = service.fetchPostsOnce(WithForwardedHeaders(context.Background(), header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4bf92f3577b34da6a3ce929d0e0e4736"}, exemplarTraceIDs(t, registry, "fetch_duration_seconds"))
+
+	// Test exemplars are left out unless enabled
+	registry = prometheus.NewRegistry()
+	cfg.MetricsExemplars = false
+	service = NewService(cfg, nil, WithMetrics(registry))
+	_, err = service.fetchPostsOnce(WithForwardedHeaders(context.Background(), header))
+	assert.NoError(t, err)
+	assert.Empty(t, exemplarTraceIDs(t, registry, "fetch_duration_seconds"))
+}
+
+func TestTraceIDFrom(t *testing.T) {
+	tests := []struct {
+		traceparent string
+		want        string
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", ""},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ""},
+		{"00-4bf92f35-00f067aa0ba902b7-01", ""},
+		{"garbage", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		header.Set("traceparent", tt.traceparent)
+		assert.Equal(t, tt.want, traceIDFrom(WithForwardedHeaders(context.Background(), header)), tt.traceparent)
+	}
+	assert.Empty(t, traceIDFrom(context.Background()))
+}
+
+func TestService_PushMetrics(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Pushed"}})
+	}))
+	defer upstream.Close()
+
+	// Create mock gateway recording each push
+	var pushes []string
+	var pushed []byte
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes = append(pushes, r.Method+" "+r.URL.Path)
+		pushed, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         upstream.URL,
+		Timeout:             30 * time.Second,
+		RetryCount:          1,
+		MaxCycles:           1,
+		PushgatewayURL:      gateway.URL,
+		PushgatewayJob:      "ingest-job",
+		PushgatewayInstance: "runner-1",
+	}
+	registry := prometheus.NewRegistry()
+	service := NewService(cfg, mockStorage, WithMetrics(registry))
+
+	// Test the run's metrics are pushed under its job and instance
+	assert.NoError(t, service.Start(context.Background()))
+	assert.NoError(t, service.PushMetrics(context.Background(), registry))
+
+	assert.Equal(t, []string{"PUT /metrics/job/ingest-job/instance/runner-1"}, pushes)
+	assert.Contains(t, string(pushed), "posts_ingested_total")
+
+	// Test nothing is pushed outside batch mode
+	cfg.MaxCycles = 0
+	service = NewService(cfg, mockStorage)
+	assert.NoError(t, service.PushMetrics(context.Background(), registry))
+	assert.Len(t, pushes, 1)
+
+	// Test a failed push is reported
+	gateway.Close()
+	cfg.MaxCycles = 1
+	service = NewService(cfg, mockStorage)
+	assert.ErrorContains(t, service.PushMetrics(context.Background(), registry), "failed to push metrics")
+}
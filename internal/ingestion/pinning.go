@@ -0,0 +1,64 @@
+package ingestion
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// pinnedTransport returns a transport that, on top of the usual CA
+// verification, refuses servers whose verified chain has no certificate with
+// one of the given SHA-256 fingerprints. Malformed fingerprints are logged
+// and skipped; if none remain, every TLS connection is refused.
+func pinnedTransport(fingerprints []string, logger *slog.Logger) *http.Transport {
+	pins := make(map[[sha256.Size]byte]bool, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		pin, err := parseFingerprint(fingerprint)
+		if err != nil {
+			logger.Warn("Ignoring certificate pin", "pin", fingerprint, "error", err)
+			continue
+		}
+		pins[pin] = true
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{VerifyPeerCertificate: verifyPins(pins)}
+	return transport
+}
+
+// parseFingerprint reads a hex SHA-256 fingerprint, optionally colon-separated
+// as printed by openssl
+func parseFingerprint(fingerprint string) ([sha256.Size]byte, error) {
+	var pin [sha256.Size]byte
+	decoded, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+	if err != nil {
+		return pin, fmt.Errorf("failed to decode fingerprint: %w", err)
+	}
+	if len(decoded) != sha256.Size {
+		return pin, fmt.Errorf("fingerprint is %d bytes, expected %d", len(decoded), sha256.Size)
+	}
+	copy(pin[:], decoded)
+	return pin, nil
+}
+
+// verifyPins is a tls.Config VerifyPeerCertificate accepting chains that
+// contain a pinned certificate. Only verified chains are checked, since a
+// server could present a pinned certificate it doesn't hold the key for.
+func verifyPins(pins map[[sha256.Size]byte]bool) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if pins[sha256.Sum256(cert.Raw)] {
+					return nil
+				}
+			}
+		}
+		return errors.New("server certificate doesn't match any pinned fingerprint")
+	}
+}
@@ -0,0 +1,81 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestService_CertPins(t *testing.T) {
+	// Create mock TLS server; its certificate is trusted, so only the pin differs
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post"}})
+	}))
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	other := strings.Repeat("ab", sha256.Size)
+
+	tests := []struct {
+		name    string
+		pins    []string
+		wantErr bool
+	}{
+		{name: "matching pin", pins: []string{other, fingerprint}},
+		{name: "colon-separated uppercase pin", pins: []string{colonSeparated(strings.ToUpper(fingerprint))}},
+		{name: "mismatched pin", pins: []string{other}, wantErr: true},
+		{name: "only malformed pins", pins: []string{"not-hex", "abcd"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+			cfg := config.IngestionConfig{
+				APIEndpoint:     server.URL,
+				Timeout:         30 * time.Second,
+				RetryCount:      1,
+				StoreRetryCount: 1,
+				CertPins:        tt.pins,
+			}
+			service := NewService(cfg, mockStorage)
+			transport := service.httpClient.(*http.Client).Transport.(*http.Transport)
+			transport.TLSClientConfig.RootCAs = roots
+
+			err := service.IngestData(context.Background())
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "doesn't match any pinned fingerprint")
+				mockStorage.AssertNotCalled(t, "StorePosts", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				mockStorage.AssertNumberOfCalls(t, "StorePosts", 1)
+			}
+		})
+	}
+}
+
+func colonSeparated(fingerprint string) string {
+	pairs := make([]string, 0, len(fingerprint)/2)
+	for i := 0; i < len(fingerprint); i += 2 {
+		pairs = append(pairs, fingerprint[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
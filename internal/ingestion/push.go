@@ -0,0 +1,35 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PushMetrics pushes gatherer's metrics to PushgatewayURL once a batch run
+// (MaxCycles set) is done, since nothing scrapes a process that has exited.
+// The metrics replace those of earlier runs grouped under the same job and
+// instance, the hostname unless PushgatewayInstance is set. It does nothing
+// without a gateway or outside batch mode.
+func (s *Service) PushMetrics(ctx context.Context, gatherer prometheus.Gatherer) error {
+	if s.config.PushgatewayURL == "" || s.config.MaxCycles <= 0 {
+		return nil
+	}
+
+	instance := s.config.PushgatewayInstance
+	if instance == "" {
+		instance, _ = os.Hostname()
+	}
+
+	pusher := push.New(s.config.PushgatewayURL, s.config.PushgatewayJob).Gatherer(gatherer)
+	if instance != "" {
+		pusher = pusher.Grouping("instance", instance)
+	}
+	if err := pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	return nil
+}
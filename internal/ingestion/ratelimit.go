@@ -0,0 +1,46 @@
+package ingestion
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// recordRateLimit captures the upstream's reported rate-limit budget from a
+// response, so callers can throttle before the upstream starts returning 429
+func (s *Service) recordRateLimit(source string, header http.Header) {
+	if s.config.RateLimitRemainingHeader == "" {
+		return
+	}
+	remaining, err := strconv.Atoi(header.Get(s.config.RateLimitRemainingHeader))
+	if err != nil {
+		return // Not reported, or not a number
+	}
+
+	observed := &models.RateLimit{Remaining: remaining, ObservedAt: s.now()}
+	if s.config.RateLimitLimitHeader != "" {
+		if limit, err := strconv.Atoi(header.Get(s.config.RateLimitLimitHeader)); err == nil {
+			observed.Limit = limit
+			s.metrics.rateLimitLimit.WithLabelValues(source).Set(float64(limit))
+		}
+	}
+	s.metrics.rateLimitRemaining.WithLabelValues(source).Set(float64(remaining))
+
+	s.rateLimitMu.Lock()
+	s.rateLimit = observed
+	s.rateLimitMu.Unlock()
+}
+
+// RateLimit returns the upstream's latest reported rate-limit budget, or nil
+// if it hasn't reported one
+func (s *Service) RateLimit() *models.RateLimit {
+	s.rateLimitMu.Lock()
+	defer s.rateLimitMu.Unlock()
+
+	if s.rateLimit == nil {
+		return nil
+	}
+	observed := *s.rateLimit
+	return &observed
+}
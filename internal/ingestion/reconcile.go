@@ -0,0 +1,167 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// reconcilePageSize is the number of stored posts read per page while sampling
+const reconcilePageSize = 100
+
+// runReconciler reconciles on every ReconcileInterval until ctx is done
+func (s *Service) runReconciler(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Reconcile(ctx); err != nil {
+				s.logger.Error("Reconciliation error", "error", err)
+			}
+		}
+	}
+}
+
+// Reconcile re-fetches a sample of stored posts from the upstream and reports
+// those that no longer match, re-ingesting them if configured to
+func (s *Service) Reconcile(ctx context.Context) (*models.ReconcileReport, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	sample, err := s.sampleStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ReconcileReport{
+		RunAt:      s.now().UTC(),
+		Sampled:    len(sample),
+		Mismatches: []models.ReconcileMismatch{},
+	}
+	var drifted []models.TransformedPost
+
+	for _, stored := range sample {
+		upstream, err := s.fetchPostByID(ctx, stored.ID)
+		if err != nil {
+			return nil, err
+		}
+		if upstream == nil {
+			report.Mismatches = append(report.Mismatches, models.ReconcileMismatch{ID: stored.ID, Fields: []string{"missing_upstream"}})
+			continue
+		}
+
+		// Compare against the upstream post as it would be stored today
+		current := s.transformPosts([]models.Post{*upstream}, stored.Source)
+		if len(current) == 0 {
+			continue
+		}
+		if fields := driftedFields(stored, current[0]); len(fields) > 0 {
+			report.Mismatches = append(report.Mismatches, models.ReconcileMismatch{ID: stored.ID, Fields: fields})
+			drifted = append(drifted, current[0])
+		}
+	}
+
+	for _, mismatch := range report.Mismatches {
+		s.logger.Warn("Stored post differs from upstream", "id", mismatch.ID, "fields", strings.Join(mismatch.Fields, ","))
+	}
+
+	if s.config.ReconcileReingest && len(drifted) > 0 {
+		drifted, err := s.storePosts(ctx, drifted)
+		if err != nil {
+			return nil, fmt.Errorf("failed to re-ingest drifted posts: %w", err)
+		}
+		report.Reingested = len(drifted)
+		s.notifyStored(len(drifted))
+	}
+
+	s.reportMu.Lock()
+	s.lastReport = report
+	s.reportMu.Unlock()
+
+	s.logger.Info("Reconciliation complete", "sampled", report.Sampled, "mismatches", len(report.Mismatches))
+	return report, nil
+}
+
+// LastReconcileReport returns the most recent reconciliation report, or nil
+// if none has run
+func (s *Service) LastReconcileReport() *models.ReconcileReport {
+	s.reportMu.Lock()
+	defer s.reportMu.Unlock()
+	return s.lastReport
+}
+
+// sampleStored reads every stored post, keeping each with probability
+// ReconcileSampleRate
+func (s *Service) sampleStored(ctx context.Context) ([]models.TransformedPost, error) {
+	var sample []models.TransformedPost
+	for offset := 0; ; offset += reconcilePageSize {
+		page, err := s.storage.GetPosts(ctx, reconcilePageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored posts: %w", err)
+		}
+		for _, post := range page {
+			if rand.Float64() < s.config.ReconcileSampleRate {
+				sample = append(sample, post)
+			}
+		}
+		if len(page) < reconcilePageSize {
+			return sample, nil
+		}
+	}
+}
+
+// fetchPostByID fetches a single post from {APIEndpoint}/{id}, returning nil
+// if the upstream no longer has it
+func (s *Service) fetchPostByID(ctx context.Context, id int) (*models.Post, error) {
+	endpoint := strings.TrimSuffix(s.config.APIEndpoint, "/") + "/" + strconv.Itoa(id)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch post %d: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned status %d for post %d", resp.StatusCode, id)
+	}
+
+	var post models.Post
+	if err := json.NewDecoder(resp.Body).Decode(&post); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal post %d: %w", id, err)
+	}
+	return &post, nil
+}
+
+// driftedFields lists the fields of stored that differ from current
+func driftedFields(stored, current models.TransformedPost) []string {
+	var fields []string
+	if stored.UserID != current.UserID {
+		fields = append(fields, "userId")
+	}
+	if stored.Title != current.Title {
+		fields = append(fields, "title")
+	}
+	if stored.Body != current.Body {
+		fields = append(fields, "body")
+	}
+	return fields
+}
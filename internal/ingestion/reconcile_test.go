@@ -0,0 +1,114 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestService_Reconcile(t *testing.T) {
+	upstream := map[string]models.Post{
+		"/posts/1": {UserID: 1, ID: 1, Title: "Test Post 1", Body: "Test body 1"},
+		"/posts/2": {UserID: 1, ID: 2, Title: "Edited upstream", Body: "Test body 2"},
+	}
+
+	// Create mock server serving posts by ID; post 3 has been removed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post, ok := upstream[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(post)
+	}))
+	defer server.Close()
+
+	stored := []models.TransformedPost{
+		{Post: models.Post{UserID: 1, ID: 1, Title: "Test Post 1", Body: "Test body 1"}},
+		{Post: models.Post{UserID: 1, ID: 2, Title: "Test Post 2", Body: "Test body 2"}},
+		{Post: models.Post{UserID: 1, ID: 3, Title: "Test Post 3", Body: "Test body 3"}},
+	}
+
+	var reingested []models.TransformedPost
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, reconcilePageSize, 0).Return(stored, nil)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) { reingested = args.Get(1).([]models.TransformedPost) }).
+		Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         server.URL + "/posts",
+		Timeout:             30 * time.Second,
+		StoreRetryCount:     1,
+		ReconcileSampleRate: 1,
+		ReconcileReingest:   true,
+	}
+	service := NewService(cfg, mockStorage)
+	assert.Nil(t, service.LastReconcileReport())
+
+	// Test Reconcile
+	report, err := service.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Sampled)
+	assert.Equal(t, []models.ReconcileMismatch{
+		{ID: 2, Fields: []string{"title"}},
+		{ID: 3, Fields: []string{"missing_upstream"}},
+	}, report.Mismatches)
+
+	// Only the drifted post is re-ingested, with the upstream's content
+	assert.Equal(t, 1, report.Reingested)
+	if assert.Len(t, reingested, 1) {
+		assert.Equal(t, "Edited upstream", reingested[0].Title)
+	}
+	assert.Equal(t, report, service.LastReconcileReport())
+}
+
+func TestService_Reconcile_SampleRate(t *testing.T) {
+	// Create mock server that must not be called
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upstream request %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, reconcilePageSize, 0).
+		Return([]models.TransformedPost{{Post: models.Post{ID: 1}}}, nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         server.URL,
+		Timeout:             30 * time.Second,
+		ReconcileSampleRate: 0,
+	}
+	service := NewService(cfg, mockStorage)
+
+	report, err := service.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Sampled)
+	assert.Empty(t, report.Mismatches)
+}
+
+func TestService_fetchPostByID_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	service := NewService(config.IngestionConfig{APIEndpoint: server.URL, Timeout: 30 * time.Second}, nil)
+
+	_, err := service.fetchPostByID(context.Background(), 7)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status 502")
+}
@@ -0,0 +1,23 @@
+package ingestion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// maxRedirects matches the limit http.Client applies by default
+const maxRedirects = 10
+
+// refuseDowngrade is an http.Client CheckRedirect that stops https→http
+// redirects, which would send the request in the clear, while still
+// following upgrades and same-scheme redirects
+func refuseDowngrade(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if prev := via[len(via)-1]; prev.URL.Scheme == "https" && req.URL.Scheme == "http" {
+		return errors.New("refusing redirect from https to http: " + req.URL.Redacted())
+	}
+	return nil
+}
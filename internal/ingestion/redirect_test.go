@@ -0,0 +1,65 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestService_fetchPostsOnce_RedirectDowngrade(t *testing.T) {
+	// Create a plain-http upstream, and an https one redirecting to it
+	insecure := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Insecure"}})
+	}))
+	defer insecure.Close()
+	secure := httptest.NewTLSServer(http.RedirectHandler(insecure.URL, http.StatusFound))
+	defer secure.Close()
+
+	newService := func(refuse bool) *Service {
+		service := NewService(config.IngestionConfig{
+			APIEndpoint:             secure.URL,
+			Timeout:                 30 * time.Second,
+			RefuseRedirectDowngrade: refuse,
+		}, nil)
+		// Trust the test server's certificate
+		service.httpClient.(*http.Client).Transport = secure.Client().Transport
+		return service
+	}
+
+	// Test the downgrade is refused when protection is enabled
+	_, err := newService(true).fetchPostsOnce(context.Background())
+
+	assert.ErrorContains(t, err, "refusing redirect from https to http")
+
+	// Test it is followed when protection is disabled
+	posts, err := newService(false).fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+}
+
+func TestRefuseDowngrade(t *testing.T) {
+	request := func(url string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, url, nil)
+	}
+
+	// Test upgrades and same-scheme redirects are allowed
+	assert.NoError(t, refuseDowngrade(request("https://a/posts"), []*http.Request{request("http://a/posts")}))
+	assert.NoError(t, refuseDowngrade(request("https://b/posts"), []*http.Request{request("https://a/posts")}))
+
+	// Test the default redirect limit still applies
+	via := make([]*http.Request, maxRedirects)
+	for i := range via {
+		via[i] = request("https://a/posts")
+	}
+	assert.ErrorContains(t, refuseDowngrade(request("https://a/posts"), via), "stopped after 10 redirects")
+}
@@ -0,0 +1,86 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// backoffBase is DefaultBackoff's delay before the first retry, before jitter
+	backoffBase = time.Second
+
+	// backoffMax caps DefaultBackoff's delay
+	backoffMax = 30 * time.Second
+)
+
+// RetryClassifier decides whether a failed fetch is worth retrying.
+// statusCode is the upstream's response status, or 0 when the request failed
+// without one (e.g. a network or decoding error).
+type RetryClassifier func(err error, statusCode int) bool
+
+// statusError is a fetch failure caused by a non-200 upstream response
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API returned status %d", e.code)
+}
+
+// statusCode returns the upstream status behind err, or 0 if there is none
+func statusCode(err error) int {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code
+	}
+	return 0
+}
+
+// DefaultIsRetryable retries failures without a response and 5xx, 429 and
+// 408 responses; other 4xx responses won't change on a retry. Cancellation is
+// never retried.
+func DefaultIsRetryable(err error, statusCode int) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case statusCode == 0:
+		return true
+	}
+	return statusCode >= 500 ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusRequestTimeout
+}
+
+// Backoff returns the delay before the retry following attempt, counted from 0
+type Backoff func(attempt int) time.Duration
+
+// DefaultBackoff doubles the delay each attempt, from a second up to 30s,
+// and picks a random point in its upper half so that simultaneous failures
+// don't all retry at once
+func DefaultBackoff(attempt int) time.Duration {
+	delay := backoffMax
+	if attempt < 5 {
+		delay = min(backoffBase<<attempt, backoffMax)
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// WithBackoff replaces DefaultBackoff in spacing fetch and store retries,
+// e.g. with a fixed sequence in tests
+func WithBackoff(backoff Backoff) Option {
+	return func(s *Service) {
+		s.backoff = backoff
+	}
+}
+
+// WithRetryClassifier replaces DefaultIsRetryable in deciding which failed
+// fetches are retried
+func WithRetryClassifier(isRetryable RetryClassifier) Option {
+	return func(s *Service) {
+		s.isRetryable = isRetryable
+	}
+}
@@ -0,0 +1,127 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+)
+
+func TestDefaultIsRetryable(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		want       bool
+	}{
+		{"network error", errors.New("connection refused"), 0, true},
+		{"cancelled", context.Canceled, 0, false},
+		{"server error", &statusError{code: 503}, 503, true},
+		{"rate limited", &statusError{code: 429}, 429, true},
+		{"request timeout", &statusError{code: 408}, 408, true},
+		{"not found", &statusError{code: 404}, 404, false},
+		{"unauthorized", &statusError{code: 401}, 401, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DefaultIsRetryable(tt.err, tt.statusCode))
+		})
+	}
+}
+
+func TestService_fetchWithRetry_Classifier(t *testing.T) {
+	// Create mock server that always returns 404
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint: server.URL,
+		Timeout:     30 * time.Second,
+		RetryCount:  2,
+	}
+
+	// Test 404 is not retried by default
+	service := NewService(cfg, nil)
+	_, err := service.fetchWithRetry(context.Background(), server.URL, nil)
+
+	assert.ErrorContains(t, err, "failed after 1 attempts: API returned status 404")
+	assert.Equal(t, int64(1), requests.Load())
+
+	// Test a custom classifier can make 404 retryable
+	requests.Store(0)
+	var classified []int
+	service = NewService(cfg, nil, WithRetryClassifier(func(err error, statusCode int) bool {
+		classified = append(classified, statusCode)
+		return statusCode == http.StatusNotFound
+	}))
+	_, err = service.fetchWithRetry(context.Background(), server.URL, nil)
+
+	assert.ErrorContains(t, err, "failed after 2 attempts: API returned status 404")
+	assert.Equal(t, int64(2), requests.Load())
+	assert.Equal(t, []int{404, 404}, classified)
+}
+
+func TestService_fetchWithRetry_Backoff(t *testing.T) {
+	// Create mock server that always fails, recording when each request arrives
+	var arrivals []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrivals = append(arrivals, time.Now())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint: server.URL,
+		Timeout:     30 * time.Second,
+		RetryCount:  3,
+	}
+
+	// Test the injected sequence spaces the retries
+	sequence := []time.Duration{20 * time.Millisecond, 60 * time.Millisecond}
+	var attempts []int
+	service := NewService(cfg, nil, WithBackoff(func(attempt int) time.Duration {
+		attempts = append(attempts, attempt)
+		return sequence[attempt]
+	}))
+	_, err := service.fetchWithRetry(context.Background(), server.URL, nil)
+
+	assert.ErrorContains(t, err, "failed after 3 attempts")
+	assert.Equal(t, []int{0, 1}, attempts)
+	if assert.Len(t, arrivals, 3) {
+		assert.GreaterOrEqual(t, arrivals[1].Sub(arrivals[0]), sequence[0])
+		assert.GreaterOrEqual(t, arrivals[2].Sub(arrivals[1]), sequence[1])
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{5, 15 * time.Second, 30 * time.Second},
+		{100, 15 * time.Second, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			delay := DefaultBackoff(tt.attempt)
+			assert.GreaterOrEqual(t, delay, tt.min, "attempt %d", tt.attempt)
+			assert.LessOrEqual(t, delay, tt.max, "attempt %d", tt.attempt)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ohler55/ojg/asm"
+	"github.com/ohler55/ojg/oj"
+	"github.com/ohler55/ojg/sen"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// LoadScript compiles the ojg asm script at path into a transformer. The
+// script sees the post's JSON form as $.post, may modify it in place, and
+// drops the post by setting $.drop to true:
+//
+//	[asm
+//	  [set $.post.title [toupper $.post.title]]
+//	  [set $.drop [eq 0 [mod $.post.id 2]]]
+//	]
+//
+// A post the script fails on is kept unchanged, and the failure logged.
+func LoadScript(path string, logger *slog.Logger) (Transformer, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transform script: %w", err)
+	}
+	plan, err := compileScript(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile transform script %s: %w", path, err)
+	}
+
+	return func(post *models.TransformedPost) bool {
+		keep, err := runScript(plan, post)
+		if err != nil {
+			logger.Warn("Transform script failed, keeping post unchanged", "post_id", post.ID, "error", err)
+			return true
+		}
+		return keep
+	}, nil
+}
+
+func compileScript(src []byte) (*asm.Plan, error) {
+	v, err := sen.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("script must be an array, got %T", v)
+	}
+	return asm.NewPlan(list), nil
+}
+
+// runScript runs plan against post, only updating post once the script has
+// succeeded and produced a valid post
+func runScript(plan *asm.Plan, post *models.TransformedPost) (bool, error) {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return false, err
+	}
+	doc, err := oj.Parse(data)
+	if err != nil {
+		return false, err
+	}
+
+	root := map[string]any{"post": doc}
+	if err := plan.Execute(root); err != nil {
+		return false, err
+	}
+	if drop, _ := root["drop"].(bool); drop {
+		return false, nil
+	}
+
+	var transformed models.TransformedPost
+	if err := json.Unmarshal([]byte(oj.JSON(root["post"])), &transformed); err != nil {
+		return false, fmt.Errorf("script produced an invalid post: %w", err)
+	}
+	*post = transformed
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package ingestion
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// sampleScript uppercases titles and drops posts with even IDs
+const sampleScript = `[asm
+  [set $.post.title [toupper $.post.title]]
+  [set $.drop [eq 0 [mod $.post.id 2]]]
+]`
+
+func writeScript(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "transform.sen")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o600))
+	return path
+}
+
+func TestService_transformPosts_TransformScript(t *testing.T) {
+	script, err := LoadScript(writeScript(t, sampleScript), nil)
+	require.NoError(t, err)
+	service := NewService(config.IngestionConfig{}, nil, WithTransformers(script))
+
+	transformed := service.transformPosts([]models.Post{
+		{UserID: 1, ID: 1, Title: "first post", Body: "body"},
+		{UserID: 1, ID: 2, Title: "second post"},
+		{UserID: 2, ID: 3, Title: "third post"},
+	}, primarySource)
+
+	if assert.Len(t, transformed, 2) {
+		assert.Equal(t, 1, transformed[0].ID)
+		assert.Equal(t, "FIRST POST", transformed[0].Title)
+		assert.Equal(t, "body", transformed[0].Body)
+		assert.Equal(t, primarySource, transformed[0].Source)
+		assert.False(t, transformed[0].IngestedAt.IsZero())
+		assert.Equal(t, 3, transformed[1].ID)
+		assert.Equal(t, "THIRD POST", transformed[1].Title)
+	}
+}
+
+func TestLoadScript_Errors(t *testing.T) {
+	_, err := LoadScript(filepath.Join(t.TempDir(), "missing.sen"), nil)
+	assert.Error(t, err)
+
+	_, err = LoadScript(writeScript(t, `{not: "a plan"}`), nil)
+	assert.Error(t, err)
+}
+
+func TestLoadScript_RuntimeError(t *testing.T) {
+	// Test a post the script fails on is kept unchanged
+	var logs bytes.Buffer
+	transform, err := LoadScript(writeScript(t, `[asm [set $.post.title [toupper $.post.id]]]`),
+		slog.New(slog.NewTextHandler(&logs, nil)))
+	require.NoError(t, err)
+
+	post := &models.TransformedPost{Post: models.Post{ID: 1, Title: "Title"}}
+	assert.True(t, transform(post))
+	assert.Equal(t, "Title", post.Title)
+	assert.Contains(t, logs.String(), "Transform script failed")
+}
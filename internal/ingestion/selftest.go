@@ -0,0 +1,95 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// ProbeUpstreams probes every configured endpoint, the primary or each of its
+// mirrors and the fallback, at once with a HEAD request, so ctx should carry
+// a short deadline
+func (s *Service) ProbeUpstreams(ctx context.Context) []models.UpstreamProbe {
+	var probes []models.UpstreamProbe
+	for _, endpoint := range s.primaryEndpoints() {
+		probes = append(probes, models.UpstreamProbe{Source: s.primarySourceName(), URL: endpoint})
+	}
+	if s.config.FallbackAPIEndpoint != "" {
+		probes = append(probes, models.UpstreamProbe{Source: s.fallbackSourceName(), URL: s.config.FallbackAPIEndpoint})
+	}
+
+	var wg sync.WaitGroup
+	for i := range probes {
+		wg.Add(1)
+		go func(probe *models.UpstreamProbe) {
+			defer wg.Done()
+			start := time.Now()
+			err := s.probe(ctx, probe.URL)
+			probe.LatencyMS = time.Since(start).Milliseconds()
+			probe.Reachable = err == nil
+			if err != nil {
+				probe.Error = err.Error()
+			}
+		}(&probes[i])
+	}
+	wg.Wait()
+	return probes
+}
+
+// primaryEndpoints returns the primary's mirrors, or the primary itself
+func (s *Service) primaryEndpoints() []string {
+	if len(s.config.APIEndpoints) == 0 {
+		return []string{s.config.APIEndpoint}
+	}
+	endpoints := make([]string, 0, len(s.config.APIEndpoints))
+	for _, endpoint := range s.config.APIEndpoints {
+		endpoints = append(endpoints, endpoint.URL)
+	}
+	return endpoints
+}
+
+// SelfTest probes the primary upstream with a HEAD request, failing fast on
+// a misconfigured or unreachable endpoint before the first cycle. With
+// mirrors configured it passes if any of them responds. Any response short
+// of a server error counts, as does 501 from upstreams without HEAD.
+func (s *Service) SelfTest(ctx context.Context) error {
+	var errs []error
+	for _, endpoint := range s.primaryEndpoints() {
+		err := s.probe(ctx, endpoint)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+	return errors.Join(errs...)
+}
+
+// probe sends a HEAD request to endpoint
+func (s *Service) probe(ctx context.Context, endpoint string) error {
+	target, err := s.requestURL(endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	s.forwardHeaders(ctx, req)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach upstream: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package ingestion
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+)
+
+// unreachableURL returns the URL of a port nothing listens on
+func unreachableURL(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return "http://" + addr + "/posts"
+}
+
+func TestService_Start_SelfTestUnreachable(t *testing.T) {
+	// Create mock storage that must not be written to
+	mockStorage := new(MockStorage)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:        unreachableURL(t),
+		Timeout:            time.Second,
+		RetryCount:         1,
+		StartupHealthCheck: true,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test Start fails before ingesting anything
+	err := service.Start(context.Background())
+
+	assert.ErrorContains(t, err, "startup self-test failed")
+	assert.ErrorContains(t, err, "failed to reach upstream")
+	mockStorage.AssertNotCalled(t, "StorePosts")
+}
+
+func TestService_SelfTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"head not allowed", http.StatusMethodNotAllowed, false},
+		{"head not implemented", http.StatusNotImplemented, false},
+		{"server error", http.StatusServiceUnavailable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var method string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			service := NewService(config.IngestionConfig{APIEndpoint: server.URL, Timeout: time.Second}, nil)
+
+			err := service.SelfTest(context.Background())
+
+			assert.Equal(t, http.MethodHead, method)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestService_SelfTest_AnyMirror(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// Test one reachable mirror is enough
+	service := NewService(config.IngestionConfig{
+		APIEndpoints: []config.WeightedEndpoint{{URL: unreachableURL(t), Weight: 1}, {URL: server.URL, Weight: 1}},
+		Timeout:      time.Second,
+	}, nil)
+
+	assert.NoError(t, service.SelfTest(context.Background()))
+}
@@ -1,120 +1,923 @@
 package ingestion
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ohler55/ojg/jp"
+
 	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/dedup"
 	"github.com/cyderes/data-ingestion-service/internal/models"
 	"github.com/cyderes/data-ingestion-service/internal/storage"
 )
 
+// Source tags recorded on posts, naming the upstream they were fetched from
+const (
+	primarySource  = "placeholder_api"
+	fallbackSource = "fallback_api"
+)
+
+// ErrReadOnly is returned instead of running a cycle while storage writes are failing
+var ErrReadOnly = errors.New("storage is read-only: writes are failing")
+
+// ErrStale is returned by Start when the dead-man switch trips: no cycle
+// succeeded within MaxStalePeriod
+var ErrStale = errors.New("ingestion is stale")
+
+// Doer sends HTTP requests. *http.Client satisfies it; tests and middleware
+// can substitute their own implementation.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// flushNotifier is implemented by storage that buffers posts, reporting
+// them once they are actually written
+type flushNotifier interface {
+	OnFlush(fn func(posts []models.TransformedPost))
+}
+
 // Service handles data ingestion from external APIs
 type Service struct {
-	config     config.IngestionConfig
-	storage    storage.Storage
-	httpClient *http.Client
+	config       config.IngestionConfig
+	storage      storage.Storage
+	httpClient   Doer
+	logger       *slog.Logger
+	now          func() time.Time
+	transformers []Transformer
+	afterIngest  []func(stored int)
+	seen         *dedup.BloomFilter // IDs already stored, nil when dedup is disabled
+	balancer     *endpointBalancer  // Mirror selection, nil when APIEndpoints is unset
+	metrics      *metrics
+	vars         *debugVars
+	isRetryable  RetryClassifier
+	backoff      Backoff
+	heapStats    HeapStats
+	fetchLimiter *FetchLimiter // Bounds concurrent upstream requests, nil when unlimited
+	users        *userCache    // Looked-up post authors, nil when enrichment is disabled
+	archiver     RunArchiver   // Copies each run's stored posts, nil when archiving is disabled
+	buffered     bool          // Storage buffers posts; they count as stored once flushed
+
+	runMu         sync.Mutex    // Serializes scheduled and API-triggered runs
+	fetchFailures int           // Consecutive failed fetches
+	writeFailures int           // Consecutive failed stores
+	readOnly      atomic.Bool   // Storage writes are failing; cycles are skipped
+	shedding      atomic.Bool   // The heap is above MaxHeapBytes; cycles are skipped
+	emptyCycles   int           // Consecutive cycles that ingested nothing
+	pollInterval  time.Duration // Current interval, adjusted for empty cycles
+	lastSuccess   time.Time     // Last run recorded as successful
+	deduplicated  int           // Duplicate IDs dropped from the current cycle's fetch
+	marshalSkips  int           // Posts storage couldn't marshal in the current cycle
+
+	snapshot models.MetricsSnapshot // The current cycle's counts, persisted when MetricsHistory is set
+
+	lastCycleOK atomic.Int64 // UnixNano of the last successful cycle, 0 until one succeeds
+
+	errorsMu     sync.Mutex
+	recentErrors []models.IngestionError // Ring buffer of the last ErrorHistorySize errors
+	nextError    int                     // Index the next error is written to
+
+	reportMu   sync.Mutex
+	lastReport *models.ReconcileReport // Latest reconciliation, nil until one runs
+
+	rateLimitMu sync.Mutex
+	rateLimit   *models.RateLimit // Latest upstream budget, nil until reported
+
+	lastFetchMu sync.Mutex
+	lastFetch   *models.FetchRecord // Latest upstream response, nil until one arrives
+}
+
+// Transformer adjusts a post after the built-in transformation. Returning
+// false drops the post from the cycle.
+type Transformer func(post *models.TransformedPost) bool
+
+// Option customizes a Service
+type Option func(*Service)
+
+// WithLogger sets the logger used for ingestion events
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Service) {
+		s.logger = logger
+	}
+}
+
+// WithClock replaces time.Now, e.g. for deterministic tests
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// WithTransformers appends transformers applied, in order, to every post
+func WithTransformers(transformers ...Transformer) Option {
+	return func(s *Service) {
+		s.transformers = append(s.transformers, transformers...)
+	}
+}
+
+// WithAfterIngest registers a hook called after a cycle stores posts, e.g.
+// to invalidate caches
+func WithAfterIngest(hook func(stored int)) Option {
+	return func(s *Service) {
+		s.afterIngest = append(s.afterIngest, hook)
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client used for upstream requests
+func WithHTTPClient(client Doer) Option {
+	return func(s *Service) {
+		s.httpClient = client
+	}
 }
 
 // NewService creates a new ingestion service
-func NewService(cfg config.IngestionConfig, store storage.Storage) *Service {
-	return &Service{
-		config:  cfg,
-		storage: store,
-		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
-		},
+func NewService(cfg config.IngestionConfig, store storage.Storage, opts ...Option) *Service {
+	// Fetches also apply their source's own timeout, so the client must not
+	// cut off the longest of them
+	client := &http.Client{
+		Timeout: max(cfg.Timeout, cfg.FallbackTimeout),
 	}
+	if cfg.RefuseRedirectDowngrade {
+		client.CheckRedirect = refuseDowngrade
+	}
+
+	s := &Service{
+		config:       cfg,
+		storage:      store,
+		httpClient:   client,
+		logger:       slog.Default(),
+		now:          time.Now,
+		pollInterval: cfg.Interval,
+		balancer:     newEndpointBalancer(cfg.APIEndpoints, cfg.EndpointProbeInterval),
+		metrics:      newMetrics(),
+		vars:         newDebugVars(),
+		isRetryable:  DefaultIsRetryable,
+		backoff:      DefaultBackoff,
+		heapStats:    runtimeHeapStats,
+	}
+
+	if cfg.UserLookupEndpoint != "" {
+		s.users = newUserCache()
+	}
+
+	if cfg.NormalizeTitles {
+		s.transformers = append(s.transformers, NormalizeTitle(cfg.LowercaseTitles))
+	}
+	if len(cfg.CategoryKeywords) > 0 {
+		s.transformers = append(s.transformers, Categorize(cfg.CategoryKeywords, cfg.DefaultCategory))
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Pinning applies to the default client only; one set by WithHTTPClient
+	// is used as given
+	if len(cfg.CertPins) > 0 {
+		client.Transport = pinnedTransport(cfg.CertPins, s.logger)
+	}
+
+	// Limit before signing, so requests aren't signed long before they're sent
+	if s.fetchLimiter == nil && cfg.GlobalFetchConcurrency > 0 {
+		s.fetchLimiter = NewFetchLimiter(cfg.GlobalFetchConcurrency)
+	}
+	if s.fetchLimiter != nil {
+		s.httpClient = &limitedDoer{next: s.httpClient, limiter: s.fetchLimiter}
+	}
+
+	switch cfg.AuthType {
+	case "":
+	case AuthTypeSigV4:
+		s.httpClient = newSigV4Doer(s.httpClient, nil, cfg.SigV4Region, cfg.SigV4Service, s.now)
+	default:
+		s.logger.Warn("Ignoring unknown upstream auth type", "auth_type", cfg.AuthType)
+	}
+
+	if cfg.DedupFilterPath != "" {
+		s.seen = s.loadSeenFilter()
+	}
+	if notifier, ok := store.(flushNotifier); ok {
+		s.buffered = true
+		notifier.OnFlush(s.flushed)
+	}
+
+	return s
+}
+
+// loadSeenFilter restores the persisted dedup filter, starting empty if
+// there is none yet or it can't be read
+func (s *Service) loadSeenFilter() *dedup.BloomFilter {
+	hasher, err := dedup.NewHasher(s.config.HashAlgorithm)
+	if err != nil {
+		s.logger.Warn("Falling back to the default hash algorithm", "error", err)
+		hasher = dedup.DefaultHasher()
+	}
+
+	filter, err := dedup.LoadBloomFilter(s.config.DedupFilterPath)
+	switch {
+	case err == nil && filter.Hasher() == hasher:
+		return filter
+	case err == nil:
+		// Hashes from another algorithm can't be looked up
+		s.logger.Warn("Dedup filter uses a different hash algorithm, starting empty",
+			"stored", filter.Hasher().Name(), "configured", hasher.Name())
+	case !errors.Is(err, os.ErrNotExist):
+		s.logger.Warn("Failed to load dedup filter, starting empty", "error", err)
+	}
+	return dedup.NewBloomFilterWithHasher(s.config.DedupExpectedItems, s.config.DedupFalsePositiveRate, hasher)
 }
 
-// Start begins the ingestion process
+// Start begins the ingestion process. It returns once ctx is cancelled or
+// MaxCycles are done, and only after any cycle or reconciliation in progress
+// has finished, so storage may be closed as soon as it returns. With
+// MaxStalePeriod set it also returns ErrStale once no cycle has succeeded for
+// that long.
 func (s *Service) Start(ctx context.Context) error {
-	// Perform initial ingestion
+	if s.config.StartupHealthCheck {
+		if err := s.SelfTest(ctx); err != nil {
+			s.logger.Error("Upstream self-test failed", "error", err)
+			return fmt.Errorf("startup self-test failed: %w", err)
+		}
+		s.logger.Info("Upstream self-test passed")
+	}
+
+	// Stop the background goroutines and wait for them on return, so no
+	// storage operation outlives Start
+	ctx, cancel := context.WithCancelCause(ctx)
+	var background sync.WaitGroup
+	defer background.Wait()
+	defer cancel(nil)
+	if s.config.MaxStalePeriod > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			s.watchStale(ctx, cancel)
+		}()
+	}
+
+	// Perform initial ingestion. The dead-man switch, if enabled, decides
+	// when failures are fatal.
 	if err := s.IngestData(ctx); err != nil {
-		return fmt.Errorf("initial ingestion failed: %w", err)
+		if s.config.MaxStalePeriod <= 0 {
+			return fmt.Errorf("initial ingestion failed: %w", err)
+		}
+		s.logger.Error("Initial ingestion error", "error", err)
 	}
 
-	// Set up periodic ingestion
-	ticker := time.NewTicker(s.config.Interval)
-	defer ticker.Stop()
+	if s.config.ReconcileInterval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			s.runReconciler(ctx)
+		}()
+	}
 
-	for {
+	// Set up periodic ingestion, stopping after MaxCycles when set
+	timer := time.NewTimer(s.nextDelay())
+	defer timer.Stop()
+
+	cycles := 1
+	for s.config.MaxCycles <= 0 || cycles < s.config.MaxCycles {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
+			return context.Cause(ctx)
+		case <-timer.C:
 			if err := s.IngestData(ctx); err != nil {
 				// Log error but don't stop the service
-				fmt.Printf("Ingestion error: %v\n", err)
+				s.logger.Error("Ingestion error", "error", err)
 			}
+			cycles++
+			timer.Reset(s.nextDelay())
 		}
 	}
+
+	s.logger.Info("Completed the configured number of ingestion cycles", "cycles", cycles)
+	return nil
+}
+
+// LastSuccess returns when the last ingestion cycle succeeded, or the zero
+// time if none has since startup
+func (s *Service) LastSuccess() time.Time {
+	if last := s.lastCycleOK.Load(); last != 0 {
+		return time.Unix(0, last)
+	}
+	return time.Time{}
+}
+
+// watchStale cancels ctx with ErrStale once no cycle has succeeded for
+// MaxStalePeriod
+func (s *Service) watchStale(ctx context.Context, cancel context.CancelCauseFunc) {
+	started := time.Now()
+	timer := time.NewTimer(s.config.MaxStalePeriod)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		last := time.Unix(0, max(s.lastCycleOK.Load(), started.UnixNano()))
+		if wait := time.Until(last.Add(s.config.MaxStalePeriod)); wait > 0 {
+			timer.Reset(wait)
+			continue
+		}
+		s.logger.Error("No successful ingestion within the stale period", "period", s.config.MaxStalePeriod, "last_success", last)
+		cancel(fmt.Errorf("%w: no successful ingestion since %s", ErrStale, last.UTC().Format(time.RFC3339)))
+		return
+	}
+}
+
+// nextDelay returns how long to wait before the next cycle. Cycles triggered
+// through the API update the fields it reads, so it holds runMu.
+func (s *Service) nextDelay() time.Duration {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if s.isDegraded() {
+		return s.config.DegradedInterval
+	}
+	return s.pollInterval
+}
+
+// recordCycle adapts the polling interval to the number of records ingested,
+// backing off while the upstream has nothing new
+func (s *Service) recordCycle(records int) {
+	if records > 0 || s.config.EmptyCycleThreshold <= 0 {
+		s.emptyCycles = 0
+		s.pollInterval = s.config.Interval
+		return
+	}
+
+	s.emptyCycles++
+	if s.emptyCycles < s.config.EmptyCycleThreshold {
+		return
+	}
+
+	s.pollInterval *= 2
+	if s.config.MaxInterval > 0 && s.pollInterval > s.config.MaxInterval {
+		s.pollInterval = s.config.MaxInterval
+	}
+}
+
+// isDegraded reports whether the upstream has failed enough consecutive
+// fetches to slow down polling
+func (s *Service) isDegraded() bool {
+	return s.config.DegradedThreshold > 0 && s.fetchFailures >= s.config.DegradedThreshold
 }
 
 // IngestData fetches data from the API and stores it
 func (s *Service) IngestData(ctx context.Context) error {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	runCtx := ctx
+	if s.config.MaxRunDuration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.config.MaxRunDuration)
+		defer cancel()
+	}
+
+	s.vars.cycles.Add(1)
+	started := time.Now()
+	s.snapshot = models.MetricsSnapshot{}
+	err := s.ingest(runCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("ingestion run exceeded %s: %w", s.config.MaxRunDuration, err)
+		s.recordStatus(ctx, "timed_out", 0, err)
+	}
+	if err != nil {
+		s.recordError(err)
+		s.vars.lastError.Set(err.Error())
+	} else {
+		s.lastCycleOK.Store(time.Now().UnixNano())
+	}
+	if s.config.MetricsHistory && !errors.Is(err, ErrReadOnly) && !errors.Is(err, ErrMemoryPressure) {
+		s.recordSnapshot(ctx, time.Since(started), err)
+	}
+	return err
+}
+
+// recordSnapshot persists the metrics snapshot of the cycle just run
+func (s *Service) recordSnapshot(ctx context.Context, duration time.Duration, runErr error) {
+	snapshot := s.snapshot
+	snapshot.Time = s.now().UTC()
+	snapshot.DurationMS = duration.Milliseconds()
+	if runErr != nil {
+		snapshot.Error = runErr.Error()
+	}
+
+	if err := s.storage.RecordMetricsSnapshot(ctx, snapshot); err != nil {
+		s.logger.Error("Failed to record metrics snapshot", "error", err)
+	}
+}
+
+// ingest runs one ingestion cycle; callers must hold runMu
+func (s *Service) ingest(ctx context.Context) error {
+	if s.readOnly.Load() && !s.probeWrite(ctx) {
+		return ErrReadOnly
+	}
+	if s.shedLoad() {
+		return ErrMemoryPressure
+	}
+
 	// Fetch data from API
-	posts, err := s.fetchPosts(ctx)
+	s.deduplicated = 0
+	s.marshalSkips = 0
+	posts, source, err := s.fetchPosts(ctx)
 	if err != nil {
+		s.fetchFailures++
+		if s.isDegraded() {
+			s.recordStatus(ctx, "degraded", 0, err)
+		}
 		return fmt.Errorf("failed to fetch posts: %w", err)
 	}
 
+	recovered := s.isDegraded()
+	s.fetchFailures = 0
+	s.snapshot.Source = source
+	s.snapshot.RecordsIn = len(posts)
+	logger := s.logger.With("source", source)
+
 	// Transform data
-	transformedPosts := s.transformPosts(posts)
+	posts, s.deduplicated = dedupeByID(posts)
+	if s.deduplicated > 0 {
+		logger.Info("Dropped duplicate post IDs from the fetch", "count", s.deduplicated)
+	}
+	posts, skipped := s.filterPostsByAge(posts)
+	if skipped > 0 {
+		logger.Info("Skipped posts outside the configured age window", "count", skipped)
+	}
+	posts, alreadySeen := s.filterSeen(posts, source)
+	if alreadySeen > 0 {
+		logger.Info("Skipped previously stored posts", "count", alreadySeen)
+	}
+	transformedPosts := s.transformPosts(posts, source)
+	if s.users != nil {
+		s.enrichPosts(ctx, transformedPosts)
+	}
+	runID := newRunID()
+	if s.config.StampRunID {
+		for i := range transformedPosts {
+			transformedPosts[i].RunID = runID
+		}
+		logger = logger.With("run_id", runID)
+	}
+	if s.config.SortByID {
+		sortByID(transformedPosts)
+	}
 
-	// Store data
-	if err := s.storage.StorePosts(ctx, transformedPosts); err != nil {
-		return fmt.Errorf("failed to store posts: %w", err)
+	// Store data, in sub-batches when the cycle is larger than allowed
+	batches := s.batches(transformedPosts)
+	stored := 0
+	var archived []models.TransformedPost
+	cycleRetries := 0
+	for _, pending := range batches {
+		start := s.now()
+		batch, err := s.storePosts(ctx, pending)
+		// Retry with the posts already fetched rather than waiting for the
+		// next cycle to fetch them again
+		for err != nil && cycleRetries < s.config.CycleRetryCount {
+			logger.Warn("Failed to store posts, retrying with the fetched posts", "retry", cycleRetries+1, "error", err)
+			if sleepContext(ctx, s.backoff(cycleRetries)) != nil {
+				break
+			}
+			cycleRetries++
+			batch, err = s.storePosts(ctx, pending)
+		}
+		s.observe(ctx, s.metrics.storeDuration.WithLabelValues(source), s.now().Sub(start))
+		if err != nil {
+			s.recordWriteFailure()
+			s.archiveRun(ctx, runID, archived)
+			s.notifyStored(stored)
+			return fmt.Errorf("failed to store posts (%d of %d stored): %w", stored, len(transformedPosts), err)
+		}
+		s.writeFailures = 0
+		if !s.buffered {
+			s.markSeen(batch)
+			s.metrics.postsIngested.WithLabelValues(source).Add(float64(len(batch)))
+		}
+		if s.archiver != nil {
+			archived = append(archived, batch...)
+		}
+		if s.config.IngestComments {
+			s.ingestComments(ctx, batch)
+		}
+
+		stored += len(batch)
+		s.snapshot.RecordsOut = stored
+		if len(batches) > 1 && stored+s.marshalSkips < len(transformedPosts) {
+			s.recordStatus(ctx, "running", stored, nil)
+		}
 	}
 
-	fmt.Printf("Successfully ingested %d posts\n", len(transformedPosts))
+	if recovered || len(batches) > 1 || s.marshalSkips > 0 {
+		s.recordStatus(ctx, "success", stored, nil)
+	}
+	s.recordCycle(len(transformedPosts))
+	s.archiveRun(ctx, runID, archived)
+	s.notifyStored(stored)
+
+	logger.Info("Successfully ingested posts", "count", stored)
 	return nil
 }
 
-// fetchPosts fetches posts from the API with retry logic
-func (s *Service) fetchPosts(ctx context.Context) ([]models.Post, error) {
+// notifyStored runs the after-ingest hooks if any posts were stored
+func (s *Service) notifyStored(stored int) {
+	if stored == 0 {
+		return
+	}
+	s.vars.postsStored.Add(int64(stored))
+	for _, hook := range s.afterIngest {
+		hook(stored)
+	}
+}
+
+// recordError adds a failed cycle to the error history, overwriting the
+// oldest entry once the buffer is full
+func (s *Service) recordError(err error) {
+	size := s.config.ErrorHistorySize
+	if size <= 0 {
+		return
+	}
+
+	entry := models.IngestionError{Time: s.now().UTC(), Message: err.Error()}
+
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+	if len(s.recentErrors) < size {
+		s.recentErrors = append(s.recentErrors, entry)
+	} else {
+		s.recentErrors[s.nextError] = entry
+	}
+	s.nextError = (s.nextError + 1) % size
+}
+
+// RecentErrors returns the recorded ingestion errors, newest first
+func (s *Service) RecentErrors() []models.IngestionError {
+	s.errorsMu.Lock()
+	defer s.errorsMu.Unlock()
+
+	result := make([]models.IngestionError, 0, len(s.recentErrors))
+	for i := 1; i <= len(s.recentErrors); i++ {
+		idx := (s.nextError - i + len(s.recentErrors)) % len(s.recentErrors)
+		result = append(result, s.recentErrors[idx])
+	}
+	return result
+}
+
+// ReadOnly reports whether ingestion is paused because storage writes are failing
+func (s *Service) ReadOnly() bool {
+	return s.readOnly.Load()
+}
+
+// recordWriteFailure counts a failed store, entering read-only mode once the
+// threshold is reached
+func (s *Service) recordWriteFailure() {
+	s.writeFailures++
+	if s.config.ReadOnlyThreshold > 0 && s.writeFailures >= s.config.ReadOnlyThreshold && !s.readOnly.Load() {
+		s.readOnly.Store(true)
+		s.logger.Warn("Storage writes are failing, entering read-only mode", "failures", s.writeFailures)
+	}
+}
+
+// probeWrite checks whether storage accepts writes again by recording a
+// read-only status, leaving read-only mode if it does
+func (s *Service) probeWrite(ctx context.Context) bool {
+	status := models.IngestionStatus{
+		LastSuccessfulRun: s.lastSuccess,
+		LastAttempt:       s.now().UTC(),
+		Status:            "read_only",
+	}
+	if err := s.storage.UpdateIngestionStatus(ctx, status); err != nil {
+		return false
+	}
+
+	s.readOnly.Store(false)
+	s.writeFailures = 0
+	s.logger.Info("Storage writes recovered, leaving read-only mode")
+	return true
+}
+
+// recordStatus persists the outcome of an ingestion run
+func (s *Service) recordStatus(ctx context.Context, state string, records int, runErr error) {
+	now := s.now().UTC()
+	status := models.IngestionStatus{
+		LastAttempt:     now,
+		Status:          state,
+		RecordsIngested: records,
+
+		RecordsDeduplicated: s.deduplicated,
+		RecordsSkipped:      s.marshalSkips,
+	}
+	if runErr != nil {
+		status.ErrorMessage = runErr.Error()
+	}
+	if state == "success" {
+		s.lastSuccess = now
+	}
+	status.LastSuccessfulRun = s.lastSuccess
+
+	if err := s.storage.UpdateIngestionStatus(ctx, status); err != nil {
+		s.logger.Error("Failed to update ingestion status", "error", err)
+	}
+}
+
+// fetchPosts fetches posts from the API with retry logic. When the primary
+// upstream exhausts its retries the fallback endpoint, if configured, is
+// tried before giving up. It returns the source tag of the endpoint that
+// served the posts.
+func (s *Service) fetchPosts(ctx context.Context) ([]models.Post, string, error) {
+	posts, err := s.fetchPrimary(ctx)
+	if err == nil {
+		return posts, s.primarySourceName(), nil
+	}
+	if ctx.Err() == nil {
+		s.metrics.fetchFailures.WithLabelValues(s.primarySourceName()).Inc()
+	}
+	if s.config.FallbackAPIEndpoint == "" || ctx.Err() != nil {
+		return nil, "", err
+	}
+
+	s.logger.Warn("Primary upstream failed, trying fallback", "source", s.primarySourceName(), "error", err, "fallback", s.config.FallbackAPIEndpoint)
+	posts, fallbackErr := s.fetchFrom(ctx, s.config.FallbackAPIEndpoint)
+	if fallbackErr != nil {
+		if ctx.Err() == nil {
+			s.metrics.fetchFailures.WithLabelValues(s.fallbackSourceName()).Inc()
+		}
+		return nil, "", fmt.Errorf("%w; fallback: %w", err, fallbackErr)
+	}
+	return posts, s.fallbackSourceName(), nil
+}
+
+// primarySourceName returns the source recorded on posts from the primary
+// upstream and its mirrors
+func (s *Service) primarySourceName() string {
+	if s.config.SourceName != "" {
+		return s.config.SourceName
+	}
+	return primarySource
+}
+
+// fallbackSourceName returns the source recorded on posts from the fallback
+func (s *Service) fallbackSourceName() string {
+	if s.config.FallbackSourceName != "" {
+		return s.config.FallbackSourceName
+	}
+	return fallbackSource
+}
+
+// sourceFor returns the source name of the upstream endpoint belongs to
+func (s *Service) sourceFor(endpoint string) string {
+	if s.isFallback(endpoint) {
+		return s.fallbackSourceName()
+	}
+	return s.primarySourceName()
+}
+
+// isFallback reports whether endpoint is the fallback upstream
+func (s *Service) isFallback(endpoint string) bool {
+	return endpoint != "" && endpoint == s.config.FallbackAPIEndpoint
+}
+
+// fetchPrimary fetches from APIEndpoint, or from the mirror selected for this
+// fetch when APIEndpoints is configured, moving on to the next mirror when one
+// fails
+func (s *Service) fetchPrimary(ctx context.Context) ([]models.Post, error) {
+	if s.balancer == nil {
+		return s.fetchFrom(ctx, s.config.APIEndpoint)
+	}
+
+	var errs []error
+	for _, endpoint := range s.balancer.next(s.now()) {
+		posts, err := s.fetchFrom(ctx, endpoint)
+		if ctx.Err() != nil {
+			return nil, err // Cancellation says nothing about the endpoint
+		}
+		s.balancer.record(endpoint, err, s.now().UTC())
+		if err == nil {
+			return posts, nil
+		}
+
+		s.logger.Warn("Upstream mirror failed, trying the next", "endpoint", endpoint, "error", err)
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// EndpointHealth returns the health of each upstream mirror, or nil when
+// APIEndpoints is unset
+func (s *Service) EndpointHealth() []models.EndpointHealth {
+	if s.balancer == nil {
+		return nil
+	}
+	return s.balancer.snapshot()
+}
+
+// fetchFrom fetches all posts from one endpoint, sharding when configured
+func (s *Service) fetchFrom(ctx context.Context, endpoint string) ([]models.Post, error) {
+	if s.sharded() {
+		return s.fetchSharded(ctx, endpoint)
+	}
+	return s.fetchWithRetry(ctx, endpoint, nil)
+}
+
+// fetchWithRetry fetches posts matching the extra query parameters, retrying
+// failed attempts
+func (s *Service) fetchWithRetry(ctx context.Context, endpoint string, params url.Values) ([]models.Post, error) {
 	var lastErr error
+	attempts := s.retryCountFor(endpoint)
 	
-	for attempt := 0; attempt < s.config.RetryCount; attempt++ {
-		posts, err := s.fetchPostsOnce(ctx)
+	for attempt := 0; attempt < attempts; attempt++ {
+		posts, err := s.fetchPage(ctx, endpoint, params)
 		if err == nil {
 			return posts, nil
 		}
 		
 		lastErr = err
-		if attempt < s.config.RetryCount-1 {
-			// Wait before retrying (exponential backoff)
-			waitTime := time.Duration(attempt+1) * time.Second
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(waitTime):
+		if !s.isRetryable(err, statusCode(err)) {
+			return nil, fmt.Errorf("failed after %d attempts: %w", attempt+1, err)
+		}
+		if attempt < attempts-1 {
+			if err := sleepContext(ctx, s.backoff(attempt)); err != nil {
+				return nil, err
 			}
 		}
 	}
 	
-	return nil, fmt.Errorf("failed after %d attempts: %w", s.config.RetryCount, lastErr)
+	return nil, fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+}
+
+// timeoutFor returns the fetch timeout of the source endpoint belongs to
+func (s *Service) timeoutFor(endpoint string) time.Duration {
+	if s.config.FallbackTimeout > 0 && s.isFallback(endpoint) {
+		return s.config.FallbackTimeout
+	}
+	return s.config.Timeout
+}
+
+// retryCountFor returns the fetch attempts of the source endpoint belongs to
+func (s *Service) retryCountFor(endpoint string) int {
+	if s.config.FallbackRetryCount > 0 && s.isFallback(endpoint) {
+		return s.config.FallbackRetryCount
+	}
+	return s.config.RetryCount
+}
+
+// sortByID orders posts by ID, keeping the fetch order of equal IDs
+func sortByID(posts []models.TransformedPost) {
+	slices.SortStableFunc(posts, func(a, b models.TransformedPost) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+}
+
+// batches splits posts into chunks of at most MaxBatchPerCycle
+func (s *Service) batches(posts []models.TransformedPost) [][]models.TransformedPost {
+	size := s.config.MaxBatchPerCycle
+	if size <= 0 || len(posts) <= size {
+		return [][]models.TransformedPost{posts}
+	}
+
+	var batches [][]models.TransformedPost
+	for start := 0; start < len(posts); start += size {
+		end := start + size
+		if end > len(posts) {
+			end = len(posts)
+		}
+		batches = append(batches, posts[start:end])
+	}
+	return batches
+}
+
+// storePosts stores posts with retry logic and returns those stored. Posts
+// storage couldn't marshal are logged and left out rather than failing the
+// batch, since retrying wouldn't help them.
+func (s *Service) storePosts(ctx context.Context, posts []models.TransformedPost) ([]models.TransformedPost, error) {
+	attempts := s.config.StoreRetryCount
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		err := s.storage.StorePosts(ctx, posts)
+		var skipped *storage.SkippedPostsError
+		if errors.As(err, &skipped) {
+			return s.dropSkipped(posts, skipped.Posts), nil
+		}
+		if err == nil {
+			return posts, nil
+		}
+
+		lastErr = err
+		if attempt < attempts-1 {
+			if err := sleepContext(ctx, s.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if attempts == 1 {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+}
+
+// postKey identifies a post; source is only set with composite keys, where
+// IDs are only unique per source
+type postKey struct {
+	source string
+	id     int
+}
+
+func (s *Service) postKey(source string, id int) postKey {
+	if s.config.CompositeKey {
+		return postKey{source: source, id: id}
+	}
+	return postKey{id: id}
+}
+
+// dropSkipped logs and counts the skipped posts and returns the rest
+func (s *Service) dropSkipped(posts []models.TransformedPost, skipped []storage.SkippedPost) []models.TransformedPost {
+	keys := make(map[postKey]bool, len(skipped))
+	for _, skip := range skipped {
+		s.logger.Error("Skipped post that failed to marshal", "id", skip.ID, "source", skip.Source, "field", skip.Field, "error", skip.Err)
+		keys[s.postKey(skip.Source, skip.ID)] = true
+	}
+	s.marshalSkips += len(skipped)
+
+	stored := make([]models.TransformedPost, 0, len(posts)-len(skipped))
+	for _, post := range posts {
+		if !keys[s.postKey(post.Source, post.ID)] {
+			stored = append(stored, post)
+		}
+	}
+	return stored
+}
+
+// sleepContext waits for d, returning early with the context's error if it
+// is cancelled first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
 }
 
 // fetchPostsOnce performs a single fetch attempt
 func (s *Service) fetchPostsOnce(ctx context.Context) ([]models.Post, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", s.config.APIEndpoint, nil)
+	return s.fetchPage(ctx, s.config.APIEndpoint, nil)
+}
+
+// fetchPage performs a single fetch attempt against endpoint with extra query
+// parameters
+func (s *Service) fetchPage(ctx context.Context, endpoint string, params url.Values) ([]models.Post, error) {
+	source := s.sourceFor(endpoint)
+	if timeout := s.timeoutFor(endpoint); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	endpoint, err := s.requestURL(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	s.forwardHeaders(ctx, req)
 
+	start := s.now()
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
 	}
 	defer resp.Body.Close()
+	s.recordRateLimit(source, resp.Header)
+	s.recordLastFetch(req.URL, resp)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+		return nil, &statusError{code: resp.StatusCode}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -122,26 +925,276 @@ func (s *Service) fetchPostsOnce(ctx context.Context) ([]models.Post, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !isJSONContentType(contentType) {
+		return nil, fmt.Errorf("unexpected content type %q, body: %q", contentType, snippet(body))
+	}
+
+	if s.config.JSONPath != "" {
+		if body, err = extractJSONPath(body, s.config.JSONPath); err != nil {
+			return nil, err
+		}
+	}
+
+	body = asArray(body)
+
 	var posts []models.Post
 	if err := json.Unmarshal(body, &posts); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	if field := s.config.CreatedAtField; field != "" && field != "createdAt" {
+		if err := mapCreatedAt(body, field, posts); err != nil {
+			return nil, err
+		}
+	}
+
+	latency := s.now().Sub(start)
+	s.checkLatency(source, endpoint, latency)
+	s.observe(ctx, s.metrics.fetchDuration.WithLabelValues(source), latency)
 	return posts, nil
 }
 
-// transformPosts adds ingestion metadata to posts
-func (s *Service) transformPosts(posts []models.Post) []models.TransformedPost {
-	now := time.Now().UTC()
-	transformed := make([]models.TransformedPost, len(posts))
+// checkLatency warns about a successful fetch that took longer than
+// SlowFetchThreshold, giving notice before the upstream starts timing out
+func (s *Service) checkLatency(source, endpoint string, latency time.Duration) {
+	if s.config.SlowFetchThreshold <= 0 || latency <= s.config.SlowFetchThreshold {
+		return
+	}
 
+	s.metrics.slowFetches.WithLabelValues(source).Inc()
+	s.logger.Warn("Slow upstream fetch", "source", source, "endpoint", endpoint, "latency", latency, "threshold", s.config.SlowFetchThreshold)
+}
+
+// extractJSONPath returns the JSON array of values selected by path. A path
+// selecting a single array, e.g. "$.result.items", yields that array.
+func extractJSONPath(body []byte, path string) ([]byte, error) {
+	expr, err := jp.ParseString(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %w", path, err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	selected := expr.Get(doc)
+	if len(selected) == 1 {
+		if items, ok := selected[0].([]interface{}); ok {
+			selected = items
+		}
+	}
+	if selected == nil {
+		selected = []interface{}{}
+	}
+
+	return json.Marshal(selected)
+}
+
+// asArray wraps a JSON object in a one-element array, for upstreams that
+// return a bare object when there is only one record. Anything else is
+// returned unchanged.
+func asArray(body []byte) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return body
+	}
+
+	wrapped := make([]byte, 0, len(trimmed)+2)
+	wrapped = append(wrapped, '[')
+	wrapped = append(wrapped, trimmed...)
+	return append(wrapped, ']')
+}
+
+// isJSONContentType reports whether a Content-Type header denotes JSON,
+// e.g. "application/json; charset=utf-8" or "application/vnd.api+json"
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// snippet truncates a response body for inclusion in error messages
+func snippet(body []byte) string {
+	const maxSnippet = 200
+	if len(body) <= maxSnippet {
+		return string(body)
+	}
+	return string(body[:maxSnippet]) + "..."
+}
+
+// mapCreatedAt populates CreatedAt from a custom upstream field name
+func mapCreatedAt(body []byte, field string, posts []models.Post) error {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	for i := range posts {
+		value, ok := raw[i][field]
+		if !ok {
+			continue
+		}
+
+		var createdAt time.Time
+		if err := json.Unmarshal(value, &createdAt); err != nil {
+			return fmt.Errorf("invalid %s for post %d: %w", field, posts[i].ID, err)
+		}
+		posts[i].CreatedAt = &createdAt
+	}
+
+	return nil
+}
+
+// requestURL builds the upstream URL, merging configured query parameters
+// with any already present in the endpoint
+func (s *Service) requestURL(endpoint string, params url.Values) (string, error) {
+	if len(s.config.QueryParams) == 0 && len(params) == 0 {
+		return endpoint, nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid API endpoint: %w", err)
+	}
+
+	query := u.Query()
+	for key, value := range s.config.QueryParams {
+		query.Set(key, value)
+	}
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// dedupeByID drops all but the last occurrence of each post ID, returning the
+// unique posts in order of their last occurrence and the number dropped
+func dedupeByID(posts []models.Post) ([]models.Post, int) {
+	last := make(map[int]int, len(posts))
+	for i, post := range posts {
+		last[post.ID] = i
+	}
+	if len(last) == len(posts) {
+		return posts, 0
+	}
+
+	unique := make([]models.Post, 0, len(last))
 	for i, post := range posts {
-		transformed[i] = models.TransformedPost{
+		if last[post.ID] == i {
+			unique = append(unique, post)
+		}
+	}
+	return unique, len(posts) - len(unique)
+}
+
+// filterSeen drops posts whose IDs are already in the dedup filter. With
+// composite keys IDs are only unique per source, so they're looked up
+// under source.
+func (s *Service) filterSeen(posts []models.Post, source string) ([]models.Post, int) {
+	if s.seen == nil {
+		return posts, 0
+	}
+
+	fresh := make([]models.Post, 0, len(posts))
+	for _, post := range posts {
+		seen := s.seen.Contains(post.ID)
+		if s.config.CompositeKey {
+			seen = s.seen.ContainsSourceID(source, post.ID)
+		}
+		if !seen {
+			fresh = append(fresh, post)
+		}
+	}
+	return fresh, len(posts) - len(fresh)
+}
+
+// flushed records posts a buffering storage has written. Until then they
+// aren't marked seen, so posts lost with the buffer are fetched again.
+func (s *Service) flushed(posts []models.TransformedPost) {
+	s.markSeen(posts)
+	for _, post := range posts {
+		s.metrics.postsIngested.WithLabelValues(post.Source).Inc()
+	}
+}
+
+// markSeen records stored posts in the dedup filter and persists it
+func (s *Service) markSeen(posts []models.TransformedPost) {
+	if s.seen == nil || len(posts) == 0 {
+		return
+	}
+
+	for _, post := range posts {
+		if s.config.CompositeKey {
+			s.seen.AddSourceID(post.Source, post.ID)
+		} else {
+			s.seen.Add(post.ID)
+		}
+	}
+	if err := s.seen.Save(s.config.DedupFilterPath); err != nil {
+		s.logger.Error("Failed to persist dedup filter", "error", err)
+	}
+}
+
+// filterPostsByAge drops posts whose CreatedAt falls outside the configured
+// age window. Posts without a CreatedAt are always kept.
+func (s *Service) filterPostsByAge(posts []models.Post) ([]models.Post, int) {
+	if s.config.MinPostAge == 0 && s.config.MaxPostAge == 0 {
+		return posts, 0
+	}
+
+	now := s.now().UTC()
+	kept := make([]models.Post, 0, len(posts))
+	skipped := 0
+
+	for _, post := range posts {
+		if post.CreatedAt != nil {
+			age := now.Sub(*post.CreatedAt)
+			if (s.config.MinPostAge > 0 && age < s.config.MinPostAge) ||
+				(s.config.MaxPostAge > 0 && age > s.config.MaxPostAge) {
+				skipped++
+				continue
+			}
+		}
+		kept = append(kept, post)
+	}
+
+	return kept, skipped
+}
+
+// transformPosts adds ingestion metadata to posts fetched from source
+func (s *Service) transformPosts(posts []models.Post, source string) []models.TransformedPost {
+	now := s.now().UTC()
+	if s.config.IngestedAtOverride != nil {
+		now = s.config.IngestedAtOverride.UTC()
+	}
+	transformed := make([]models.TransformedPost, 0, len(posts))
+
+	for _, post := range posts {
+		tp := models.TransformedPost{
 			Post:       post,
 			IngestedAt: now,
-			Source:     "placeholder_api",
+			Source:     source,
+		}
+		if s.applyTransformers(&tp) {
+			transformed = append(transformed, tp)
 		}
 	}
 
 	return transformed
+}
+
+// applyTransformers runs the configured transformers, reporting whether the
+// post should be kept
+func (s *Service) applyTransformers(post *models.TransformedPost) bool {
+	for _, transform := range s.transformers {
+		if !transform(post) {
+			return false
+		}
+	}
+	return true
 }
\ No newline at end of file
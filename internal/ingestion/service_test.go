@@ -1,10 +1,20 @@
 package ingestion
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -13,6 +23,7 @@ import (
 
 	"github.com/cyderes/data-ingestion-service/internal/config"
 	"github.com/cyderes/data-ingestion-service/internal/models"
+	"github.com/cyderes/data-ingestion-service/internal/storage"
 )
 
 // MockStorage is a mock implementation of the Storage interface
@@ -30,11 +41,67 @@ func (m *MockStorage) GetPosts(ctx context.Context, limit int, offset int) ([]mo
 	return args.Get(0).([]models.TransformedPost), args.Error(1)
 }
 
+func (m *MockStorage) GetPostsByIngestionRange(ctx context.Context, from, to time.Time, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, from, to, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetPostsByCategory(ctx context.Context, category string, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, category, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetPostsByRunID(ctx context.Context, runID string, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, runID, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+// ExportPosts passes fn the posts set up with On("ExportPosts", ctx, asOf)
+func (m *MockStorage) ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error {
+	args := m.Called(ctx, asOf)
+	for _, post := range args.Get(0).([]models.TransformedPost) {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
 func (m *MockStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*models.TransformedPost), args.Error(1)
 }
 
+func (m *MockStorage) GetLatestPost(ctx context.Context) (*models.TransformedPost, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetUserIDs(ctx context.Context) ([]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockStorage) StoreComments(ctx context.Context, comments []models.Comment) error {
+	args := m.Called(ctx, comments)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetComments(ctx context.Context, postID int) ([]models.Comment, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).([]models.Comment), args.Error(1)
+}
+
+func (m *MockStorage) DeletePost(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeletePosts(ctx context.Context, ids []int) (int, error) {
+	args := m.Called(ctx, ids)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockStorage) UpdateIngestionStatus(ctx context.Context, status models.IngestionStatus) error {
 	args := m.Called(ctx, status)
 	return args.Error(0)
@@ -45,6 +112,16 @@ func (m *MockStorage) GetIngestionStatus(ctx context.Context) (*models.Ingestion
 	return args.Get(0).(*models.IngestionStatus), args.Error(1)
 }
 
+func (m *MockStorage) RecordMetricsSnapshot(ctx context.Context, snapshot models.MetricsSnapshot) error {
+	args := m.Called(ctx, snapshot)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetMetricsHistory(ctx context.Context, limit int) ([]models.MetricsSnapshot, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]models.MetricsSnapshot), args.Error(1)
+}
+
 func (m *MockStorage) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -84,6 +161,93 @@ func TestService_fetchPostsOnce(t *testing.T) {
 	assert.Equal(t, testPosts[0].Title, posts[0].Title)
 }
 
+// stubDoer returns a canned response without touching the network
+type stubDoer struct {
+	status   int
+	body     string
+	requests []*http.Request
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.requests = append(d.requests, req)
+	return &http.Response{
+		StatusCode: d.status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(d.body)),
+	}, nil
+}
+
+func TestService_fetchPostsOnce_WithHTTPClient(t *testing.T) {
+	// Create service with a stub client instead of a server
+	doer := &stubDoer{
+		status: http.StatusOK,
+		body:   `[{"userId": 1, "id": 7, "title": "Stubbed", "body": "From stub"}]`,
+	}
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		APIEndpoint: "http://upstream.invalid/posts",
+		RetryCount:  1,
+	}
+
+	service := NewService(cfg, mockStorage, WithHTTPClient(doer))
+
+	// Test fetchPostsOnce
+	posts, err := service.fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, posts, 1) {
+		assert.Equal(t, 7, posts[0].ID)
+		assert.Equal(t, "Stubbed", posts[0].Title)
+	}
+	if assert.Len(t, doer.requests, 1) {
+		assert.Equal(t, "http://upstream.invalid/posts", doer.requests[0].URL.String())
+	}
+}
+
+func TestService_fetchPostsOnce_SingleObject(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantIDs []int
+	}{
+		{"array", `[{"userId": 1, "id": 1, "title": "First"}, {"userId": 1, "id": 2, "title": "Second"}]`, []int{1, 2}},
+		{"single object", `{"userId": 1, "id": 3, "title": "Only"}`, []int{3}},
+		{"single object with whitespace", "\n  {\"userId\": 1, \"id\": 4, \"title\": \"Padded\"}\n", []int{4}},
+		{"empty array", `[]`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doer := &stubDoer{status: http.StatusOK, body: tt.body}
+			cfg := config.IngestionConfig{APIEndpoint: "http://upstream.invalid/posts", RetryCount: 1}
+			service := NewService(cfg, nil, WithHTTPClient(doer))
+
+			posts, err := service.fetchPostsOnce(context.Background())
+
+			assert.NoError(t, err)
+			var ids []int
+			for _, post := range posts {
+				ids = append(ids, post.ID)
+				assert.Equal(t, 1, post.UserID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+		})
+	}
+}
+
+func TestService_fetchPostsOnce_SingleObject_CreatedAtField(t *testing.T) {
+	doer := &stubDoer{status: http.StatusOK, body: `{"userId": 1, "id": 5, "title": "Only", "published": "2024-01-15T10:30:00Z"}`}
+	cfg := config.IngestionConfig{APIEndpoint: "http://upstream.invalid/posts", RetryCount: 1, CreatedAtField: "published"}
+	service := NewService(cfg, nil, WithHTTPClient(doer))
+
+	posts, err := service.fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, posts, 1) && assert.NotNil(t, posts[0].CreatedAt) {
+		assert.Equal(t, time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), posts[0].CreatedAt.UTC())
+	}
+}
+
 func TestService_fetchPostsOnce_APIError(t *testing.T) {
 	// Create mock server that returns error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -137,6 +301,69 @@ func TestService_fetchPostsOnce_InvalidJSON(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to unmarshal response")
 }
 
+func TestService_fetchPostsOnce_QueryParams(t *testing.T) {
+	var receivedQuery url.Values
+
+	// Create mock server that records the query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{})
+	}))
+	defer server.Close()
+
+	// Create service with an endpoint that already carries a query parameter
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		APIEndpoint: server.URL + "?userId=1",
+		Timeout:     30 * time.Second,
+		RetryCount:  3,
+		QueryParams: map[string]string{
+			"version": "2",
+			"format":  "json",
+		},
+	}
+
+	service := NewService(cfg, mockStorage)
+
+	// Test fetchPostsOnce
+	ctx := context.Background()
+	_, err := service.fetchPostsOnce(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1", receivedQuery.Get("userId"))
+	assert.Equal(t, "2", receivedQuery.Get("version"))
+	assert.Equal(t, "json", receivedQuery.Get("format"))
+}
+
+func TestService_fetchPostsOnce_UnexpectedContentType(t *testing.T) {
+	// Create mock server that returns an HTML error page with a 200
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body>Service temporarily unavailable</body></html>"))
+	}))
+	defer server.Close()
+
+	// Create service
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		APIEndpoint: server.URL,
+		Timeout:     30 * time.Second,
+		RetryCount:  3,
+	}
+
+	service := NewService(cfg, mockStorage)
+
+	// Test fetchPostsOnce
+	ctx := context.Background()
+	posts, err := service.fetchPostsOnce(ctx)
+
+	assert.Error(t, err)
+	assert.Nil(t, posts)
+	assert.Contains(t, err.Error(), `unexpected content type "text/html; charset=utf-8"`)
+	assert.Contains(t, err.Error(), "Service temporarily unavailable")
+}
+
 func TestService_transformPosts(t *testing.T) {
 	// Create test data
 	originalPosts := []models.Post{
@@ -150,7 +377,7 @@ func TestService_transformPosts(t *testing.T) {
 	service := NewService(cfg, mockStorage)
 
 	// Test transformPosts
-	transformedPosts := service.transformPosts(originalPosts)
+	transformedPosts := service.transformPosts(originalPosts, primarySource)
 
 	assert.Len(t, transformedPosts, 2)
 	
@@ -164,6 +391,89 @@ func TestService_transformPosts(t *testing.T) {
 	}
 }
 
+func TestService_transformPosts_IngestedAtOverride(t *testing.T) {
+	// Create test data
+	originalPosts := []models.Post{
+		{UserID: 1, ID: 1, Title: "Test Post 1"},
+		{UserID: 1, ID: 2, Title: "Test Post 2"},
+		{UserID: 2, ID: 3, Title: "Test Post 3"},
+	}
+
+	// Create service with a fixed ingestion time in another zone
+	override := time.Date(2024, 1, 15, 12, 30, 0, 0, time.FixedZone("EET", 2*60*60))
+	cfg := config.IngestionConfig{IngestedAtOverride: &override}
+	service := NewService(cfg, new(MockStorage))
+
+	// Test every post carries the override, in UTC
+	transformedPosts := service.transformPosts(originalPosts, primarySource)
+
+	assert.Len(t, transformedPosts, 3)
+	for _, post := range transformedPosts {
+		assert.Equal(t, time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), post.IngestedAt)
+	}
+}
+
+func TestService_filterPostsByAge(t *testing.T) {
+	now := time.Now().UTC()
+	tooNew := now.Add(-time.Minute)
+	inWindow := now.Add(-2 * time.Hour)
+	tooOld := now.Add(-48 * time.Hour)
+
+	// Create test data
+	posts := []models.Post{
+		{ID: 1, Title: "Too new", CreatedAt: &tooNew},
+		{ID: 2, Title: "In window", CreatedAt: &inWindow},
+		{ID: 3, Title: "Too old", CreatedAt: &tooOld},
+		{ID: 4, Title: "No timestamp"},
+	}
+
+	// Create service with an age window of 1h-24h
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		MinPostAge: time.Hour,
+		MaxPostAge: 24 * time.Hour,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test filterPostsByAge
+	kept, skipped := service.filterPostsByAge(posts)
+
+	assert.Equal(t, 2, skipped)
+	assert.Len(t, kept, 2)
+	assert.Equal(t, 2, kept[0].ID)
+	assert.Equal(t, 4, kept[1].ID)
+}
+
+func TestService_fetchPostsOnce_CreatedAtField(t *testing.T) {
+	// Create mock server using a custom timestamp field
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": 1, "title": "Test Post 1", "published": "2024-01-15T10:30:00Z"}]`))
+	}))
+	defer server.Close()
+
+	// Create service
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		APIEndpoint:    server.URL,
+		Timeout:        30 * time.Second,
+		RetryCount:     3,
+		CreatedAtField: "published",
+	}
+
+	service := NewService(cfg, mockStorage)
+
+	// Test fetchPostsOnce
+	ctx := context.Background()
+	posts, err := service.fetchPostsOnce(ctx)
+
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	if assert.NotNil(t, posts[0].CreatedAt) {
+		assert.Equal(t, time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC), *posts[0].CreatedAt)
+	}
+}
+
 func TestService_IngestData(t *testing.T) {
 	// Create test data
 	testPosts := []models.Post{
@@ -258,11 +568,12 @@ func TestService_fetchPosts_WithRetry(t *testing.T) {
 		RetryCount:  3,
 	}
 	
-	service := NewService(cfg, mockStorage)
+	// Retry at once, so the test doesn't wait out real backoff
+	service := NewService(cfg, mockStorage, WithBackoff(func(int) time.Duration { return 0 }))
 
 	// Test fetchPosts with retry
 	ctx := context.Background()
-	posts, err := service.fetchPosts(ctx)
+	posts, _, err := service.fetchPosts(ctx)
 
 	assert.NoError(t, err)
 	assert.Len(t, posts, 1)
@@ -288,9 +599,1128 @@ func TestService_fetchPosts_ExceedsRetryLimit(t *testing.T) {
 
 	// Test fetchPosts with exceeded retry limit
 	ctx := context.Background()
-	posts, err := service.fetchPosts(ctx)
+	posts, _, err := service.fetchPosts(ctx)
 
 	assert.Error(t, err)
 	assert.Nil(t, posts)
 	assert.Contains(t, err.Error(), "failed after 3 attempts")
-}
\ No newline at end of file
+}
+
+func TestService_IngestData_FallbackEndpoint(t *testing.T) {
+	// Create a primary that always fails and a healthy fallback
+	primaryCalls := 0
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{
+			{UserID: 1, ID: 1, Title: "From fallback", Body: "Fallback body"},
+		})
+	}))
+	defer fallback.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.MatchedBy(func(posts []models.TransformedPost) bool {
+		return len(posts) == 1 && posts[0].Title == "From fallback" && posts[0].Source == fallbackSource
+	})).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         primary.URL,
+		FallbackAPIEndpoint: fallback.URL,
+		Timeout:             30 * time.Second,
+		RetryCount:          2,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test IngestData stores the fallback's posts tagged with their source
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, primaryCalls, "primary should exhaust its retries first")
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_fetchPosts_FallbackFails(t *testing.T) {
+	// Create mock servers that both fail
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         server.URL,
+		FallbackAPIEndpoint: server.URL + "/fallback",
+		Timeout:             30 * time.Second,
+		RetryCount:          1,
+	}
+	service := NewService(cfg, nil)
+
+	// Test fetchPosts reports both failures
+	posts, source, err := service.fetchPosts(context.Background())
+
+	assert.Error(t, err)
+	assert.Nil(t, posts)
+	assert.Empty(t, source)
+	assert.Contains(t, err.Error(), "fallback")
+}
+
+func TestService_fetchPosts_FallbackTimeout(t *testing.T) {
+	// Create a server slower than the primary's timeout but not the fallback's
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		mu.Unlock()
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Slow", Body: "Slow body"}})
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         server.URL + "/primary",
+		FallbackAPIEndpoint: server.URL + "/fallback",
+		Timeout:             20 * time.Millisecond,
+		RetryCount:          1,
+		FallbackTimeout:     time.Second,
+		FallbackRetryCount:  2,
+	}
+	service := NewService(cfg, nil)
+
+	// Test each source fetches with its own timeout
+	posts, source, err := service.fetchPosts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, fallbackSource, source)
+	assert.Equal(t, map[string]int{"/primary": 1, "/fallback": 1}, calls)
+
+	// Test the fallback's retry count falls back to the global one when unset
+	assert.Equal(t, 2, service.retryCountFor(cfg.FallbackAPIEndpoint))
+	service.config.FallbackRetryCount = 0
+	assert.Equal(t, 1, service.retryCountFor(cfg.FallbackAPIEndpoint))
+	assert.Equal(t, 20*time.Millisecond, service.timeoutFor(cfg.APIEndpoint))
+}
+
+func TestService_Start_MaxCycles(t *testing.T) {
+	for _, maxCycles := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d cycles", maxCycles), func(t *testing.T) {
+			// Create mock server counting fetches
+			var fetches atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches.Add(1)
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post"}})
+			}))
+			defer server.Close()
+
+			mockStorage := new(MockStorage)
+			mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+			cfg := config.IngestionConfig{
+				APIEndpoint: server.URL,
+				Interval:    10 * time.Millisecond,
+				Timeout:     30 * time.Second,
+				RetryCount:  1,
+				MaxCycles:   maxCycles,
+			}
+			service := NewService(cfg, mockStorage)
+
+			// Test Start returns cleanly after the configured cycles
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := service.Start(ctx)
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(maxCycles), fetches.Load())
+		})
+	}
+}
+
+func TestService_Start_ConcurrentTriggers(t *testing.T) {
+	// Create mock server that always fails, so every cycle updates the
+	// failure count the scheduler reads
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.Anything).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:       server.URL,
+		Interval:          time.Millisecond,
+		Timeout:           30 * time.Second,
+		RetryCount:        1,
+		MaxCycles:         20,
+		MaxStalePeriod:    time.Hour, // Keeps the failing initial cycle from stopping Start
+		DegradedThreshold: 2,
+		DegradedInterval:  time.Millisecond,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test API-triggered cycles can run alongside the scheduler; run with
+	// -race to catch unsynchronized state
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var triggers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		triggers.Add(1)
+		go func() {
+			defer triggers.Done()
+			for j := 0; j < 5; j++ {
+				service.IngestData(ctx)
+			}
+		}()
+	}
+
+	assert.NoError(t, service.Start(ctx))
+	triggers.Wait()
+}
+
+func TestService_Start_NoStorageAfterClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post"}})
+	}))
+	defer server.Close()
+
+	// Create storage whose reads are slow enough to be in flight at shutdown,
+	// counting those still running once it's closed
+	var closed atomic.Bool
+	var reads, afterClose atomic.Int64
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("GetPosts", mock.Anything, mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		reads.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		if closed.Load() {
+			afterClose.Add(1)
+		}
+	}).Return([]models.TransformedPost{}, nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         server.URL,
+		Interval:            time.Hour,
+		Timeout:             30 * time.Second,
+		RetryCount:          1,
+		ReconcileInterval:   5 * time.Millisecond,
+		ReconcileSampleRate: 1,
+	}
+	service := NewService(cfg, mockStorage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- service.Start(ctx) }()
+	assert.Eventually(t, func() bool { return reads.Load() > 0 }, time.Second, time.Millisecond)
+
+	// Test shutting down the way main does: cancel, wait for Start, then close
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	closed.Store(true)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, afterClose.Load(), "storage was used after Close")
+}
+
+func TestService_Start_DeadManSwitch(t *testing.T) {
+	// Create mock server that fails until healthy is set
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post"}})
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:    server.URL,
+		Interval:       10 * time.Millisecond,
+		Timeout:        30 * time.Second,
+		RetryCount:     1,
+		MaxStalePeriod: 100 * time.Millisecond,
+	}
+
+	// Test a perpetually failing loop trips the switch, initial failure included
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	started := time.Now()
+	err := NewService(cfg, mockStorage).Start(ctx)
+
+	assert.ErrorIs(t, err, ErrStale)
+	assert.GreaterOrEqual(t, time.Since(started), cfg.MaxStalePeriod)
+	assert.NoError(t, ctx.Err(), "the switch should trip before the test's deadline")
+
+	// Test a succeeding loop keeps running past the period
+	healthy.Store(true)
+	ctx, cancel = context.WithTimeout(context.Background(), 3*cfg.MaxStalePeriod)
+	defer cancel()
+	err = NewService(cfg, mockStorage).Start(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestService_IngestData_MaxRunDuration(t *testing.T) {
+	// Create mock server slower than the run may take
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.MatchedBy(func(status models.IngestionStatus) bool {
+		return status.Status == "timed_out"
+	})).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:    server.URL,
+		Timeout:        30 * time.Second,
+		RetryCount:     3,
+		MaxRunDuration: 50 * time.Millisecond,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test the run aborts once the max duration elapses
+	start := time.Now()
+	err := service.IngestData(context.Background())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "ingestion run exceeded 50ms")
+	assert.Less(t, time.Since(start), time.Second, "the run should not wait out the slow upstream or retries")
+	mockStorage.AssertExpectations(t)
+	mockStorage.AssertNotCalled(t, "StorePosts", mock.Anything, mock.Anything)
+}
+
+func TestService_IngestData_DegradedAfterConsecutiveFailures(t *testing.T) {
+	failing := true
+
+	// Create mock server that fails until told otherwise
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}})
+	}))
+	defer server.Close()
+
+	// Create service with mock storage
+	mockStorage := new(MockStorage)
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.MatchedBy(func(s models.IngestionStatus) bool {
+		return s.Status == "degraded"
+	})).Return(nil).Once()
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.MatchedBy(func(s models.IngestionStatus) bool {
+		return s.Status == "success"
+	})).Return(nil).Once()
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:       server.URL,
+		Interval:          5 * time.Minute,
+		Timeout:           30 * time.Second,
+		RetryCount:        1,
+		DegradedThreshold: 2,
+		DegradedInterval:  30 * time.Minute,
+	}
+
+	service := NewService(cfg, mockStorage)
+	ctx := context.Background()
+
+	// First failure keeps the normal interval
+	assert.Error(t, service.IngestData(ctx))
+	assert.Equal(t, 5*time.Minute, service.nextDelay())
+
+	// Second failure reaches the threshold
+	assert.Error(t, service.IngestData(ctx))
+	assert.Equal(t, 30*time.Minute, service.nextDelay())
+
+	// Recovery restores the normal interval
+	failing = false
+	assert.NoError(t, service.IngestData(ctx))
+	assert.Equal(t, 5*time.Minute, service.nextDelay())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_IngestData_AdaptivePolling(t *testing.T) {
+	var testPosts []models.Post
+
+	// Create mock server returning whatever testPosts currently holds
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	// Create service with mock storage
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:         server.URL,
+		Interval:            time.Minute,
+		Timeout:             30 * time.Second,
+		RetryCount:          1,
+		EmptyCycleThreshold: 2,
+		MaxInterval:         5 * time.Minute,
+	}
+
+	service := NewService(cfg, mockStorage)
+	ctx := context.Background()
+
+	// Empty cycles lengthen the interval once the threshold is reached, up to the cap
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}
+	for _, want := range expected {
+		assert.NoError(t, service.IngestData(ctx))
+		assert.Equal(t, want, service.nextDelay())
+	}
+
+	// New data restores the configured interval
+	testPosts = []models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}}
+	assert.NoError(t, service.IngestData(ctx))
+	assert.Equal(t, time.Minute, service.nextDelay())
+}
+
+func TestService_storePosts_CancelDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Create mock storage that fails and cancels the context on the first call
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) { cancel() }).
+		Return(assert.AnError).Once()
+
+	cfg := config.IngestionConfig{
+		StoreRetryCount: 3,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test storePosts returns as soon as the context is cancelled
+	start := time.Now()
+	_, err := service.storePosts(ctx, []models.TransformedPost{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_IngestData_CycleRetry(t *testing.T) {
+	fetches := 0
+
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: fetches, Title: "Test Post"}})
+	}))
+	defer server.Close()
+
+	// Create mock storage failing twice then succeeding, recording each batch
+	var batches [][]models.TransformedPost
+	record := func(args mock.Arguments) {
+		batches = append(batches, args.Get(1).([]models.TransformedPost))
+	}
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(record).Return(errors.New("write throttled")).Twice()
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(record).Return(nil).Once()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:     server.URL,
+		Timeout:         30 * time.Second,
+		RetryCount:      1,
+		StoreRetryCount: 1,
+		CycleRetryCount: 2,
+	}
+	service := NewService(cfg, mockStorage, WithBackoff(func(int) time.Duration { return 0 }))
+
+	// Test the cycle succeeds by storing the posts it already fetched
+	assert.NoError(t, service.IngestData(context.Background()))
+	assert.Equal(t, 1, fetches)
+	if assert.Len(t, batches, 3) {
+		assert.Equal(t, batches[0], batches[1])
+		assert.Equal(t, batches[0], batches[2])
+	}
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_fetchPostsOnce_ForwardedHeaders(t *testing.T) {
+	var received http.Header
+
+	// Create mock server that records request headers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{})
+	}))
+	defer server.Close()
+
+	// Create service
+	mockStorage := new(MockStorage)
+	cfg := config.IngestionConfig{
+		APIEndpoint:    server.URL,
+		Timeout:        30 * time.Second,
+		RetryCount:     1,
+		ForwardHeaders: []string{"traceparent", "x-b3-*"},
+	}
+
+	service := NewService(cfg, mockStorage)
+
+	incoming := http.Header{}
+	incoming.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set("X-B3-TraceId", "80f198ee56343ba864fe8b2a57d3eff7")
+	incoming.Set("Authorization", "Bearer secret")
+
+	// Test headers are only forwarded when carried by the context
+	_, err := service.fetchPostsOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, received.Get("Traceparent"))
+
+	ctx := WithForwardedHeaders(context.Background(), incoming)
+	_, err = service.fetchPostsOnce(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, incoming.Get("Traceparent"), received.Get("Traceparent"))
+	assert.Equal(t, incoming.Get("X-B3-TraceId"), received.Get("X-B3-TraceId"))
+	assert.Empty(t, received.Get("Authorization"), "unlisted headers must not be forwarded")
+}
+
+func TestService_IngestData_SkipsSeenAcrossRestart(t *testing.T) {
+	testPosts := []models.Post{
+		{UserID: 1, ID: 1, Title: "Test Post 1"},
+		{UserID: 1, ID: 2, Title: "Test Post 2"},
+	}
+
+	// Create mock server returning whatever testPosts currently holds
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:            server.URL,
+		Timeout:                30 * time.Second,
+		RetryCount:             1,
+		DedupFilterPath:        filepath.Join(t.TempDir(), "seen.bloom"),
+		DedupExpectedItems:     1000,
+		DedupFalsePositiveRate: 0.001,
+	}
+
+	storedIDs := func(posts []models.TransformedPost) []int {
+		ids := make([]int, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		return ids
+	}
+
+	// First run stores everything
+	firstStorage := new(MockStorage)
+	firstStorage.On("StorePosts", mock.Anything, mock.MatchedBy(func(posts []models.TransformedPost) bool {
+		return assert.ObjectsAreEqual([]int{1, 2}, storedIDs(posts))
+	})).Return(nil).Once()
+
+	assert.NoError(t, NewService(cfg, firstStorage).IngestData(context.Background()))
+	firstStorage.AssertExpectations(t)
+
+	// After a restart only the new post is stored
+	testPosts = append(testPosts, models.Post{UserID: 1, ID: 3, Title: "Test Post 3"})
+	secondStorage := new(MockStorage)
+	secondStorage.On("StorePosts", mock.Anything, mock.MatchedBy(func(posts []models.TransformedPost) bool {
+		return assert.ObjectsAreEqual([]int{3}, storedIDs(posts))
+	})).Return(nil).Once()
+
+	assert.NoError(t, NewService(cfg, secondStorage).IngestData(context.Background()))
+	secondStorage.AssertExpectations(t)
+}
+
+func TestService_IngestData_SkipsSeenPerSource(t *testing.T) {
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}})
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:            server.URL,
+		Timeout:                30 * time.Second,
+		RetryCount:             1,
+		SourceName:             "source_a",
+		DedupFilterPath:        filepath.Join(t.TempDir(), "seen.bloom"),
+		DedupExpectedItems:     1000,
+		DedupFalsePositiveRate: 0.001,
+		CompositeKey:           true,
+	}
+
+	// First source stores the post
+	firstStorage := new(MockStorage)
+	firstStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil).Once()
+	assert.NoError(t, NewService(cfg, firstStorage).IngestData(context.Background()))
+	firstStorage.AssertExpectations(t)
+
+	// Test another source's post with the same ID isn't skipped
+	cfg.SourceName = "source_b"
+	secondStorage := new(MockStorage)
+	secondStorage.On("StorePosts", mock.Anything, mock.MatchedBy(func(posts []models.TransformedPost) bool {
+		return len(posts) == 1 && posts[0].ID == 1 && posts[0].Source == "source_b"
+	})).Return(nil).Once()
+	assert.NoError(t, NewService(cfg, secondStorage).IngestData(context.Background()))
+	secondStorage.AssertExpectations(t)
+
+	// Test the first source's post still is
+	cfg.SourceName = "source_a"
+	thirdStorage := new(MockStorage)
+	thirdStorage.On("StorePosts", mock.Anything, mock.MatchedBy(func(posts []models.TransformedPost) bool {
+		return len(posts) == 0
+	})).Return(nil).Once()
+	assert.NoError(t, NewService(cfg, thirdStorage).IngestData(context.Background()))
+	thirdStorage.AssertExpectations(t)
+}
+
+func TestService_IngestData_BufferedMarksSeenOnFlush(t *testing.T) {
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}, {UserID: 1, ID: 2, Title: "Test Post 2"}})
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:            server.URL,
+		Timeout:                30 * time.Second,
+		RetryCount:             1,
+		DedupFilterPath:        filepath.Join(t.TempDir(), "seen.bloom"),
+		DedupExpectedItems:     1000,
+		DedupFalsePositiveRate: 0.001,
+	}
+
+	// Create storage buffering posts in front of one whose first write fails
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(errors.New("write throttled")).Once()
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil).Once()
+	buffered := storage.NewBufferedStorage(mockStorage, 100, 0, false)
+	service := NewService(cfg, buffered)
+
+	// Test buffered posts aren't marked seen, in memory or on disk
+	assert.NoError(t, service.IngestData(context.Background()))
+	assert.False(t, service.seen.Contains(1))
+	assert.Error(t, buffered.Flush(context.Background()))
+	assert.False(t, service.seen.Contains(1))
+	assert.False(t, NewService(cfg, mockStorage).seen.Contains(1))
+
+	// Test they are once a flush writes them
+	assert.NoError(t, buffered.Flush(context.Background()))
+	assert.True(t, service.seen.Contains(1))
+	assert.True(t, service.seen.Contains(2))
+	assert.True(t, NewService(cfg, mockStorage).seen.Contains(2))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_IngestData_MaxBatchPerCycle(t *testing.T) {
+	// Create test data larger than the batch size
+	var testPosts []models.Post
+	for id := 1; id <= 5; id++ {
+		testPosts = append(testPosts, models.Post{UserID: 1, ID: id, Title: "Test Post"})
+	}
+
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	// Create service with mock storage expecting three batches
+	var batchSizes []int
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) {
+			batchSizes = append(batchSizes, len(args.Get(1).([]models.TransformedPost)))
+		}).
+		Return(nil)
+
+	var statuses []models.IngestionStatus
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.AnythingOfType("models.IngestionStatus")).
+		Run(func(args mock.Arguments) {
+			statuses = append(statuses, args.Get(1).(models.IngestionStatus))
+		}).
+		Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:      server.URL,
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		MaxBatchPerCycle: 2,
+	}
+
+	service := NewService(cfg, mockStorage)
+
+	// Test IngestData
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 1}, batchSizes)
+
+	// Status count is updated after each batch
+	if assert.Len(t, statuses, 3) {
+		assert.Equal(t, "running", statuses[0].Status)
+		assert.Equal(t, 2, statuses[0].RecordsIngested)
+		assert.Equal(t, "running", statuses[1].Status)
+		assert.Equal(t, 4, statuses[1].RecordsIngested)
+		assert.Equal(t, "success", statuses[2].Status)
+		assert.Equal(t, 5, statuses[2].RecordsIngested)
+	}
+}
+
+func TestService_IngestData_SortByID(t *testing.T) {
+	// Create mock server returning posts out of order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{
+			{UserID: 1, ID: 4, Title: "Test Post"},
+			{UserID: 1, ID: 2, Title: "Test Post"},
+			{UserID: 1, ID: 5, Title: "Test Post"},
+			{UserID: 1, ID: 1, Title: "Test Post"},
+			{UserID: 1, ID: 3, Title: "Test Post"},
+		})
+	}))
+	defer server.Close()
+
+	// Create service with mock storage recording the stored order
+	var batches [][]int
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) {
+			var ids []int
+			for _, post := range args.Get(1).([]models.TransformedPost) {
+				ids = append(ids, post.ID)
+			}
+			batches = append(batches, ids)
+		}).
+		Return(nil)
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.AnythingOfType("models.IngestionStatus")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:      server.URL,
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		MaxBatchPerCycle: 2,
+		SortByID:         true,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test posts are stored in ID order with reproducible batch boundaries
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, batches)
+
+	// Test fetch order is kept when sorting is disabled
+	batches = nil
+	service = NewService(config.IngestionConfig{APIEndpoint: server.URL, Timeout: 30 * time.Second, RetryCount: 1}, mockStorage)
+	err = service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{4, 2, 5, 1, 3}}, batches)
+}
+
+func TestNewService_Options(t *testing.T) {
+	testPosts := []models.Post{
+		{UserID: 1, ID: 1, Title: "keep me", Body: "Test body 1"},
+		{UserID: 1, ID: 2, Title: "drop me", Body: "Test body 2"},
+	}
+
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	var stored []models.TransformedPost
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).([]models.TransformedPost) }).
+		Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint: server.URL,
+		Timeout:     30 * time.Second,
+		RetryCount:  1,
+	}
+
+	// Create service with a custom logger, clock and transformers
+	var logs bytes.Buffer
+	fixed := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	service := NewService(cfg, mockStorage,
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithClock(func() time.Time { return fixed }),
+		WithTransformers(
+			func(post *models.TransformedPost) bool { return post.Title != "drop me" },
+			func(post *models.TransformedPost) bool {
+				post.Title = strings.ToUpper(post.Title)
+				return true
+			},
+		),
+	)
+
+	// Test IngestData
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, stored, 1) {
+		assert.Equal(t, "KEEP ME", stored[0].Title)
+		assert.Equal(t, fixed, stored[0].IngestedAt)
+	}
+	assert.Contains(t, logs.String(), "Successfully ingested posts")
+	assert.Contains(t, logs.String(), "count=1")
+}
+
+func TestService_IngestData_ReadOnlyMode(t *testing.T) {
+	testPosts := []models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}}
+	fetches := 0
+
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	// Create mock storage whose writes fail until it recovers
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(errors.New("write throttled")).Times(2)
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.AnythingOfType("models.IngestionStatus")).Return(errors.New("write throttled")).Once()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:       server.URL,
+		Timeout:           30 * time.Second,
+		RetryCount:        1,
+		StoreRetryCount:   1,
+		ReadOnlyThreshold: 2,
+	}
+
+	service := NewService(cfg, mockStorage)
+
+	// Enter read-only mode after two failed stores
+	for i := 0; i < 2; i++ {
+		assert.Error(t, service.IngestData(context.Background()))
+	}
+	assert.True(t, service.ReadOnly())
+
+	// Cycles are skipped while the probe write fails
+	err := service.IngestData(context.Background())
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.Equal(t, 2, fetches)
+	assert.True(t, service.ReadOnly())
+
+	// Writes recover: the probe succeeds and the cycle runs
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.MatchedBy(func(status models.IngestionStatus) bool {
+		return status.Status == "read_only"
+	})).Return(nil).Once()
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil).Once()
+
+	err = service.IngestData(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, service.ReadOnly())
+	assert.Equal(t, 3, fetches)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestService_fetchPostsOnce_JSONPath(t *testing.T) {
+	// Create mock server with the posts deeply nested
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"meta": {"page": 1, "items": [{"id": 99}]},
+			"result": {"data": {"items": [
+				{"userId": 1, "id": 1, "title": "Test Post 1", "body": "Test body 1"},
+				{"userId": 2, "id": 2, "title": "Test Post 2", "body": "Test body 2"}
+			]}}
+		}`))
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"$.result.data.items[*]", "$.result.data.items", "$..data.items[*]"} {
+		t.Run(path, func(t *testing.T) {
+			cfg := config.IngestionConfig{
+				APIEndpoint: server.URL,
+				Timeout:     30 * time.Second,
+				JSONPath:    path,
+			}
+			service := NewService(cfg, nil)
+
+			// Test fetchPostsOnce
+			posts, err := service.fetchPostsOnce(context.Background())
+
+			assert.NoError(t, err)
+			if assert.Len(t, posts, 2) {
+				assert.Equal(t, 1, posts[0].ID)
+				assert.Equal(t, "Test Post 2", posts[1].Title)
+			}
+		})
+	}
+}
+
+func TestService_fetchPostsOnce_InvalidJSONPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result": []}`))
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint: server.URL,
+		Timeout:     30 * time.Second,
+		JSONPath:    "$.result[",
+	}
+	service := NewService(cfg, nil)
+
+	_, err := service.fetchPostsOnce(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid JSONPath")
+}
+
+func TestService_RecentErrors(t *testing.T) {
+	cfg := config.IngestionConfig{ErrorHistorySize: 3}
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	service := NewService(cfg, nil, WithClock(func() time.Time { return now }))
+
+	assert.Empty(t, service.RecentErrors())
+
+	// Test the buffer keeps only the newest errors
+	for i := 1; i <= 5; i++ {
+		now = now.Add(time.Minute)
+		service.recordError(fmt.Errorf("error %d", i))
+	}
+
+	errs := service.RecentErrors()
+	if assert.Len(t, errs, 3) {
+		assert.Equal(t, "error 5", errs[0].Message)
+		assert.Equal(t, "error 4", errs[1].Message)
+		assert.Equal(t, "error 3", errs[2].Message)
+		assert.True(t, errs[0].Time.After(errs[1].Time))
+	}
+}
+
+func TestService_IngestData_DeduplicatesIDs(t *testing.T) {
+	testPosts := []models.Post{
+		{UserID: 1, ID: 1, Title: "First"},
+		{UserID: 1, ID: 2, Title: "Second"},
+		{UserID: 1, ID: 1, Title: "First, revised"},
+		{UserID: 1, ID: 3, Title: "Third"},
+		{UserID: 1, ID: 2, Title: "Second, revised"},
+	}
+
+	// Create mock server returning duplicate IDs
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	var stored []models.TransformedPost
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) { stored = append(stored, args.Get(1).([]models.TransformedPost)...) }).
+		Return(nil)
+	var statuses []models.IngestionStatus
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.AnythingOfType("models.IngestionStatus")).
+		Run(func(args mock.Arguments) { statuses = append(statuses, args.Get(1).(models.IngestionStatus)) }).
+		Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:      server.URL,
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		MaxBatchPerCycle: 2,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test IngestData
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	var titles []string
+	for _, post := range stored {
+		titles = append(titles, post.Title)
+	}
+	assert.Equal(t, []string{"First, revised", "Third", "Second, revised"}, titles)
+
+	if assert.NotEmpty(t, statuses) {
+		final := statuses[len(statuses)-1]
+		assert.Equal(t, "success", final.Status)
+		assert.Equal(t, 3, final.RecordsIngested)
+		assert.Equal(t, 2, final.RecordsDeduplicated)
+	}
+}
+
+func TestService_IngestData_SkippedPosts(t *testing.T) {
+	// Create test server
+	testPosts := []models.Post{
+		{UserID: 1, ID: 1, Title: "First"},
+		{UserID: 1, ID: 2, Title: "Second"},
+		{UserID: 1, ID: 3, Title: "Third"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(testPosts)
+	}))
+	defer server.Close()
+
+	// Create mock storage that couldn't marshal post 2
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Return(&storage.SkippedPostsError{Posts: []storage.SkippedPost{{ID: 2, Field: "category", Err: assert.AnError}}}).Once()
+	var statuses []models.IngestionStatus
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.AnythingOfType("models.IngestionStatus")).
+		Run(func(args mock.Arguments) { statuses = append(statuses, args.Get(1).(models.IngestionStatus)) }).
+		Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:     server.URL,
+		Timeout:         30 * time.Second,
+		RetryCount:      1,
+		StoreRetryCount: 3,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test IngestData succeeds without retrying the store and records the skip
+	err := service.IngestData(context.Background())
+
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, "success", statuses[0].Status)
+		assert.Equal(t, 2, statuses[0].RecordsIngested)
+		assert.Equal(t, 1, statuses[0].RecordsSkipped)
+	}
+}
+
+func TestService_dropSkipped_CompositeKey(t *testing.T) {
+	posts := []models.TransformedPost{
+		{Post: models.Post{ID: 1}, Source: "source_a"},
+		{Post: models.Post{ID: 1}, Source: "source_b"},
+		{Post: models.Post{ID: 2}, Source: "source_a"},
+	}
+	skipped := []storage.SkippedPost{{ID: 1, Source: "source_a", Err: assert.AnError}}
+
+	// Test only the skipped source's post is dropped under composite keys
+	service := NewService(config.IngestionConfig{CompositeKey: true}, new(MockStorage))
+	assert.Equal(t, posts[1:], service.dropSkipped(posts, skipped))
+
+	// Test without them the ID alone identifies the post
+	service = NewService(config.IngestionConfig{}, new(MockStorage))
+	assert.Equal(t, posts[2:], service.dropSkipped(posts, skipped))
+}
+
+func TestService_IngestData_MetricsHistory(t *testing.T) {
+	// Create test server that serves two posts, then fails
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "First"}, {UserID: 1, ID: 2, Title: "Second"}})
+	}))
+	defer server.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+	var snapshots []models.MetricsSnapshot
+	mockStorage.On("RecordMetricsSnapshot", mock.Anything, mock.AnythingOfType("models.MetricsSnapshot")).
+		Run(func(args mock.Arguments) { snapshots = append(snapshots, args.Get(1).(models.MetricsSnapshot)) }).
+		Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:    server.URL,
+		Timeout:        30 * time.Second,
+		RetryCount:     1,
+		MetricsHistory: true,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test every cycle persists a snapshot, failed ones included
+	assert.NoError(t, service.IngestData(context.Background()))
+	fail = true
+	assert.Error(t, service.IngestData(context.Background()))
+
+	if assert.Len(t, snapshots, 2) {
+		assert.Equal(t, primarySource, snapshots[0].Source)
+		assert.Equal(t, 2, snapshots[0].RecordsIn)
+		assert.Equal(t, 2, snapshots[0].RecordsOut)
+		assert.Empty(t, snapshots[0].Error)
+		assert.False(t, snapshots[0].Time.IsZero())
+
+		assert.Zero(t, snapshots[1].RecordsIn)
+		assert.Zero(t, snapshots[1].RecordsOut)
+		assert.Contains(t, snapshots[1].Error, "failed to fetch posts")
+	}
+}
+
+func TestService_IngestData_StampRunID(t *testing.T) {
+	// Create test server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "First"}, {UserID: 1, ID: 2, Title: "Second"}})
+	}))
+	defer server.Close()
+
+	var batches [][]models.TransformedPost
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) { batches = append(batches, args.Get(1).([]models.TransformedPost)) }).
+		Return(nil)
+	mockStorage.On("UpdateIngestionStatus", mock.Anything, mock.AnythingOfType("models.IngestionStatus")).Return(nil)
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:      server.URL,
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		MaxBatchPerCycle: 1,
+		StampRunID:       true,
+	}
+	service := NewService(cfg, mockStorage)
+
+	// Test every post of a run shares its ID, across batches
+	assert.NoError(t, service.IngestData(context.Background()))
+	assert.NoError(t, service.IngestData(context.Background()))
+
+	if assert.Len(t, batches, 4) {
+		first := batches[0][0].RunID
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, first)
+		assert.Equal(t, first, batches[1][0].RunID)
+
+		// Test the next run gets its own ID
+		assert.NotEqual(t, first, batches[2][0].RunID)
+		assert.Equal(t, batches[2][0].RunID, batches[3][0].RunID)
+	}
+}
@@ -0,0 +1,92 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// idRange is an inclusive range of post IDs
+type idRange struct {
+	start, end int
+}
+
+// sharded reports whether fetches are split across ID ranges
+func (s *Service) sharded() bool {
+	return s.config.ShardCount > 1 && s.config.ShardIDEnd >= s.config.ShardIDStart && s.config.ShardIDEnd > 0
+}
+
+// shardRanges splits [start, end] into at most count contiguous ranges whose
+// sizes differ by at most one
+func shardRanges(start, end, count int) []idRange {
+	total := end - start + 1
+	if count > total {
+		count = total
+	}
+
+	size, extra := total/count, total%count
+	ranges := make([]idRange, 0, count)
+	for i := 0; i < count; i++ {
+		n := size
+		if i < extra {
+			n++
+		}
+		ranges = append(ranges, idRange{start: start, end: start + n - 1})
+		start += n
+	}
+	return ranges
+}
+
+// fetchSharded fetches every shard from endpoint in parallel, combining the
+// results in ID range order. The first failing shard cancels the others.
+func (s *Service) fetchSharded(ctx context.Context, endpoint string) ([]models.Post, error) {
+	ranges := shardRanges(s.config.ShardIDStart, s.config.ShardIDEnd, s.config.ShardCount)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]models.Post, len(ranges))
+	var (
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for i, r := range ranges {
+		wg.Add(1)
+		go func(i int, r idRange) {
+			defer wg.Done()
+
+			params := url.Values{}
+			params.Set(s.config.ShardStartParam, strconv.Itoa(r.start))
+			params.Set(s.config.ShardEndParam, strconv.Itoa(r.end))
+
+			posts, err := s.fetchWithRetry(ctx, endpoint, params)
+			if err != nil {
+				// Record the root cause before cancelling the other shards
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to fetch IDs %d-%d: %w", r.start, r.end, err)
+				}
+				errMu.Unlock()
+				cancel()
+				return
+			}
+			results[i] = posts
+		}(i, r)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var posts []models.Post
+	for _, shard := range results {
+		posts = append(posts, shard...)
+	}
+	return posts, nil
+}
@@ -0,0 +1,116 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestShardRanges(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		count      int
+		want       []idRange
+	}{
+		{"even", 1, 100, 4, []idRange{{1, 25}, {26, 50}, {51, 75}, {76, 100}}},
+		{"uneven", 1, 10, 3, []idRange{{1, 4}, {5, 7}, {8, 10}}},
+		{"more shards than IDs", 5, 6, 4, []idRange{{5, 5}, {6, 6}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, shardRanges(tt.start, tt.end, tt.count))
+		})
+	}
+}
+
+func TestService_fetchPosts_Sharded(t *testing.T) {
+	var mu sync.Mutex
+	requested := make(map[int]int)
+
+	// Create mock server serving IDs 1-103 filtered by range
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.Atoi(r.URL.Query().Get("id_gte"))
+		to, _ := strconv.Atoi(r.URL.Query().Get("id_lte"))
+
+		var posts []models.Post
+		mu.Lock()
+		for id := from; id <= to && id <= 103; id++ {
+			requested[id]++
+			posts = append(posts, models.Post{UserID: 1, ID: id, Title: "Test Post"})
+		}
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(posts)
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:     server.URL,
+		Timeout:         30 * time.Second,
+		RetryCount:      1,
+		ShardCount:      4,
+		ShardIDStart:    1,
+		ShardIDEnd:      103,
+		ShardStartParam: "id_gte",
+		ShardEndParam:   "id_lte",
+	}
+	service := NewService(cfg, nil)
+
+	// Test fetchPosts
+	posts, _, err := service.fetchPosts(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, posts, 103) {
+		for i, post := range posts {
+			assert.Equal(t, i+1, post.ID, "posts should be combined in ID order")
+		}
+	}
+	assert.Len(t, requested, 103)
+	for id, count := range requested {
+		assert.Equal(t, 1, count, "ID %d fetched more than once", id)
+	}
+}
+
+func TestService_fetchPosts_ShardFailure(t *testing.T) {
+	// Create mock server failing one shard
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id_gte") == "6" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	cfg := config.IngestionConfig{
+		APIEndpoint:     server.URL,
+		Timeout:         30 * time.Second,
+		RetryCount:      1,
+		ShardCount:      2,
+		ShardIDStart:    1,
+		ShardIDEnd:      10,
+		ShardStartParam: "id_gte",
+		ShardEndParam:   "id_lte",
+	}
+	service := NewService(cfg, nil)
+
+	_, _, err := service.fetchPosts(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "IDs 6-10")
+	assert.Contains(t, err.Error(), "status 500")
+}
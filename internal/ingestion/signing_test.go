@@ -0,0 +1,96 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestService_fetchPostsOnce_SigV4(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+
+	// Create mock server that records the signed request
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Signed"}})
+	}))
+	defer server.Close()
+
+	signedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := config.IngestionConfig{
+		APIEndpoint:  server.URL,
+		Timeout:      30 * time.Second,
+		AuthType:     AuthTypeSigV4,
+		SigV4Region:  "eu-west-1",
+		SigV4Service: "execute-api",
+	}
+	service := NewService(cfg, nil, WithClock(func() time.Time { return signedAt }))
+
+	// Test the request carries a SigV4 Authorization header
+	posts, err := service.fetchPostsOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	auth := received.Get("Authorization")
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 "), auth)
+	assert.Contains(t, auth, "Credential=AKIDEXAMPLE/20240301/eu-west-1/execute-api/aws4_request")
+	assert.Contains(t, auth, "SignedHeaders=host;x-amz-date")
+	assert.Equal(t, "20240301T120000Z", received.Get("X-Amz-Date"))
+}
+
+func TestSigV4Doer_SignsBody(t *testing.T) {
+	creds := credentials.NewStaticCredentials("AKIDEXAMPLE", "secret", "")
+	signedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Create mock server that records the signed request and its body
+	var received *http.Request
+	var receivedBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+	}))
+	defer server.Close()
+
+	doer := newSigV4Doer(http.DefaultClient, creds, "us-east-1", "execute-api", func() time.Time { return signedAt })
+
+	sign := func(body string) string {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/search", strings.NewReader(body))
+		resp, err := doer.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+		return received.Header.Get("Authorization")
+	}
+
+	// Test the body is still delivered after being hashed
+	first := sign(`{"query":"a"}`)
+
+	assert.Equal(t, `{"query":"a"}`, receivedBody)
+
+	// Test the signature matches an independent signing of the same request
+	expected, _ := http.NewRequest(http.MethodPost, server.URL+"/search", nil)
+	_, err := v4.NewSigner(creds).Sign(expected, strings.NewReader(`{"query":"a"}`), "execute-api", "us-east-1", signedAt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Header.Get("Authorization"), first)
+
+	// Test a different body yields a different signature
+	assert.NotEqual(t, first, sign(`{"query":"b"}`))
+}
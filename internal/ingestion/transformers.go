@@ -0,0 +1,48 @@
+package ingestion
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// NormalizeTitle trims the title and collapses internal whitespace, also
+// lowercasing it if requested. The original is kept in OriginalTitle when
+// normalization changes it.
+func NormalizeTitle(lowercase bool) Transformer {
+	return func(post *models.TransformedPost) bool {
+		title := strings.Join(strings.Fields(post.Title), " ")
+		if lowercase {
+			title = strings.ToLower(title)
+		}
+		if title != post.Title {
+			post.OriginalTitle = post.Title
+			post.Title = title
+		}
+		return true
+	}
+}
+
+// Categorize sets the post's category from the first title word found in
+// keywords (matched case-insensitively), or defaultCategory if none is
+func Categorize(keywords map[string]string, defaultCategory string) Transformer {
+	lookup := make(map[string]string, len(keywords))
+	for keyword, category := range keywords {
+		lookup[strings.ToLower(keyword)] = category
+	}
+
+	return func(post *models.TransformedPost) bool {
+		post.Category = defaultCategory
+		for _, word := range strings.Fields(post.Title) {
+			word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			}))
+			if category, ok := lookup[word]; ok {
+				post.Category = category
+				break
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,94 @@
+package ingestion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		name         string
+		title        string
+		lowercase    bool
+		wantTitle    string
+		wantOriginal string
+	}{
+		{"already normal", "Test Post", false, "Test Post", ""},
+		{"trims and collapses", "  Test \t  Post\n", false, "Test Post", "  Test \t  Post\n"},
+		{"lowercases", "Test  Post", true, "test post", "Test  Post"},
+		{"already lowercase", "test post", true, "test post", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &models.TransformedPost{Post: models.Post{Title: tt.title}}
+
+			assert.True(t, NormalizeTitle(tt.lowercase)(post))
+			assert.Equal(t, tt.wantTitle, post.Title)
+			assert.Equal(t, tt.wantOriginal, post.OriginalTitle)
+		})
+	}
+}
+
+func TestService_transformPosts_NormalizeTitles(t *testing.T) {
+	cfg := config.IngestionConfig{NormalizeTitles: true, LowercaseTitles: true}
+	service := NewService(cfg, nil)
+
+	transformed := service.transformPosts([]models.Post{{UserID: 1, ID: 1, Title: " Hello   World "}}, primarySource)
+
+	if assert.Len(t, transformed, 1) {
+		assert.Equal(t, "hello world", transformed[0].Title)
+		assert.Equal(t, " Hello   World ", transformed[0].OriginalTitle)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	categorize := Categorize(map[string]string{
+		"Go":      "programming",
+		"rust":    "programming",
+		"recipe":  "food",
+		"weather": "news",
+	}, "uncategorized")
+
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Writing go services", "programming"},
+		{"A RECIPE for pancakes", "food"},
+		{"Weather: sunny, then a recipe", "news"},
+		{"Nothing relevant here", "uncategorized"},
+		{"Gopher sightings", "uncategorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			post := &models.TransformedPost{Post: models.Post{Title: tt.title}}
+
+			assert.True(t, categorize(post))
+			assert.Equal(t, tt.want, post.Category)
+		})
+	}
+}
+
+func TestService_transformPosts_Categorize(t *testing.T) {
+	cfg := config.IngestionConfig{
+		CategoryKeywords: map[string]string{"go": "programming"},
+		DefaultCategory:  "other",
+	}
+	service := NewService(cfg, nil)
+
+	transformed := service.transformPosts([]models.Post{
+		{UserID: 1, ID: 1, Title: "Learning Go"},
+		{UserID: 1, ID: 2, Title: "Gardening"},
+	}, primarySource)
+
+	if assert.Len(t, transformed, 2) {
+		assert.Equal(t, "programming", transformed[0].Category)
+		assert.Equal(t, "other", transformed[1].Category)
+	}
+}
@@ -0,0 +1,32 @@
+package ingestion
+
+import (
+	"expvar"
+)
+
+// debugVars are lightweight counters for introspection without a metrics
+// stack. They are kept in an unpublished map so each Service has its own.
+type debugVars struct {
+	root        *expvar.Map
+	cycles      *expvar.Int    // Runs attempted, successful or not
+	postsStored *expvar.Int    // Posts stored across all runs and imports
+	lastError   *expvar.String // Error of the most recent failed run
+}
+
+func newDebugVars() *debugVars {
+	v := &debugVars{
+		root:        new(expvar.Map).Init(),
+		cycles:      new(expvar.Int),
+		postsStored: new(expvar.Int),
+		lastError:   new(expvar.String),
+	}
+	v.root.Set("cycles", v.cycles)
+	v.root.Set("posts_stored", v.postsStored)
+	v.root.Set("last_error", v.lastError)
+	return v
+}
+
+// Vars returns the service's debug counters as a single expvar value
+func (s *Service) Vars() expvar.Var {
+	return s.vars.root
+}
@@ -1,27 +1,231 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Post represents the original post structure from the API
 type Post struct {
-	UserID int    `json:"userId"`
-	ID     int    `json:"id"`
-	Title  string `json:"title"`
-	Body   string `json:"body"`
+	UserID    int        `json:"userId"`
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"` // Optional, only if the upstream provides it
+}
+
+// PostLimits caps field lengths in bytes; zero leaves a field unlimited
+type PostLimits struct {
+	MaxTitleLength int
+	MaxBodyLength  int
+}
+
+// FieldLengthError reports a field longer than its limit
+type FieldLengthError struct {
+	Field  string
+	Length int
+	Max    int
+}
+
+func (e *FieldLengthError) Error() string {
+	return fmt.Sprintf("%s is %d bytes, exceeding the maximum of %d", e.Field, e.Length, e.Max)
+}
+
+// Validate reports the first problem that makes the post unfit to store.
+// Overlong fields are reported as a *FieldLengthError.
+func (p Post) Validate(limits PostLimits) error {
+	switch {
+	case p.ID <= 0:
+		return errors.New("id must be positive")
+	case p.UserID <= 0:
+		return errors.New("userId must be positive")
+	case strings.TrimSpace(p.Title) == "":
+		return errors.New("title is required")
+	case limits.MaxTitleLength > 0 && len(p.Title) > limits.MaxTitleLength:
+		return &FieldLengthError{Field: "title", Length: len(p.Title), Max: limits.MaxTitleLength}
+	case limits.MaxBodyLength > 0 && len(p.Body) > limits.MaxBodyLength:
+		return &FieldLengthError{Field: "body", Length: len(p.Body), Max: limits.MaxBodyLength}
+	}
+	return nil
 }
 
 // TransformedPost represents the post after transformation
 type TransformedPost struct {
-	Post       `json:",inline"`
+	// Untagged, so both encoding/json and dynamodbattribute flatten its fields
+	Post
+	IngestedAt    time.Time  `json:"ingested_at"`
+	Source        string     `json:"source"`
+	OriginalTitle string     `json:"original_title,omitempty"` // Title as received, when normalization changed it
+	Category      string     `json:"category,omitempty"`
+	BodyRef       string     `json:"body_ref,omitempty"`      // S3 location of an offloaded body
+	BodyEncoding  string     `json:"body_encoding,omitempty"` // Set when Body is stored compressed
+	Version       int        `json:"version,omitempty"`       // Incremented on each store when versioning is enabled
+	RunID         string     `json:"run_id,omitempty"`        // Ingestion run that stored the post, when STAMP_RUN_ID is set
+	Deleted       bool       `json:"deleted,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+
+	// User holds the selected fields of the author's record from the user
+	// lookup API, when enrichment is enabled
+	User map[string]any `json:"user,omitempty"`
+}
+
+// Flatten returns the post as a flat column map for storage backends with a
+// fixed column layout, independent of the JSON shape. Unset optional times are nil.
+func (p TransformedPost) Flatten() map[string]any {
+	return map[string]any{
+		"user_id":        p.UserID,
+		"id":             p.ID,
+		"title":          p.Title,
+		"body":           p.Body,
+		"created_at":     optionalTime(p.CreatedAt),
+		"ingested_at":    p.IngestedAt,
+		"source":         p.Source,
+		"original_title": p.OriginalTitle,
+		"category":       p.Category,
+		"body_ref":       p.BodyRef,
+		"body_encoding":  p.BodyEncoding,
+		"version":        p.Version,
+		"run_id":         p.RunID,
+		"deleted":        p.Deleted,
+		"deleted_at":     optionalTime(p.DeletedAt),
+		"user":           p.User,
+	}
+}
+
+func optionalTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// Comment is a comment on a post, fetched from the post's comments resource
+type Comment struct {
+	PostID     int       `json:"postId"`
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Body       string    `json:"body"`
 	IngestedAt time.Time `json:"ingested_at"`
-	Source     string    `json:"source"`
 }
 
 // IngestionStatus tracks the status of ingestion runs
 type IngestionStatus struct {
 	LastSuccessfulRun time.Time `json:"last_successful_run"`
 	LastAttempt       time.Time `json:"last_attempt"`
-	Status            string    `json:"status"` // "success", "failure", "running"
+	Status            string    `json:"status"` // "success", "failure", "running", "degraded", "read_only", "timed_out", "shedding_load"
 	ErrorMessage      string    `json:"error_message,omitempty"`
 	RecordsIngested   int       `json:"records_ingested"`
-}
\ No newline at end of file
+
+	// RecordsDeduplicated counts duplicate IDs dropped from the run's fetch
+	RecordsDeduplicated int `json:"records_deduplicated,omitempty"`
+
+	// RecordsSkipped counts posts storage couldn't marshal and left out of the run
+	RecordsSkipped int `json:"records_skipped,omitempty"`
+
+	// RateLimit is the upstream's latest reported budget; filled in by the
+	// API, not stored
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+
+	// Endpoints is the health of each upstream mirror; filled in by the
+	// API, not stored
+	Endpoints []EndpointHealth `json:"endpoints,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding unset timestamps as null
+// rather than 0001-01-01T00:00:00Z so clients can tell a run never happened
+func (s IngestionStatus) MarshalJSON() ([]byte, error) {
+	type status IngestionStatus // Without the method, to avoid recursion
+	return json.Marshal(struct {
+		status
+		LastSuccessfulRun *time.Time `json:"last_successful_run"`
+		LastAttempt       *time.Time `json:"last_attempt"`
+	}{status(s), setTime(s.LastSuccessfulRun), setTime(s.LastAttempt)})
+}
+
+// setTime returns t, or nil if it's the zero time
+func setTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// MetricsSnapshot is a compact record of one ingestion cycle, kept for
+// historical analysis without a metrics backend
+type MetricsSnapshot struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source,omitempty"`
+	RecordsIn  int       `json:"records_in"`  // Posts fetched from the upstream
+	RecordsOut int       `json:"records_out"` // Posts stored
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// EndpointHealth is an upstream mirror's recent fetch record
+type EndpointHealth struct {
+	URL                 string     `json:"url"`
+	Healthy             bool       `json:"healthy"` // The last fetch succeeded
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	DemotedUntil        *time.Time `json:"demoted_until,omitempty"` // Tried only after healthy mirrors until then
+}
+
+// UpstreamProbe is the result of probing one upstream endpoint on demand
+type UpstreamProbe struct {
+	Source    string `json:"source"`
+	URL       string `json:"url"`
+	Reachable bool   `json:"reachable"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FetchRecord describes the most recent upstream response, for debugging
+type FetchRecord struct {
+	Time    time.Time           `json:"time"`
+	URL     string              `json:"url"` // Without the query, which may carry credentials
+	Status  int                 `json:"status"`
+	Headers map[string][]string `json:"headers"`
+}
+
+// RateLimit is the rate-limit budget the upstream reported on its latest response
+type RateLimit struct {
+	Remaining  int       `json:"remaining"`
+	Limit      int       `json:"limit,omitempty"` // 0 when the upstream doesn't report it
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+// IngestionError records a failed ingestion run
+type IngestionError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// AuditRecord records a write operation for compliance
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"` // "ingestion", or the API key that triggered the write
+	RequestID string    `json:"request_id,omitempty"`
+	Operation string    `json:"operation"` // "store_posts", "store_comments", "delete_post", "delete_posts"
+	IDs       []int     `json:"ids"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ReconcileReport summarizes a comparison of stored posts against the upstream
+type ReconcileReport struct {
+	RunAt      time.Time           `json:"run_at"`
+	Sampled    int                 `json:"sampled"`
+	Mismatches []ReconcileMismatch `json:"mismatches"`
+	Reingested int                 `json:"reingested"`
+}
+
+// ReconcileMismatch identifies a stored post that differs from the upstream
+type ReconcileMismatch struct {
+	ID     int      `json:"id"`
+	Fields []string `json:"fields"` // Differing fields, or "missing_upstream"
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformedPost_Flatten(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ingested := created.Add(time.Hour)
+	post := TransformedPost{
+		Post:          Post{UserID: 1, ID: 2, Title: "TITLE", Body: "body", CreatedAt: &created},
+		IngestedAt:    ingested,
+		Source:        "placeholder_api",
+		OriginalTitle: "title",
+		Category:      "news",
+		BodyRef:       "s3://bucket/2",
+		BodyEncoding:  "gzip",
+		Version:       3,
+		RunID:         "run-1",
+		User:          map[string]any{"name": "Leanne Graham"},
+	}
+
+	flat := post.Flatten()
+
+	assert.Equal(t, map[string]any{
+		"user_id":        1,
+		"id":             2,
+		"title":          "TITLE",
+		"body":           "body",
+		"created_at":     created,
+		"ingested_at":    ingested,
+		"source":         "placeholder_api",
+		"original_title": "title",
+		"category":       "news",
+		"body_ref":       "s3://bucket/2",
+		"body_encoding":  "gzip",
+		"version":        3,
+		"run_id":         "run-1",
+		"deleted":        false,
+		"deleted_at":     nil,
+		"user":           map[string]any{"name": "Leanne Graham"},
+	}, flat)
+}
+
+func TestTransformedPost_Flatten_Deleted(t *testing.T) {
+	deletedAt := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	post := TransformedPost{Post: Post{ID: 1}, Deleted: true, DeletedAt: &deletedAt}
+
+	flat := post.Flatten()
+
+	assert.Nil(t, flat["created_at"])
+	assert.Equal(t, true, flat["deleted"])
+	assert.Equal(t, deletedAt, flat["deleted_at"])
+}
+
+func TestPost_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		post Post
+		err  string
+	}{
+		{"valid", Post{UserID: 1, ID: 1, Title: "title"}, ""},
+		{"missing id", Post{UserID: 1, Title: "title"}, "id must be positive"},
+		{"negative user", Post{UserID: -1, ID: 1, Title: "title"}, "userId must be positive"},
+		{"blank title", Post{UserID: 1, ID: 1, Title: " "}, "title is required"},
+		{"overlong title", Post{UserID: 1, ID: 1, Title: "a long title"}, "title is 12 bytes, exceeding the maximum of 10"},
+		{"overlong body", Post{UserID: 1, ID: 1, Title: "title", Body: "a body over the limit"}, "body is 21 bytes, exceeding the maximum of 20"},
+	}
+
+	limits := PostLimits{MaxTitleLength: 10, MaxBodyLength: 20}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.post.Validate(limits)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestIngestionStatus_MarshalJSON(t *testing.T) {
+	// Test unset timestamps encode as null and set ones as usual
+	attempt := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(IngestionStatus{LastAttempt: attempt, Status: "failure", ErrorMessage: "timeout"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"last_successful_run":null,"last_attempt":"2026-10-14T12:00:00Z",`+
+		`"status":"failure","error_message":"timeout","records_ingested":0}`, string(data))
+
+	// Test null decodes back to the zero time
+	var status IngestionStatus
+	assert.NoError(t, json.Unmarshal(data, &status))
+	assert.True(t, status.LastSuccessfulRun.IsZero())
+	assert.Equal(t, attempt, status.LastAttempt)
+}
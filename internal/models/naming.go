@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// FieldNaming is the key convention posts are serialized with
+type FieldNaming string
+
+const (
+	// NamingAsTagged keeps each field's own key, so the upstream's userId
+	// sits alongside ingested_at
+	NamingAsTagged  FieldNaming = ""
+	NamingCamelCase FieldNaming = "camelCase"
+	NamingSnakeCase FieldNaming = "snake_case"
+)
+
+// ParseFieldNaming returns the named convention, or NamingAsTagged for ""
+func ParseFieldNaming(name string) (FieldNaming, error) {
+	switch naming := FieldNaming(name); naming {
+	case NamingAsTagged, NamingCamelCase, NamingSnakeCase:
+		return naming, nil
+	}
+	return NamingAsTagged, fmt.Errorf("unsupported field naming: %s", name)
+}
+
+// Key converts a JSON key to the convention
+func (n FieldNaming) Key(key string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range key {
+		switch {
+		case n == NamingSnakeCase && unicode.IsUpper(r):
+			b.WriteByte('_')
+			b.WriteRune(unicode.ToLower(r))
+		case n == NamingCamelCase && r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// NamedPost serializes a post with every key in Naming's convention,
+// keeping the fields and omissions of TransformedPost's own encoding
+type NamedPost struct {
+	TransformedPost
+	Naming FieldNaming
+}
+
+// MarshalJSON implements json.Marshaler
+func (p NamedPost) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(p.TransformedPost)
+	if err != nil || p.Naming == NamingAsTagged {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	named := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		named[p.Naming.Key(key)] = value
+	}
+	return json.Marshal(named)
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fullPost returns a post with every field set, so none is omitted
+func fullPost() TransformedPost {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return TransformedPost{
+		Post:          Post{UserID: 1, ID: 2, Title: "TITLE", Body: "body", CreatedAt: &created},
+		IngestedAt:    created.Add(time.Hour),
+		Source:        "placeholder_api",
+		OriginalTitle: "title",
+		Category:      "news",
+		BodyRef:       "s3://bucket/2",
+		BodyEncoding:  "gzip",
+		Version:       3,
+		RunID:         "run-1",
+		Deleted:       true,
+		DeletedAt:     &created,
+		User:          map[string]any{"name": "Leanne Graham"},
+	}
+}
+
+func encodedKeys(t *testing.T, v any) []string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestNamedPost_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		naming FieldNaming
+		keys   []string
+	}{
+		{NamingSnakeCase, []string{"user_id", "id", "title", "body", "created_at", "ingested_at", "source",
+			"original_title", "category", "body_ref", "body_encoding", "version", "run_id", "deleted", "deleted_at", "user"}},
+		{NamingCamelCase, []string{"userId", "id", "title", "body", "createdAt", "ingestedAt", "source",
+			"originalTitle", "category", "bodyRef", "bodyEncoding", "version", "runId", "deleted", "deletedAt", "user"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.naming), func(t *testing.T) {
+			// Test every field's key follows the convention
+			named := NamedPost{TransformedPost: fullPost(), Naming: tt.naming}
+			assert.ElementsMatch(t, tt.keys, encodedKeys(t, named))
+
+			// Test the values are unchanged
+			data, err := json.Marshal(named)
+			require.NoError(t, err)
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal(data, &fields))
+			assert.Equal(t, "TITLE", fields["title"])
+			assert.Equal(t, float64(1), fields[tt.naming.Key("userId")])
+			assert.Equal(t, "2024-01-02T04:04:05Z", fields[tt.naming.Key("ingested_at")])
+		})
+	}
+}
+
+func TestNamedPost_MarshalJSON_AsTagged(t *testing.T) {
+	post := TransformedPost{Post: Post{UserID: 1, ID: 2, Title: "Title"}, Source: "placeholder_api"}
+
+	// Test the default naming matches the post's own encoding, omissions included
+	expected, err := json.Marshal(post)
+	require.NoError(t, err)
+	actual, err := json.Marshal(NamedPost{TransformedPost: post})
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// Test omitted fields stay omitted under a convention
+	assert.NotContains(t, encodedKeys(t, NamedPost{TransformedPost: post, Naming: NamingSnakeCase}), "deleted_at")
+}
+
+func TestParseFieldNaming(t *testing.T) {
+	for _, name := range []string{"", "camelCase", "snake_case"} {
+		naming, err := ParseFieldNaming(name)
+		assert.NoError(t, err)
+		assert.Equal(t, FieldNaming(name), naming)
+	}
+
+	naming, err := ParseFieldNaming("kebab-case")
+	assert.Error(t, err)
+	assert.Equal(t, NamingAsTagged, naming)
+}
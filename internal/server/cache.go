@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/dedup"
+)
+
+// responseCache holds recent GET responses for a fixed TTL, filed under the
+// hash of their request key
+type responseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	hasher     *dedup.Hasher
+	entries    map[uint64]cacheEntry
+	now        func() time.Time
+
+	// generation counts invalidations, so a response read before one isn't
+	// stored after it
+	generation uint64
+}
+
+type cacheEntry struct {
+	key         string // Told apart from another key with the same hash
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int, hasher *dedup.Hasher) *responseCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		hasher:     hasher,
+		entries:    make(map[uint64]cacheEntry),
+		now:        time.Now,
+	}
+}
+
+func (c *responseCache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := c.hasher.Sum64([]byte(key))
+	entry, ok := c.entries[hash]
+	if !ok || entry.key != key {
+		return cacheEntry{}, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, hash)
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// currentGeneration returns the generation to pass to put for a response
+// about to be read
+func (c *responseCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// put stores entry unless the cache was invalidated since generation
+func (c *responseCache) put(key string, generation uint64, entry cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return // Read before an invalidation, so possibly stale
+	}
+
+	hash := c.hasher.Sum64([]byte(key))
+	if _, exists := c.entries[hash]; !exists && len(c.entries) >= c.maxEntries {
+		c.evictLocked()
+	}
+	entry.key = key
+	entry.expires = c.now().Add(c.ttl)
+	c.entries[hash] = entry
+}
+
+// evictLocked drops expired entries, or the one expiring soonest if none have
+func (c *responseCache) evictLocked() {
+	now := c.now()
+	var oldestKey uint64
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest.IsZero() || entry.expires.Before(oldest) {
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// invalidate drops every entry
+func (c *responseCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[uint64]cacheEntry)
+	c.generation++
+}
+
+// cacheRecorder passes a response through while keeping a copy of the body.
+// Only successful responses are marked cacheable for clients.
+type cacheRecorder struct {
+	http.ResponseWriter
+	maxAge time.Duration
+	status int
+	body   bytes.Buffer
+}
+
+func (r *cacheRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		if status == http.StatusOK {
+			r.Header().Set("Cache-Control", cacheControl(r.maxAge))
+			r.Header().Set("X-Cache", "MISS")
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *cacheRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// cached serves repeated GET requests from the response cache. Streaming
+// responses and errors are never cached.
+func (s *Server) cached(next http.HandlerFunc) http.HandlerFunc {
+	if s.cache == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Method != http.MethodGet || query.Get("format") == "ndjson" {
+			next(w, r)
+			return
+		}
+
+		// Encode sorts parameters, so equivalent queries share an entry
+		key := r.URL.Path + "?" + query.Encode()
+		if entry, ok := s.cache.get(key); ok {
+			w.Header().Set("Content-Type", entry.contentType)
+			w.Header().Set("Cache-Control", cacheControl(entry.expires.Sub(s.cache.now())))
+			w.Header().Set("X-Cache", "HIT")
+			w.Write(entry.body)
+			return
+		}
+
+		generation := s.cache.currentGeneration()
+		rec := &cacheRecorder{ResponseWriter: w, maxAge: s.cache.ttl}
+		next(rec, r)
+
+		if rec.status == http.StatusOK {
+			s.cache.put(key, generation, cacheEntry{
+				contentType: w.Header().Get("Content-Type"),
+				body:        rec.body.Bytes(),
+			})
+		}
+	}
+}
+
+func cacheControl(maxAge time.Duration) string {
+	return fmt.Sprintf("max-age=%d", int(maxAge.Seconds()))
+}
+
+// InvalidateCache drops cached responses, e.g. after new posts are ingested
+func (s *Server) InvalidateCache() {
+	if s.cache != nil {
+		s.cache.invalidate()
+	}
+}
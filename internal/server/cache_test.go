@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/dedup"
+	"github.com/cyderes/data-ingestion-service/internal/ingestion"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestServer_PostsCache_Hit(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 5, 0).Return(makePosts(1, 5), nil).Once()
+
+	s := NewServer(config.ServerConfig{CacheTTL: time.Minute, CacheMaxEntries: 10}, mockStorage)
+
+	// First request misses and reads storage
+	req := httptest.NewRequest(http.MethodGet, "/posts?limit=5&offset=0", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+	assert.Equal(t, "max-age=60", rec.Header().Get("Cache-Control"))
+	first := rec.Body.String()
+
+	// Same query, parameters reordered, is served from the cache
+	req = httptest.NewRequest(http.MethodGet, "/posts?offset=0&limit=5", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HIT", rec.Header().Get("X-Cache"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, first, rec.Body.String())
+	mockStorage.AssertNumberOfCalls(t, "GetPosts", 1)
+}
+
+func TestServer_PostsCache_Expiry(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 1), nil).Twice()
+
+	s := NewServer(config.ServerConfig{CacheTTL: time.Minute, CacheMaxEntries: 10}, mockStorage)
+	now := time.Now()
+	s.cache.now = func() time.Time { return now }
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Test the entry expires after the TTL
+	now = now.Add(2 * time.Minute)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_PostsCache_InvalidatedByIngestion(t *testing.T) {
+	// Create mock upstream
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"userId": 1, "id": 1, "title": "Test Post 1"}]`))
+	}))
+	defer upstream.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 1), nil).Twice()
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	var s *Server
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoint: upstream.URL,
+		Timeout:     30 * time.Second,
+		RetryCount:  1,
+	}, mockStorage, ingestion.WithAfterIngest(func(int) { s.InvalidateCache() }))
+	s = NewServer(config.ServerConfig{CacheTTL: time.Minute, CacheMaxEntries: 10}, mockStorage)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Test a cycle storing posts clears the cache
+	assert.NoError(t, ingestor.IngestData(context.Background()))
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_PostsCache_InvalidatedDuringRead(t *testing.T) {
+	var s *Server
+	mockStorage := new(MockStorage)
+	// Ingestion finishes while the first request is reading storage
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Run(func(mock.Arguments) {
+		s.InvalidateCache()
+	}).Return(makePosts(1, 1), nil).Once()
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 2), nil).Once()
+	s = NewServer(config.ServerConfig{CacheTTL: time.Minute, CacheMaxEntries: 10}, mockStorage)
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	s.server.Handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Test the response read before the invalidation wasn't cached
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "MISS", rec.Header().Get("X-Cache"))
+	assert.Contains(t, rec.Body.String(), `"id":2`)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_PostsCache_SkipsErrors(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPostByID", mock.Anything, 1).Return((*models.TransformedPost)(nil), nil).Twice()
+
+	s := NewServer(config.ServerConfig{CacheTTL: time.Minute, CacheMaxEntries: 10}, mockStorage)
+
+	// Test a 404 is neither cached nor marked cacheable
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Empty(t, rec.Header().Get("Cache-Control"))
+	}
+	mockStorage.AssertExpectations(t)
+}
+
+func TestResponseCache_Eviction(t *testing.T) {
+	cache := newResponseCache(time.Minute, 2, dedup.DefaultHasher())
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", 0, cacheEntry{body: []byte("a")})
+	now = now.Add(time.Second)
+	cache.put("b", 0, cacheEntry{body: []byte("b")})
+	now = now.Add(time.Second)
+	cache.put("c", 0, cacheEntry{body: []byte("c")})
+
+	// Test the oldest entry was evicted
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+	_, ok = cache.get("b")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+}
+
+func TestServer_PostsCache_HashAlgorithm(t *testing.T) {
+	for _, algorithm := range []string{dedup.HashFNV, dedup.HashSHA256, dedup.HashXXHash} {
+		mockStorage := new(MockStorage)
+		mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 1), nil).Once()
+		mockStorage.On("GetPosts", mock.Anything, 5, 0).Return(makePosts(1, 1), nil).Once()
+
+		s := NewServer(config.ServerConfig{CacheTTL: time.Minute, CacheMaxEntries: 10}, mockStorage, WithHashAlgorithm(algorithm))
+		assert.Equal(t, algorithm, s.cache.hasher.Name())
+
+		// Test entries are found by key under each algorithm
+		for _, target := range []string{"/posts", "/posts?limit=5", "/posts", "/posts?limit=5"} {
+			s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+		}
+		mockStorage.AssertExpectations(t)
+	}
+
+	// Test an unknown algorithm falls back to the default
+	s := NewServer(config.ServerConfig{CacheTTL: time.Minute}, new(MockStorage), WithHashAlgorithm("md5"))
+	assert.Equal(t, dedup.DefaultHasher().Name(), s.cache.hasher.Name())
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// readPosts runs read with the request's storage context. With
+// CoalesceReads, concurrent requests for the same key share a single read.
+func (s *Server) readPosts(r *http.Request, key string, read func(ctx context.Context) ([]models.TransformedPost, error)) ([]models.TransformedPost, error) {
+	ctx := readContext(r)
+	if s.reads == nil {
+		return read(ctx)
+	}
+
+	// readContext's options change the result, so they are part of the key
+	query := r.URL.Query()
+	key += "&includeDeleted=" + query.Get("includeDeleted") + "&source=" + query.Get("source")
+
+	// The read is shared, so one client going away mustn't fail it for the rest
+	v, err, _ := s.reads.Do(key, func() (interface{}, error) {
+		return read(context.WithoutCancel(ctx))
+	})
+	posts, _ := v.([]models.TransformedPost)
+	return posts, err
+}
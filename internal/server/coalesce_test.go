@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+)
+
+func TestServer_CoalesceReads(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// The read blocks until every request has had time to join it
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 5, 0).Return(makePosts(1, 5), nil).Run(func(mock.Arguments) {
+		close(started)
+		<-release
+	}).Once()
+
+	s := NewServer(config.ServerConfig{CoalesceReads: true}, mockStorage)
+
+	const requests = 20
+	codes := make([]int, requests)
+	counts := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Equivalent spellings of the same query share the read
+			target := "/posts?limit=5"
+			if i%2 == 1 {
+				target = "/posts?offset=0&limit=5"
+			}
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			var response struct {
+				Count int `json:"count"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+			codes[i], counts[i] = rec.Code, response.Count
+		}(i)
+	}
+
+	<-started
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < requests; i++ {
+		assert.Equal(t, http.StatusOK, codes[i])
+		assert.Equal(t, 5, counts[i])
+	}
+	mockStorage.AssertNumberOfCalls(t, "GetPosts", 1)
+
+	// Once the read is done, the next request reads storage again
+	mockStorage.On("GetPosts", mock.Anything, 5, 0).Return(makePosts(1, 5), nil).Once()
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts?limit=5", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockStorage.AssertNumberOfCalls(t, "GetPosts", 2)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// handleDebugVars serves the process-wide expvar variables, such as
+// memstats, plus the ingestor's counters under "ingestion", in the same
+// format as expvar.Handler
+func (s *Server) handleDebugVars(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprint(w, "{\n")
+	first := true
+	write := func(kv expvar.KeyValue) {
+		if !first {
+			fmt.Fprint(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	}
+
+	expvar.Do(write)
+	if reporter, ok := s.ingestor.(varsReporter); ok {
+		write(expvar.KeyValue{Key: "ingestion", Value: reporter.Vars()})
+	}
+	fmt.Fprint(w, "\n}\n")
+}
+
+// handleLastFetch handles GET requests for the latest upstream response's
+// status and headers
+func (s *Server) handleLastFetch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reporter, ok := s.ingestor.(lastFetchReporter)
+	if !ok {
+		http.Error(w, "Fetch recording is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	record := reporter.LastFetch()
+	if record == nil {
+		http.Error(w, "No fetch has completed yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, r, record)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/ingestion"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// debugVars fetches /debug/vars and decodes the ingestion counters
+func debugVars(t *testing.T, s *Server) map[string]any {
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var vars map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	assert.Contains(t, vars, "memstats")
+
+	var ingestionVars map[string]any
+	assert.NoError(t, json.Unmarshal(vars["ingestion"], &ingestionVars))
+	return ingestionVars
+}
+
+func TestServer_handleDebugVars(t *testing.T) {
+	// Create mock upstream serving two posts
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(makePosts(1, 2))
+	}))
+	defer upstream.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoint: upstream.URL,
+		Timeout:     30 * time.Second,
+		RetryCount:  1,
+	}, mockStorage)
+	s := NewServer(config.ServerConfig{DebugVars: true}, mockStorage, WithIngestor(ingestor))
+
+	// Test the counters are published
+	vars := debugVars(t, s)
+	assert.Equal(t, map[string]any{"cycles": 0.0, "posts_stored": 0.0, "last_error": ""}, vars)
+
+	// Test they update after a cycle
+	assert.NoError(t, ingestor.IngestData(context.Background()))
+
+	vars = debugVars(t, s)
+	assert.Equal(t, 1.0, vars["cycles"])
+	assert.Equal(t, 2.0, vars["posts_stored"])
+}
+
+func TestServer_handleDebugVars_Disabled(t *testing.T) {
+	s := NewServer(config.ServerConfig{}, new(MockStorage))
+
+	// Without DebugVars, /debug/vars falls through to no handler
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_handleLastFetch(t *testing.T) {
+	// Create mock upstream whose headers change between fetches
+	var etag atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, etag.Add(1)))
+		w.Header().Set("Set-Cookie", "session=secret")
+		json.NewEncoder(w).Encode(makePosts(1, 1))
+	}))
+	defer upstream.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoint:   upstream.URL,
+		Timeout:       30 * time.Second,
+		RetryCount:    1,
+		QueryParams:   map[string]string{"token": "secret"},
+		RedactHeaders: []string{"set-cookie"},
+	}, mockStorage)
+	s := NewServer(config.ServerConfig{DebugLastFetch: true}, mockStorage, WithIngestor(ingestor))
+
+	// Test nothing is reported before the first fetch
+	req := httptest.NewRequest(http.MethodGet, "/debug/last-fetch", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Test the endpoint reflects the most recent fetch
+	assert.NoError(t, ingestor.IngestData(context.Background()))
+	assert.NoError(t, ingestor.IngestData(context.Background()))
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/last-fetch", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var record models.FetchRecord
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &record))
+	assert.Equal(t, http.StatusOK, record.Status)
+	assert.Equal(t, upstream.URL, record.URL, "the query may carry credentials")
+	assert.Equal(t, []string{`"v2"`}, record.Headers["Etag"])
+	assert.Equal(t, []string{"application/json"}, record.Headers["Content-Type"])
+	assert.Equal(t, []string{"[REDACTED]"}, record.Headers["Set-Cookie"])
+	assert.NotContains(t, rec.Body.String(), "secret")
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+)
+
+func TestAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	s := NewServer(config.ServerConfig{AccessLogLevel: slog.LevelDebug}, new(MockStorage), WithLogger(logger))
+
+	// Test a request that fails validation
+	req := httptest.NewRequest(http.MethodGet, "/posts/abc", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, logs.String(), "level=DEBUG")
+	assert.Contains(t, logs.String(), "method=GET")
+	assert.Contains(t, logs.String(), "path=/posts/abc")
+	assert.Contains(t, logs.String(), "status=400")
+	assert.Contains(t, logs.String(), "duration=")
+}
+
+func TestAccessLog_BelowLoggerLevel(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	s := NewServer(config.ServerConfig{AccessLogLevel: slog.LevelDebug}, new(MockStorage), WithLogger(logger))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, logs.String())
+}
+
+func TestConnMaxAge(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		wantClose bool
+	}{
+		{"young connection kept alive", time.Hour, false},
+		{"old connection closed", 50 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(config.ServerConfig{ConnMaxAge: tt.maxAge}, new(MockStorage))
+
+			// Serve through a real listener so connections are reused
+			ts := httptest.NewUnstartedServer(s.server.Handler)
+			ts.Config.ConnContext = s.server.ConnContext
+			ts.Start()
+			defer ts.Close()
+
+			client := ts.Client()
+			resp, err := client.Get(ts.URL + "/health")
+			if !assert.NoError(t, err) {
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			assert.False(t, resp.Close, "first request on a fresh connection")
+
+			time.Sleep(100 * time.Millisecond)
+
+			resp, err = client.Get(ts.URL + "/health")
+			if !assert.NoError(t, err) {
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tt.wantClose, resp.Close)
+		})
+	}
+}
@@ -2,46 +2,259 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/singleflight"
+
 	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/dedup"
+	"github.com/cyderes/data-ingestion-service/internal/ingestion"
+	"github.com/cyderes/data-ingestion-service/internal/models"
 	"github.com/cyderes/data-ingestion-service/internal/storage"
 )
 
+const (
+	// defaultPageLimit is the GET /posts limit when neither the request nor
+	// DefaultLimit sets one
+	defaultPageLimit = 10
+
+	// maxDeleteBatch caps the number of IDs a single delete request may target
+	maxDeleteBatch = 1000
+
+	// maxImportBatch caps the number of posts a single import request may carry
+	maxImportBatch = 1000
+)
+
+// Ingestor runs an ingestion cycle on demand
+type Ingestor interface {
+	IngestData(ctx context.Context) error
+}
+
+// errorHistory is implemented by ingestors that keep their recent errors
+type errorHistory interface {
+	RecentErrors() []models.IngestionError
+}
+
+// reconcileReporter is implemented by ingestors that reconcile stored posts
+type reconcileReporter interface {
+	LastReconcileReport() *models.ReconcileReport
+}
+
+// readOnlyReporter is implemented by ingestors that pause while storage
+// writes are failing
+type readOnlyReporter interface {
+	ReadOnly() bool
+}
+
+// endpointHealthReporter is implemented by ingestors that track the health
+// of their upstream mirrors
+type endpointHealthReporter interface {
+	EndpointHealth() []models.EndpointHealth
+}
+
+// upstreamProber is implemented by ingestors that can check their upstreams
+// are reachable on demand
+type upstreamProber interface {
+	ProbeUpstreams(ctx context.Context) []models.UpstreamProbe
+}
+
+// loadShedReporter is implemented by ingestors that skip cycles under
+// memory pressure
+type loadShedReporter interface {
+	SheddingLoad() bool
+}
+
+// lastFetchReporter is implemented by ingestors that record their latest
+// upstream response
+type lastFetchReporter interface {
+	LastFetch() *models.FetchRecord
+}
+
+// successReporter is implemented by ingestors that track when their last
+// cycle succeeded
+type successReporter interface {
+	LastSuccess() time.Time
+}
+
+// varsReporter is implemented by ingestors that publish expvar counters
+type varsReporter interface {
+	Vars() expvar.Var
+}
+
+// importer is implemented by ingestors that can store posts supplied directly
+type importer interface {
+	ImportPosts(ctx context.Context, posts []models.Post) (int, error)
+}
+
+// rateLimitReporter is implemented by ingestors that track the upstream's
+// rate-limit budget
+type rateLimitReporter interface {
+	RateLimit() *models.RateLimit
+}
+
 // Server handles HTTP requests
 type Server struct {
-	config  config.ServerConfig
-	storage storage.Storage
-	server  *http.Server
+	config     config.ServerConfig
+	storage    storage.Storage
+	ingestor   Ingestor
+	logger     *slog.Logger
+	metrics    *prometheus.Registry
+	middleware []Middleware
+	categories []string       // Valid values of the /posts category filter
+	cache      *responseCache // nil when response caching is disabled
+	started    time.Time      // Start of the health check's startup grace period
+	server     *http.Server
+
+	naming models.FieldNaming // Key convention of posts in responses
+
+	reads *singleflight.Group // nil when read coalescing is disabled
+
+	hashAlgorithm string // Hashes response cache keys
+}
+
+// Middleware wraps the server's handler
+type Middleware func(http.Handler) http.Handler
+
+// ServerOption customizes a Server
+type ServerOption func(*Server)
+
+// WithLogger sets the logger used for request handling errors
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// WithMetrics exposes the registry's metrics on /metrics
+func WithMetrics(registry *prometheus.Registry) ServerOption {
+	return func(s *Server) {
+		s.metrics = registry
+	}
+}
+
+// WithIngestor enables the /ingest endpoint
+func WithIngestor(ingestor Ingestor) ServerOption {
+	return func(s *Server) {
+		s.ingestor = ingestor
+	}
+}
+
+// WithCategories sets the categories accepted by the /posts category filter
+func WithCategories(categories []string) ServerOption {
+	return func(s *Server) {
+		s.categories = categories
+	}
+}
+
+// WithHashAlgorithm sets the algorithm hashing response cache keys, so the
+// cache agrees with the content hashing configured for dedup
+func WithHashAlgorithm(algorithm string) ServerOption {
+	return func(s *Server) {
+		s.hashAlgorithm = algorithm
+	}
+}
+
+// WithMiddleware wraps every request in the given middleware. The first
+// middleware given is the outermost.
+func WithMiddleware(middleware ...Middleware) ServerOption {
+	return func(s *Server) {
+		s.middleware = append(s.middleware, middleware...)
+	}
 }
 
 // NewServer creates a new HTTP server
-func NewServer(cfg config.ServerConfig, store storage.Storage) *Server {
+func NewServer(cfg config.ServerConfig, store storage.Storage, opts ...ServerOption) *Server {
 	s := &Server{
 		config:  cfg,
 		storage: store,
+		logger:  slog.Default(),
+		started: time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	naming, err := models.ParseFieldNaming(cfg.FieldNaming)
+	if err != nil {
+		s.logger.Warn("Falling back to each field's own JSON key", "error", err)
+	}
+	s.naming = naming
+
+	if cfg.CacheTTL > 0 {
+		hasher, err := dedup.NewHasher(s.hashAlgorithm)
+		if err != nil {
+			s.logger.Warn("Falling back to the default hash algorithm", "error", err)
+			hasher = dedup.DefaultHasher()
+		}
+		s.cache = newResponseCache(cfg.CacheTTL, cfg.CacheMaxEntries, hasher)
+	}
+	if cfg.CoalesceReads {
+		s.reads = &singleflight.Group{}
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/posts", s.handlePosts)
-	mux.HandleFunc("/posts/", s.handlePostByID)
+	mux.HandleFunc("/health/upstream", s.handleUpstreamHealth)
+	mux.HandleFunc("/posts", s.cached(s.handlePosts))
+	mux.HandleFunc("/posts/", s.cached(s.handlePostByID))
+	mux.HandleFunc("/posts/latest", s.cached(s.handleLatestPost))
+	mux.HandleFunc("/posts/delete", s.requireAPIKey(s.handleDeletePosts))
+	mux.HandleFunc("/users", s.handleUsers)
+	mux.HandleFunc("/reconcile/report", s.handleReconcileReport)
 	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/status/errors", s.handleStatusErrors)
+	mux.HandleFunc("/metrics/history", s.handleMetricsHistory)
+	mux.HandleFunc("/ingest", s.requireAPIKey(s.handleIngest))
+	if cfg.DebugVars {
+		mux.HandleFunc("/debug/vars", s.handleDebugVars)
+	}
+	if cfg.DebugLastFetch {
+		mux.HandleFunc("/debug/last-fetch", s.handleLastFetch)
+	}
+	if s.metrics != nil {
+		mux.Handle("/metrics", promhttp.HandlerFor(s.metrics, promhttp.HandlerOpts{EnableOpenMetrics: cfg.OpenMetrics}))
+	}
+
+	var handler http.Handler = mux
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		handler = s.middleware[i](handler)
+	}
+	if cfg.ConnMaxAge > 0 {
+		handler = limitConnAge(cfg.ConnMaxAge)(handler)
+	}
+	handler = accessLog(s.logger, cfg.AccessLogLevel)(handler)
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Port),
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
+		ConnContext:  connStartContext,
 	}
 
 	return s
 }
 
+// SetIngestor enables the /ingest endpoint after construction
+func (s *Server) SetIngestor(ingestor Ingestor) {
+	s.ingestor = ingestor
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	return s.server.ListenAndServe()
@@ -54,15 +267,97 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 // handleHealth handles health check requests
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
+	now := time.Now().UTC()
+	response := map[string]string{
 		"status": "healthy",
-		"time":   time.Now().UTC().Format(time.RFC3339),
-	})
+		"time":   now.Format(time.RFC3339),
+	}
+
+	if reason := s.staleness(r.Context(), now); reason != "" {
+		response["status"] = "unhealthy"
+		response["reason"] = reason
+		writeJSONStatus(w, r, http.StatusServiceUnavailable, response)
+		return
+	}
+
+	writeJSON(w, r, response)
+}
+
+// handleUpstreamHealth probes the ingestor's upstreams. It is kept apart from
+// /health so a flaky upstream never fails the liveness probe: it returns 503
+// only when no upstream is reachable, and "degraded" when some aren't.
+func (s *Server) handleUpstreamHealth(w http.ResponseWriter, r *http.Request) {
+	prober, ok := s.ingestor.(upstreamProber)
+	if !ok {
+		http.Error(w, "Upstream probing not available", http.StatusNotImplemented)
+		return
+	}
+
+	ctx := r.Context()
+	if s.config.UpstreamProbeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.UpstreamProbeTimeout)
+		defer cancel()
+	}
+	probes := prober.ProbeUpstreams(ctx)
+
+	reachable := 0
+	for _, probe := range probes {
+		if probe.Reachable {
+			reachable++
+		}
+	}
+	response := map[string]any{
+		"status":  "healthy",
+		"time":    time.Now().UTC().Format(time.RFC3339),
+		"sources": probes,
+	}
+	switch {
+	case reachable == 0:
+		response["status"] = "unhealthy"
+		writeJSONStatus(w, r, http.StatusServiceUnavailable, response)
+		return
+	case reachable < len(probes):
+		response["status"] = "degraded"
+	}
+	writeJSON(w, r, response)
+}
+
+// staleness explains why ingestion is considered stale, or returns "" if it
+// isn't, the check is disabled, or startup is still within the grace period
+func (s *Server) staleness(ctx context.Context, now time.Time) string {
+	maxAge := s.config.HealthMaxStaleness
+	if maxAge <= 0 || now.Sub(s.started) < s.config.HealthStartupGrace {
+		return ""
+	}
+
+	// The stored status isn't rewritten after every successful cycle, so
+	// prefer the ingestor's own record of its last success
+	var last time.Time
+	if reporter, ok := s.ingestor.(successReporter); ok {
+		last = reporter.LastSuccess()
+	} else {
+		status, err := s.storage.GetIngestionStatus(ctx)
+		if err != nil {
+			return fmt.Sprintf("failed to retrieve status: %v", err)
+		}
+		last = status.LastSuccessfulRun
+	}
+	if last.IsZero() {
+		return "no successful ingestion since startup"
+	}
+	if age := now.Sub(last); age > maxAge {
+		return fmt.Sprintf("last successful ingestion was %s ago", age.Round(time.Second))
+	}
+	return ""
 }
 
 // handlePosts handles GET requests for posts
 func (s *Server) handlePosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.requireAPIKey(s.handleImportPosts)(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -72,12 +367,18 @@ func (s *Server) handlePosts(w http.ResponseWriter, r *http.Request) {
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
-	limit := 10 // default
+	limit := s.config.DefaultLimit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
 			limit = l
 		}
 	}
+	if s.config.MaxPageLimit > 0 && limit > s.config.MaxPageLimit {
+		limit = s.config.MaxPageLimit
+	}
 
 	offset := 0 // default
 	if offsetStr != "" {
@@ -85,46 +386,211 @@ func (s *Server) handlePosts(w http.ResponseWriter, r *http.Request) {
 			offset = o
 		}
 	}
+	if s.config.MaxOffset > 0 && offset > s.config.MaxOffset && r.URL.Query().Get("format") != "ndjson" {
+		// Reaching the offset means reading every post before it, on every
+		// page; the NDJSON stream reads them once however deep it goes
+		http.Error(w, fmt.Sprintf("offset %d exceeds the maximum of %d; read large result sets "+
+			"in a single pass with format=ndjson instead", offset, s.config.MaxOffset), http.StatusBadRequest)
+		return
+	}
+
+	var asOf time.Time
+	if asOfStr := r.URL.Query().Get("asOf"); asOfStr != "" {
+		if r.URL.Query().Get("format") != "ndjson" {
+			http.Error(w, "asOf is only supported with format=ndjson", http.StatusBadRequest)
+			return
+		}
+		parsed, err := time.Parse(time.RFC3339, asOfStr)
+		if err != nil {
+			http.Error(w, "invalid asOf: expected RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		asOf = parsed
+	}
+
+	if r.URL.Query().Get("format") == "ndjson" {
+		// The export walks every post, so it can't serve the filters' indexes
+		for _, filter := range []string{"category", "runId", "ingestedFrom", "ingestedTo"} {
+			if r.URL.Query().Get(filter) != "" {
+				http.Error(w, fmt.Sprintf("%s is not supported with format=ndjson", filter), http.StatusBadRequest)
+				return
+			}
+		}
+		streamLimit := 0 // Without a limit the stream runs to the last post
+		if limitStr != "" {
+			streamLimit = limit
+		}
+		s.streamPostsNDJSON(w, r, offset, streamLimit, asOf)
+		return
+	}
+
+	from, to, byRange, err := parseIngestionRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category := r.URL.Query().Get("category")
+	runID := r.URL.Query().Get("runId")
+	if runID != "" && (byRange || category != "") {
+		http.Error(w, "runId cannot be combined with category or ingestedFrom/ingestedTo", http.StatusBadRequest)
+		return
+	}
+	if category != "" {
+		if byRange {
+			http.Error(w, "category cannot be combined with ingestedFrom/ingestedTo", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(s.categories, category) {
+			http.Error(w, fmt.Sprintf("Unknown category %q", category), http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Get posts from storage
-	posts, err := s.storage.GetPosts(r.Context(), limit, offset)
+	key := fmt.Sprintf("limit=%d&offset=%d&category=%s&runId=%s&from=%s&to=%s",
+		limit, offset, category, runID, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	posts, err := s.readPosts(r, key, func(ctx context.Context) ([]models.TransformedPost, error) {
+		switch {
+		case byRange:
+			return s.storage.GetPostsByIngestionRange(ctx, from, to, limit, offset)
+		case category != "":
+			return s.storage.GetPostsByCategory(ctx, category, limit, offset)
+		case runID != "":
+			return s.storage.GetPostsByRunID(ctx, runID, limit, offset)
+		default:
+			return s.storage.GetPosts(ctx, limit, offset)
+		}
+	})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve posts: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"posts":  posts,
+	writeJSON(w, r, map[string]interface{}{
+		"posts":  s.present(posts),
 		"count":  len(posts),
 		"limit":  limit,
 		"offset": offset,
 	})
 }
 
+// parseIngestionRange reads the optional ingestedFrom/ingestedTo window.
+// Both bounds must be given together as RFC3339 timestamps.
+func parseIngestionRange(r *http.Request) (from, to time.Time, ok bool, err error) {
+	fromStr := r.URL.Query().Get("ingestedFrom")
+	toStr := r.URL.Query().Get("ingestedTo")
+	if fromStr == "" && toStr == "" {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	if fromStr == "" || toStr == "" {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("ingestedFrom and ingestedTo must be used together")
+	}
+
+	if from, err = time.Parse(time.RFC3339, fromStr); err != nil {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("invalid ingestedFrom: expected RFC3339 timestamp")
+	}
+	if to, err = time.Parse(time.RFC3339, toStr); err != nil {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("invalid ingestedTo: expected RFC3339 timestamp")
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, false, fmt.Errorf("ingestedFrom must not be after ingestedTo")
+	}
+
+	return from, to, true, nil
+}
+
+// errStreamLimit ends an NDJSON export once it has written its limit
+var errStreamLimit = errors.New("stream limit reached")
+
+// streamPostsNDJSON writes the posts from offset onwards as newline-delimited
+// JSON, as storage exports them page by page, stopping after limit posts
+// unless it is zero. Unless asOf is zero, posts first stored after it are
+// left out, so an export taken while ingestion runs doesn't include some of
+// a later cycle's posts but not others.
+func (s *Server) streamPostsNDJSON(w http.ResponseWriter, r *http.Request, offset, limit int, asOf time.Time) {
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	started := false
+	skipped, written := 0, 0
+
+	err := s.storage.ExportPosts(readContext(r), asOf, func(post models.TransformedPost) error {
+		if skipped < offset {
+			skipped++
+			return nil
+		}
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			started = true
+		}
+		if err := encoder.Encode(s.presentPost(post)); err != nil {
+			return err // Client went away
+		}
+		written++
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if written == limit {
+			return errStreamLimit
+		}
+		return nil
+	})
+	if errors.Is(err, errStreamLimit) {
+		err = nil
+	}
+	if started {
+		if err != nil {
+			// Headers are already sent, so end the stream with a record the
+			// client can tell from a post, rather than silently truncating it
+			s.logger.Error("Failed to stream posts", "error", err, "written", written)
+			encoder.Encode(map[string]string{"error": fmt.Sprintf("Failed to retrieve posts: %v", err)})
+		}
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve posts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+}
+
 // handlePostByID handles GET requests for a specific post
 func (s *Server) handlePostByID(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// The "/posts/" subtree pattern also matches the collection with a
+	// trailing slash, which lists posts like "/posts"
+	path := r.URL.Path
+	if path == "/posts/" {
+		s.handlePosts(w, r)
 		return
 	}
 
-	// Extract ID from path
-	path := r.URL.Path
-	if len(path) < 7 { // "/posts/"
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	idStr := path[7:] // Remove "/posts/"
+	idStr, resource, nested := strings.Cut(path[7:], "/") // Remove "/posts/"
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		http.Error(w, "Invalid post ID", http.StatusBadRequest)
 		return
 	}
 
+	if nested {
+		if resource != "comments" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		s.handleComments(w, r, id)
+		return
+	}
+
 	// Get post from storage
-	post, err := s.storage.GetPostByID(r.Context(), id)
+	post, err := s.storage.GetPostByID(readContext(r), id)
+	if errors.Is(err, storage.ErrSourceRequired) {
+		http.Error(w, "The source query parameter is required", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to retrieve post: %v", err), http.StatusInternalServerError)
 		return
@@ -135,8 +601,339 @@ func (s *Server) handlePostByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	writeJSON(w, r, s.presentPost(*post))
+}
+
+// handleLatestPost handles GET requests for the most recently ingested post
+func (s *Server) handleLatestPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	post, err := s.storage.GetLatestPost(readContext(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve latest post: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if post == nil {
+		http.Error(w, "No posts ingested yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, r, s.presentPost(*post))
+}
+
+// handleComments handles GET requests for a post's comments
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request, postID int) {
+	comments, err := s.storage.GetComments(readContext(r), postID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve comments: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, map[string]interface{}{
+		"post_id":  postID,
+		"comments": nonNil(comments),
+		"count":    len(comments),
+	})
+}
+
+// handleUsers handles GET requests listing the users that have posts
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDs, err := s.storage.GetUserIDs(readContext(r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve users: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, map[string]interface{}{
+		"user_ids": nonNil(userIDs),
+		"count":    len(userIDs),
+	})
+}
+
+// requireAPIKey rejects requests that don't carry the configured API key
+func (s *Server) requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.APIKey == "" {
+			http.Error(w, "Endpoint disabled: no API key configured", http.StatusForbidden)
+			return
+		}
+
+		key := r.Header.Get("X-API-Key")
+		if subtle.ConstantTimeCompare([]byte(key), []byte(s.config.APIKey)) != 1 {
+			http.Error(w, "Invalid or missing API key", http.StatusUnauthorized)
+			return
+		}
+
+		// Attribute writes to the key without recording the key itself
+		ctx := storage.WithAuditActor(r.Context(), "api_key:"+keyID(key), r.Header.Get("X-Request-ID"))
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// deleteRequest selects posts to delete either by ID list or inclusive range
+type deleteRequest struct {
+	IDs    []int  `json:"ids"`
+	From   *int   `json:"from"`
+	To     *int   `json:"to"`
+	Source string `json:"source,omitempty"` // Required when posts are keyed by source and ID
+}
+
+// handleDeletePosts handles POST requests deleting a batch of posts
+func (s *Server) handleDeletePosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req deleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ids := req.IDs
+	switch {
+	case len(ids) > 0 && (req.From != nil || req.To != nil):
+		http.Error(w, "Specify either ids or from/to, not both", http.StatusBadRequest)
+		return
+	case req.From != nil && req.To != nil:
+		if *req.From > *req.To {
+			http.Error(w, "from must not be greater than to", http.StatusBadRequest)
+			return
+		}
+		if *req.To-*req.From >= maxDeleteBatch {
+			http.Error(w, fmt.Sprintf("Range exceeds %d posts", maxDeleteBatch), http.StatusBadRequest)
+			return
+		}
+		for id := *req.From; id <= *req.To; id++ {
+			ids = append(ids, id)
+		}
+	case len(ids) == 0:
+		http.Error(w, "Specify ids or both from and to", http.StatusBadRequest)
+		return
+	}
+
+	if len(ids) > maxDeleteBatch {
+		http.Error(w, fmt.Sprintf("Cannot delete more than %d posts at once", maxDeleteBatch), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if req.Source != "" {
+		ctx = storage.WithSource(ctx, req.Source)
+	}
+	deleted, err := s.storage.DeletePosts(ctx, ids)
+	if errors.Is(err, storage.ErrSourceRequired) {
+		http.Error(w, "A source is required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete posts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.InvalidateCache()
+
+	writeJSON(w, r, map[string]int{
+		"deleted": deleted,
+	})
+}
+
+// writeJSON encodes v as the JSON response, indented when the client passes
+// pretty=true
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	writeJSONStatus(w, r, http.StatusOK, v)
+}
+
+// present returns posts ready to encode in the configured field naming
+func (s *Server) present(posts []models.TransformedPost) interface{} {
+	if s.naming == models.NamingAsTagged {
+		return nonNil(posts)
+	}
+	named := make([]models.NamedPost, len(posts))
+	for i, post := range posts {
+		named[i] = models.NamedPost{TransformedPost: post, Naming: s.naming}
+	}
+	return named
+}
+
+// presentPost is present for a single post
+func (s *Server) presentPost(post models.TransformedPost) interface{} {
+	if s.naming == models.NamingAsTagged {
+		return post
+	}
+	return models.NamedPost{TransformedPost: post, Naming: s.naming}
+}
+
+// nonNil returns items, or an empty slice if it is nil, so lists always
+// encode as [] rather than null whatever the backend returns
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// writeJSONStatus is like writeJSON with a non-200 status code
+func writeJSONStatus(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(post)
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+		encoder.SetIndent("", "  ")
+	}
+	encoder.Encode(v)
+}
+
+// keyID returns a short, non-reversible identifier for an API key
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// readContext returns the request context, widened to include soft-deleted
+// posts when the client passes includeDeleted=true and addressing the
+// client's source, if it passes one
+func readContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if include, _ := strconv.ParseBool(r.URL.Query().Get("includeDeleted")); include {
+		ctx = storage.WithDeleted(ctx)
+	}
+	if source := r.URL.Query().Get("source"); source != "" {
+		ctx = storage.WithSource(ctx, source)
+	}
+	return ctx
+}
+
+// handleIngest handles POST requests triggering an immediate ingestion run
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.ingestor == nil {
+		http.Error(w, "Ingestion is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if s.readOnly() {
+		http.Error(w, "Ingestion paused: storage is read-only", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx := ingestion.WithForwardedHeaders(r.Context(), r.Header)
+	if err := s.ingestor.IngestData(ctx); err != nil {
+		if errors.Is(err, ingestion.ErrReadOnly) {
+			http.Error(w, "Ingestion paused: storage is read-only", http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, ingestion.ErrMemoryPressure) {
+			http.Error(w, "Ingestion shed: memory usage is too high", http.StatusServiceUnavailable)
+			return
+		}
+		s.logger.Error("Manual ingestion failed", "error", err)
+		http.Error(w, fmt.Sprintf("Ingestion failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	writeJSON(w, r, map[string]string{
+		"status": "success",
+	})
+}
+
+// rejectedPost identifies an import batch entry that failed validation
+type rejectedPost struct {
+	Index int    `json:"index"`
+	ID    int    `json:"id"`
+	Field string `json:"field,omitempty"` // Set for overlong fields
+	Error string `json:"error"`
+}
+
+// handleImportPosts handles POST requests storing a batch of posts directly,
+// bypassing the upstream fetch. Invalid posts are rejected individually,
+// but any overlong field fails the whole batch so it can be fixed up front
+// rather than failing in storage.
+func (s *Server) handleImportPosts(w http.ResponseWriter, r *http.Request) {
+	imp, ok := s.ingestor.(importer)
+	if !ok {
+		http.Error(w, "Import is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var posts []models.Post
+	if err := json.NewDecoder(r.Body).Decode(&posts); err != nil {
+		http.Error(w, "Invalid request body: expected a JSON array of posts", http.StatusBadRequest)
+		return
+	}
+	if len(posts) == 0 {
+		http.Error(w, "Specify at least one post", http.StatusBadRequest)
+		return
+	}
+	if len(posts) > maxImportBatch {
+		http.Error(w, fmt.Sprintf("Cannot import more than %d posts at once", maxImportBatch), http.StatusBadRequest)
+		return
+	}
+
+	limits := models.PostLimits{
+		MaxTitleLength: s.config.MaxTitleLength,
+		MaxBodyLength:  s.config.MaxBodyLength,
+	}
+	valid := make([]models.Post, 0, len(posts))
+	rejected := []rejectedPost{}
+	var overlong []rejectedPost
+	for i, post := range posts {
+		err := post.Validate(limits)
+		var lengthErr *models.FieldLengthError
+		switch {
+		case errors.As(err, &lengthErr):
+			overlong = append(overlong, rejectedPost{Index: i, ID: post.ID, Field: lengthErr.Field, Error: err.Error()})
+		case err != nil:
+			rejected = append(rejected, rejectedPost{Index: i, ID: post.ID, Error: err.Error()})
+		default:
+			valid = append(valid, post)
+		}
+	}
+	if len(overlong) > 0 {
+		writeJSONStatus(w, r, http.StatusBadRequest, map[string]interface{}{
+			"error":    "Posts have fields exceeding the maximum length",
+			"rejected": overlong,
+		})
+		return
+	}
+
+	stored := 0
+	if len(valid) > 0 {
+		var err error
+		if stored, err = imp.ImportPosts(r.Context(), valid); err != nil {
+			if errors.Is(err, ingestion.ErrReadOnly) {
+				http.Error(w, "Import paused: storage is read-only", http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Failed to import posts: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.InvalidateCache()
+	}
+
+	writeJSON(w, r, map[string]interface{}{
+		"stored":   stored,
+		"rejected": rejected,
+	})
+}
+
+// readOnly reports whether the ingestor has paused because writes are failing
+func (s *Server) readOnly() bool {
+	reporter, ok := s.ingestor.(readOnlyReporter)
+	return ok && reporter.ReadOnly()
 }
 
 // handleStatus handles GET requests for ingestion status
@@ -151,7 +948,88 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to retrieve status: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if s.readOnly() {
+		// The stored status can't be updated while writes fail
+		status.Status = "read_only"
+	} else if reporter, ok := s.ingestor.(loadShedReporter); ok && reporter.SheddingLoad() {
+		status.Status = "shedding_load"
+	}
+	if reporter, ok := s.ingestor.(rateLimitReporter); ok {
+		status.RateLimit = reporter.RateLimit()
+	}
+	if reporter, ok := s.ingestor.(endpointHealthReporter); ok {
+		status.Endpoints = reporter.EndpointHealth()
+	}
+
+	writeJSON(w, r, status)
+}
+
+// handleStatusErrors handles GET requests for recent ingestion errors
+func (s *Server) handleStatusErrors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(status)
+	history, ok := s.ingestor.(errorHistory)
+	if !ok {
+		http.Error(w, "Error history is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	errs := history.RecentErrors()
+	writeJSON(w, r, map[string]interface{}{
+		"errors": nonNil(errs),
+		"count":  len(errs),
+	})
+}
+
+// handleMetricsHistory handles GET requests for recent per-cycle metrics
+// snapshots, newest first
+func (s *Server) handleMetricsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 10 // default
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if s.config.MaxPageLimit > 0 && limit > s.config.MaxPageLimit {
+		limit = s.config.MaxPageLimit
+	}
+
+	snapshots, err := s.storage.GetMetricsHistory(r.Context(), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to retrieve metrics history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, map[string]interface{}{
+		"snapshots": nonNil(snapshots),
+		"count":     len(snapshots),
+	})
+}
+
+// handleReconcileReport handles GET requests for the latest reconciliation report
+func (s *Server) handleReconcileReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reporter, ok := s.ingestor.(reconcileReporter)
+	if !ok {
+		http.Error(w, "Reconciliation is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	report := reporter.LastReconcileReport()
+	if report == nil {
+		http.Error(w, "No reconciliation has run yet", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, r, report)
 }
\ No newline at end of file
@@ -0,0 +1,1402 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/ingestion"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+	"github.com/cyderes/data-ingestion-service/internal/storage"
+)
+
+// MockStorage is a mock implementation of the Storage interface
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	args := m.Called(ctx, posts)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetPostsByIngestionRange(ctx context.Context, from, to time.Time, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, from, to, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetPostsByCategory(ctx context.Context, category string, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, category, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetPostsByRunID(ctx context.Context, runID string, limit int, offset int) ([]models.TransformedPost, error) {
+	args := m.Called(ctx, runID, limit, offset)
+	return args.Get(0).([]models.TransformedPost), args.Error(1)
+}
+
+// ExportPosts passes fn the posts set up with On("ExportPosts", ctx, asOf)
+func (m *MockStorage) ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error {
+	args := m.Called(ctx, asOf)
+	for _, post := range args.Get(0).([]models.TransformedPost) {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetLatestPost(ctx context.Context) (*models.TransformedPost, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*models.TransformedPost), args.Error(1)
+}
+
+func (m *MockStorage) GetUserIDs(ctx context.Context) ([]int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockStorage) StoreComments(ctx context.Context, comments []models.Comment) error {
+	args := m.Called(ctx, comments)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetComments(ctx context.Context, postID int) ([]models.Comment, error) {
+	args := m.Called(ctx, postID)
+	return args.Get(0).([]models.Comment), args.Error(1)
+}
+
+func (m *MockStorage) DeletePost(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DeletePosts(ctx context.Context, ids []int) (int, error) {
+	args := m.Called(ctx, ids)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStorage) UpdateIngestionStatus(ctx context.Context, status models.IngestionStatus) error {
+	args := m.Called(ctx, status)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetIngestionStatus(ctx context.Context) (*models.IngestionStatus, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*models.IngestionStatus), args.Error(1)
+}
+
+func (m *MockStorage) RecordMetricsSnapshot(ctx context.Context, snapshot models.MetricsSnapshot) error {
+	args := m.Called(ctx, snapshot)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetMetricsHistory(ctx context.Context, limit int) ([]models.MetricsSnapshot, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]models.MetricsSnapshot), args.Error(1)
+}
+
+func (m *MockStorage) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func makePosts(from, count int) []models.TransformedPost {
+	posts := make([]models.TransformedPost, count)
+	for i := range posts {
+		posts[i] = models.TransformedPost{
+			Post:       models.Post{UserID: 1, ID: from + i, Title: "Test Post", Body: "Test body"},
+			IngestedAt: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			Source:     "placeholder_api",
+		}
+	}
+	return posts
+}
+
+func TestServer_handlePosts_NDJSON(t *testing.T) {
+	// Create mock storage exporting 150 posts
+	mockStorage := new(MockStorage)
+	mockStorage.On("ExportPosts", mock.Anything, time.Time{}).Return(makePosts(1, 150), nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test the NDJSON stream
+	req := httptest.NewRequest(http.MethodGet, "/posts?format=ndjson", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+
+	scanner := bufio.NewScanner(rec.Body)
+	lines := 0
+	for scanner.Scan() {
+		var post models.TransformedPost
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &post))
+		assert.Equal(t, lines+1, post.ID)
+		assert.Equal(t, "Test Post", post.Title)
+		lines++
+	}
+
+	assert.Equal(t, 150, lines)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handlePosts_NDJSONError(t *testing.T) {
+	// Create mock storage whose export fails before any post
+	mockStorage := new(MockStorage)
+	mockStorage.On("ExportPosts", mock.Anything, time.Time{}).Return([]models.TransformedPost{}, errors.New("scan failed"))
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test the failure is reported rather than sent as an empty stream
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts?format=ndjson", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestServer_handlePosts_NDJSONMidStreamError(t *testing.T) {
+	// Create mock storage whose export fails after some posts
+	mockStorage := new(MockStorage)
+	mockStorage.On("ExportPosts", mock.Anything, time.Time{}).Return(makePosts(1, 2), errors.New("scan failed"))
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts?format=ndjson", nil))
+
+	// Test the stream ends with an error record after the posts sent
+	assert.Equal(t, http.StatusOK, rec.Code)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.JSONEq(t, `{"error": "Failed to retrieve posts: scan failed"}`, lines[2])
+}
+
+func TestServer_handlePosts_NDJSONLimitAndFilters(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("ExportPosts", mock.Anything, time.Time{}).Return(makePosts(1, 10), nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test limit caps the stream after offset
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts?format=ndjson&offset=2&limit=3", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var ids []int
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var post models.TransformedPost
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &post))
+		ids = append(ids, post.ID)
+	}
+	assert.Equal(t, []int{3, 4, 5}, ids)
+
+	// Test filters the export can't apply are rejected
+	for _, query := range []string{"category=news", "runId=run-1", "ingestedFrom=2024-01-15T00:00:00Z&ingestedTo=2024-01-16T00:00:00Z"} {
+		rec = httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts?format=ndjson&"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestServer_handlePosts_NDJSONAsOf(t *testing.T) {
+	// Create mock storage exporting the posts stored as of the snapshot
+	snapshot := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	mockStorage := new(MockStorage)
+	mockStorage.On("ExportPosts", mock.Anything, snapshot).Return(makePosts(1, 3), nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test the snapshot is passed to the export, with offset applied after it
+	req := httptest.NewRequest(http.MethodGet, "/posts?format=ndjson&offset=1&asOf="+snapshot.Format(time.RFC3339), nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var ids []int
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var post models.TransformedPost
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &post))
+		ids = append(ids, post.ID)
+	}
+	assert.Equal(t, []int{2, 3}, ids)
+
+	// Test invalid snapshots are rejected
+	for _, query := range []string{"format=ndjson&asOf=yesterday", "asOf=2024-01-15T12:00:00Z"} {
+		rec = httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestServer_ReadHandlers_NilSlices(t *testing.T) {
+	// Create mock storage returning nil rather than empty slices
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return([]models.TransformedPost(nil), nil)
+	mockStorage.On("GetComments", mock.Anything, 1).Return([]models.Comment(nil), nil)
+	mockStorage.On("GetUserIDs", mock.Anything).Return([]int(nil), nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/posts", `"posts":[]`},
+		{"/posts/1/comments", `"comments":[]`},
+		{"/users", `"user_ids":[]`},
+	}
+
+	// Test every list encodes as [] rather than null
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.want)
+			assert.NotContains(t, rec.Body.String(), "null")
+		})
+	}
+}
+
+func TestServer_handlePosts_IncludeDeleted(t *testing.T) {
+	// Create mock storage expecting a context that includes deleted posts
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.MatchedBy(storage.IncludesDeleted), 10, 0).Return(makePosts(1, 2), nil).Once()
+	mockStorage.On("GetPosts", mock.MatchedBy(func(ctx context.Context) bool {
+		return !storage.IncludesDeleted(ctx)
+	}), 10, 0).Return(makePosts(1, 1), nil).Once()
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Default request hides deleted posts
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"count":1`)
+
+	// includeDeleted=true surfaces them
+	req = httptest.NewRequest(http.MethodGet, "/posts?includeDeleted=true", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"count":2`)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handleDeletePosts(t *testing.T) {
+	// Create mock storage where only some of the range exists
+	mockStorage := new(MockStorage)
+	mockStorage.On("DeletePosts", mock.Anything, []int{3, 4, 5, 6}).Return(2, nil)
+
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage)
+
+	// Test deleting a partial range
+	req := httptest.NewRequest(http.MethodPost, "/posts/delete", strings.NewReader(`{"from": 3, "to": 6}`))
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"deleted": 2}`, rec.Body.String())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_requireAPIKey_AuditActor(t *testing.T) {
+	// Create mock storage capturing who the delete is attributed to
+	var actor, requestID string
+	mockStorage := new(MockStorage)
+	mockStorage.On("DeletePosts", mock.Anything, []int{1}).
+		Run(func(args mock.Arguments) {
+			actor, requestID = storage.AuditActor(args.Get(0).(context.Context))
+		}).
+		Return(1, nil)
+
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage)
+
+	req := httptest.NewRequest(http.MethodPost, "/posts/delete", strings.NewReader(`{"ids": [1]}`))
+	req.Header.Set("X-API-Key", "secret")
+	req.Header.Set("X-Request-ID", "req-42")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	// Test the write is attributed to the key without exposing it
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "api_key:"+keyID("secret"), actor)
+	assert.NotContains(t, actor, "secret")
+	assert.Equal(t, "req-42", requestID)
+}
+
+func TestServer_handleDeletePosts_Validation(t *testing.T) {
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, new(MockStorage))
+
+	tests := []struct {
+		name   string
+		key    string
+		body   string
+		status int
+	}{
+		{"missing key", "", `{"ids": [1]}`, http.StatusUnauthorized},
+		{"wrong key", "nope", `{"ids": [1]}`, http.StatusUnauthorized},
+		{"empty request", "secret", `{}`, http.StatusBadRequest},
+		{"inverted range", "secret", `{"from": 5, "to": 1}`, http.StatusBadRequest},
+		{"ids and range", "secret", `{"ids": [1], "from": 1, "to": 2}`, http.StatusBadRequest},
+		{"oversized range", "secret", `{"from": 1, "to": 5000}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/posts/delete", strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+// importBody encodes a batch of count valid posts starting at ID from
+func importBody(from, count int) string {
+	posts := make([]models.Post, count)
+	for i := range posts {
+		posts[i] = models.Post{UserID: 1, ID: from + i, Title: "Imported Post", Body: "body"}
+	}
+	body, _ := json.Marshal(posts)
+	return string(body)
+}
+
+func TestServer_handleImportPosts(t *testing.T) {
+	// Create mock storage capturing the imported posts
+	var stored []models.TransformedPost
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).([]models.TransformedPost)
+		}).
+		Return(nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{}, mockStorage)
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage, WithIngestor(ingestor))
+
+	// Test a valid batch is transformed and stored
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(importBody(1, 3)))
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"stored": 3, "rejected": []}`, rec.Body.String())
+	if assert.Len(t, stored, 3) {
+		assert.Equal(t, 1, stored[0].ID)
+		assert.Equal(t, "import", stored[0].Source)
+		assert.False(t, stored[0].IngestedAt.IsZero())
+	}
+}
+
+func TestServer_handleImportPosts_InvalidPosts(t *testing.T) {
+	// Create mock storage capturing the imported posts
+	var stored []models.TransformedPost
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).([]models.TransformedPost)
+		}).
+		Return(nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{}, mockStorage)
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage, WithIngestor(ingestor))
+
+	// Test valid posts are stored and invalid ones reported by index
+	body := `[
+		{"userId": 1, "id": 1, "title": "Valid"},
+		{"userId": 1, "id": 0, "title": "Missing ID"},
+		{"userId": 1, "id": 3, "title": "  "},
+		{"userId": 0, "id": 4, "title": "Missing user"}
+	]`
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"stored": 1,
+		"rejected": [
+			{"index": 1, "id": 0, "error": "id must be positive"},
+			{"index": 2, "id": 3, "error": "title is required"},
+			{"index": 3, "id": 4, "error": "userId must be positive"}
+		]
+	}`, rec.Body.String())
+	if assert.Len(t, stored, 1) {
+		assert.Equal(t, 1, stored[0].ID)
+	}
+
+	// Test a batch with no valid posts stores nothing
+	stored = nil
+	req = httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`[{"id": 5}]`))
+	req.Header.Set("X-API-Key", "secret")
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"stored":0`)
+	assert.Nil(t, stored)
+}
+
+func TestServer_handleImportPosts_Overlong(t *testing.T) {
+	tests := []struct {
+		name  string
+		post  models.Post
+		field string
+	}{
+		{"overlong title", models.Post{UserID: 1, ID: 2, Title: strings.Repeat("t", 11), Body: "body"}, "title"},
+		{"overlong body", models.Post{UserID: 1, ID: 2, Title: "title", Body: strings.Repeat("b", 21)}, "body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			ingestor := ingestion.NewService(config.IngestionConfig{}, mockStorage)
+			s := NewServer(config.ServerConfig{APIKey: "secret", MaxTitleLength: 10, MaxBodyLength: 20}, mockStorage, WithIngestor(ingestor))
+
+			// Test the whole batch is rejected, naming the field and index
+			batch := []models.Post{{UserID: 1, ID: 1, Title: "Valid"}, tt.post}
+			body, _ := json.Marshal(batch)
+			req := httptest.NewRequest(http.MethodPost, "/posts", bytes.NewReader(body))
+			req.Header.Set("X-API-Key", "secret")
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp struct {
+				Rejected []rejectedPost `json:"rejected"`
+			}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			if assert.Len(t, resp.Rejected, 1) {
+				assert.Equal(t, 1, resp.Rejected[0].Index)
+				assert.Equal(t, 2, resp.Rejected[0].ID)
+				assert.Equal(t, tt.field, resp.Rejected[0].Field)
+			}
+			mockStorage.AssertNotCalled(t, "StorePosts", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestServer_handleImportPosts_Validation(t *testing.T) {
+	mockStorage := new(MockStorage)
+	ingestor := ingestion.NewService(config.IngestionConfig{}, mockStorage)
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage, WithIngestor(ingestor))
+
+	tests := []struct {
+		name   string
+		key    string
+		body   string
+		status int
+	}{
+		{"missing key", "", importBody(1, 1), http.StatusUnauthorized},
+		{"not an array", "secret", `{"id": 1}`, http.StatusBadRequest},
+		{"empty batch", "secret", `[]`, http.StatusBadRequest},
+		{"oversized batch", "secret", importBody(1, maxImportBatch+1), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+	mockStorage.AssertNotCalled(t, "StorePosts", mock.Anything, mock.Anything)
+}
+
+func TestServer_handleDeletePosts_NoKeyConfigured(t *testing.T) {
+	s := NewServer(config.ServerConfig{}, new(MockStorage))
+
+	req := httptest.NewRequest(http.MethodPost, "/posts/delete", strings.NewReader(`{"ids": [1]}`))
+	req.Header.Set("X-API-Key", "")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestServer_handleIngest_ForwardsTraceHeaders(t *testing.T) {
+	var upstreamTrace string
+
+	// Create mock upstream that records the trace header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTrace = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post 1"}})
+	}))
+	defer upstream.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.AnythingOfType("[]models.TransformedPost")).Return(nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoint:    upstream.URL,
+		Timeout:        30 * time.Second,
+		RetryCount:     1,
+		ForwardHeaders: []string{"traceparent"},
+	}, mockStorage)
+
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage)
+	s.SetIngestor(ingestor)
+
+	// Test a manually triggered ingest
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("X-API-Key", "secret")
+	req.Header.Set("traceparent", traceparent)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, traceparent, upstreamTrace)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handlePosts_IngestionRange(t *testing.T) {
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	// Create mock storage returning posts within the window
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPostsByIngestionRange", mock.Anything, from, to, 10, 0).Return(makePosts(1, 2), nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test a valid window
+	req := httptest.NewRequest(http.MethodGet, "/posts?ingestedFrom=2024-01-15T00:00:00Z&ingestedTo=2024-01-16T00:00:00Z", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"count":2`)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handlePosts_IngestionRange_Invalid(t *testing.T) {
+	s := NewServer(config.ServerConfig{}, new(MockStorage))
+
+	for _, query := range []string{
+		"ingestedFrom=2024-01-16T00:00:00Z&ingestedTo=2024-01-15T00:00:00Z",
+		"ingestedFrom=2024-01-15T00:00:00Z",
+		"ingestedFrom=yesterday&ingestedTo=2024-01-15T00:00:00Z",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/posts?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+type failingIngestor struct{}
+
+func (failingIngestor) IngestData(ctx context.Context) error {
+	return errors.New("upstream unavailable")
+}
+
+func TestNewServer_Options(t *testing.T) {
+	var logs bytes.Buffer
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, new(MockStorage),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+		WithIngestor(failingIngestor{}),
+		WithMiddleware(tag("outer"), tag("inner")),
+	)
+
+	// Test a failing ingest passes through middleware and is logged
+	req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, []string{"outer", "inner"}, order)
+	assert.Equal(t, []string{"outer", "inner"}, rec.Header().Values("X-Middleware"))
+	assert.Contains(t, logs.String(), "upstream unavailable")
+}
+
+func TestNewServer_WithMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_events_total", Help: "Test counter"})
+	registry.MustRegister(counter)
+	counter.Inc()
+
+	s := NewServer(config.ServerConfig{}, new(MockStorage), WithMetrics(registry))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "test_events_total 1")
+}
+
+func TestServer_handlePosts_Pretty(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 1), nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Default output is compact
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "\n"))
+
+	// pretty=true indents it
+	req = httptest.NewRequest(http.MethodGet, "/posts?pretty=true", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "\n  \"count\": 1,\n")
+	assert.Contains(t, rec.Body.String(), "\n      \"title\": \"Test Post\",\n")
+}
+
+// readOnlyIngestor reports storage writes as failing
+type readOnlyIngestor struct {
+	readOnly bool
+}
+
+func (i *readOnlyIngestor) IngestData(ctx context.Context) error {
+	return nil
+}
+
+func (i *readOnlyIngestor) ReadOnly() bool {
+	return i.readOnly
+}
+
+func TestServer_ReadOnlyMode(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{Status: "failure"}, nil)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 1), nil)
+
+	ingestor := &readOnlyIngestor{readOnly: true}
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage, WithIngestor(ingestor))
+
+	// Test /ingest is unavailable
+	req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Test /status reports read-only mode
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"read_only"`)
+
+	// Test /posts keeps serving
+	req = httptest.NewRequest(http.MethodGet, "/posts", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Test recovery restores /ingest
+	ingestor.readOnly = false
+	req = httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServer_SheddingLoad(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{Status: "success"}, nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{MaxHeapBytes: 1},
+		mockStorage, ingestion.WithHeapStats(func() uint64 { return 2 }))
+	s := NewServer(config.ServerConfig{APIKey: "secret"}, mockStorage, WithIngestor(ingestor))
+
+	// Test /ingest sheds the cycle
+	req := httptest.NewRequest(http.MethodPost, "/ingest", nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "memory usage is too high")
+
+	// Test /status reports the shedding
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"shedding_load"`)
+}
+
+// rateLimitIngestor reports a fixed upstream rate-limit budget
+type rateLimitIngestor struct {
+	rateLimit *models.RateLimit
+}
+
+func (i *rateLimitIngestor) IngestData(ctx context.Context) error {
+	return nil
+}
+
+func (i *rateLimitIngestor) RateLimit() *models.RateLimit {
+	return i.rateLimit
+}
+
+func TestServer_handleStatus_RateLimit(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{Status: "success"}, nil)
+
+	ingestor := &rateLimitIngestor{}
+	s := NewServer(config.ServerConfig{}, mockStorage, WithIngestor(ingestor))
+
+	// Test the budget is omitted until the upstream reports one
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "rate_limit")
+
+	// Test the reported budget is included
+	ingestor.rateLimit = &models.RateLimit{Remaining: 42, Limit: 60}
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"rate_limit":{"remaining":42,"limit":60,`)
+}
+
+func TestServer_handleStatus_EndpointHealth(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{Status: "success"}, nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoints: []config.WeightedEndpoint{{URL: "https://a/posts", Weight: 1}},
+	}, mockStorage)
+	s := NewServer(config.ServerConfig{}, mockStorage, WithIngestor(ingestor))
+
+	// Test each mirror's health is included
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"endpoints":[{"url":"https://a/posts","healthy":true,"consecutive_failures":0}]`)
+}
+
+func TestServer_handleStatus_NeverRun(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{Status: "never_run"}, nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	// Test unset timestamps are null rather than the zero time
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "0001-01-01")
+
+	var status map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, "never_run", status["status"])
+	assert.Contains(t, status, "last_successful_run")
+	assert.Nil(t, status["last_successful_run"])
+	assert.Contains(t, status, "last_attempt")
+	assert.Nil(t, status["last_attempt"])
+}
+
+func TestServer_handleHealth_StartupGrace(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{Status: "never_run"}, nil)
+
+	s := NewServer(config.ServerConfig{
+		HealthMaxStaleness: time.Minute,
+		HealthStartupGrace: time.Hour,
+	}, mockStorage)
+
+	// Test healthy within the grace period despite no successful run
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"healthy"`)
+	mockStorage.AssertNotCalled(t, "GetIngestionStatus", mock.Anything)
+
+	// Test unhealthy once the grace period has passed
+	s.started = time.Now().Add(-2 * time.Hour)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"unhealthy"`)
+	assert.Contains(t, rec.Body.String(), "no successful ingestion")
+}
+
+func TestServer_handleHealth_Staleness(t *testing.T) {
+	tests := []struct {
+		name   string
+		last   time.Time
+		status int
+	}{
+		{"recent run", time.Now().Add(-30 * time.Second), http.StatusOK},
+		{"stale run", time.Now().Add(-10 * time.Minute), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockStorage.On("GetIngestionStatus", mock.Anything).Return(&models.IngestionStatus{LastSuccessfulRun: tt.last}, nil)
+
+			s := NewServer(config.ServerConfig{HealthMaxStaleness: time.Minute}, mockStorage)
+
+			req := httptest.NewRequest(http.MethodGet, "/health", nil)
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestServer_handleHealth_StalenessFromIngestor(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]models.Post{{UserID: 1, ID: 1, Title: "Test Post"}})
+	}))
+	defer upstream.Close()
+
+	// Create an ingestor whose ordinary cycles don't rewrite the stored status
+	mockStorage := new(MockStorage)
+	mockStorage.On("StorePosts", mock.Anything, mock.Anything).Return(nil)
+	ingestor := ingestion.NewService(config.IngestionConfig{APIEndpoint: upstream.URL, Timeout: time.Second, RetryCount: 1}, mockStorage)
+	s := NewServer(config.ServerConfig{HealthMaxStaleness: time.Minute, HealthStartupGrace: time.Second}, mockStorage, WithIngestor(ingestor))
+	s.started = time.Now().Add(-time.Hour)
+
+	// Test unhealthy before any cycle succeeds
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Test single-batch cycles keep it healthy after the grace period
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, ingestor.IngestData(context.Background()))
+
+		rec = httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	mockStorage.AssertNotCalled(t, "UpdateIngestionStatus", mock.Anything, mock.Anything)
+	mockStorage.AssertNotCalled(t, "GetIngestionStatus", mock.Anything)
+}
+
+func TestServer_handleUpstreamHealth(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	get := func(cfg config.IngestionConfig) (*httptest.ResponseRecorder, map[string]any) {
+		mockStorage := new(MockStorage)
+		ingestor := ingestion.NewService(cfg, mockStorage)
+		s := NewServer(config.ServerConfig{UpstreamProbeTimeout: time.Second}, mockStorage, WithIngestor(ingestor))
+
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/upstream", nil))
+		var body map[string]any
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		mockStorage.AssertNotCalled(t, "GetIngestionStatus", mock.Anything)
+		return rec, body
+	}
+
+	// Test every upstream reachable
+	rec, body := get(config.IngestionConfig{APIEndpoint: up.URL, FallbackAPIEndpoint: up.URL})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "healthy", body["status"])
+	assert.Len(t, body["sources"], 2)
+
+	// Test a down fallback degrades the status without failing it
+	rec, body = get(config.IngestionConfig{APIEndpoint: up.URL, FallbackAPIEndpoint: down.URL})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "degraded", body["status"])
+	if sources, ok := body["sources"].([]any); assert.True(t, ok) && assert.Len(t, sources, 2) {
+		primary, fallback := sources[0].(map[string]any), sources[1].(map[string]any)
+		assert.Equal(t, "placeholder_api", primary["source"])
+		assert.Equal(t, true, primary["reachable"])
+		assert.Equal(t, "fallback_api", fallback["source"])
+		assert.Equal(t, false, fallback["reachable"])
+		assert.Contains(t, fallback["error"], "502")
+	}
+
+	// Test nothing reachable
+	rec, body = get(config.IngestionConfig{APIEndpoint: down.URL})
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "unhealthy", body["status"])
+}
+
+func TestServer_handleUsers(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetUserIDs", mock.Anything).Return([]int{1, 2, 5}, nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test listing users
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"user_ids": [1, 2, 5], "count": 3}`, rec.Body.String())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handleLatestPost(t *testing.T) {
+	latest := makePosts(7, 1)[0]
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetLatestPost", mock.Anything).Return(&latest, nil).Once()
+	mockStorage.On("GetLatestPost", mock.Anything).Return((*models.TransformedPost)(nil), nil).Once()
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test the newest post is returned
+	req := httptest.NewRequest(http.MethodGet, "/posts/latest", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var post models.TransformedPost
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &post))
+	assert.Equal(t, 7, post.ID)
+
+	// Test an empty store
+	req = httptest.NewRequest(http.MethodGet, "/posts/latest", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handleComments(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetComments", mock.Anything, 1).Return([]models.Comment{
+		{PostID: 1, ID: 1, Name: "first", Email: "a@example.com", Body: "Nice post"},
+		{PostID: 1, ID: 2, Name: "second", Email: "b@example.com", Body: "Agreed"},
+	}, nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test listing a post's comments
+	req := httptest.NewRequest(http.MethodGet, "/posts/1/comments", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		PostID   int              `json:"post_id"`
+		Comments []models.Comment `json:"comments"`
+		Count    int              `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.PostID)
+	assert.Equal(t, 2, response.Count)
+	assert.Equal(t, "Agreed", response.Comments[1].Body)
+
+	// Test unknown nested resources
+	req = httptest.NewRequest(http.MethodGet, "/posts/1/likes", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handleMetricsHistory(t *testing.T) {
+	// Create storage holding three cycles' snapshots
+	store := storage.NewStdoutSink(io.Discard)
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		snapshot := models.MetricsSnapshot{Time: base.Add(time.Duration(i) * time.Minute), RecordsIn: i}
+		assert.NoError(t, store.RecordMetricsSnapshot(context.Background(), snapshot))
+	}
+
+	s := NewServer(config.ServerConfig{}, store)
+
+	// Test the endpoint returns the newest snapshots first, up to limit
+	req := httptest.NewRequest(http.MethodGet, "/metrics/history?limit=2", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Snapshots []models.MetricsSnapshot `json:"snapshots"`
+		Count     int                      `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Count)
+	if assert.Len(t, response.Snapshots, 2) {
+		assert.Equal(t, 3, response.Snapshots[0].RecordsIn)
+		assert.Equal(t, 2, response.Snapshots[1].RecordsIn)
+	}
+}
+
+func TestServer_handleStatusErrors(t *testing.T) {
+	// Create mock upstream that fails with a different status each time
+	code := 500
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		code++
+	}))
+	defer upstream.Close()
+
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoint:      upstream.URL,
+		Timeout:          30 * time.Second,
+		RetryCount:       1,
+		ErrorHistorySize: 10,
+	}, new(MockStorage))
+
+	for i := 0; i < 3; i++ {
+		assert.Error(t, ingestor.IngestData(context.Background()))
+	}
+
+	s := NewServer(config.ServerConfig{}, new(MockStorage), WithIngestor(ingestor))
+
+	// Test the endpoint returns errors newest first
+	req := httptest.NewRequest(http.MethodGet, "/status/errors", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Errors []models.IngestionError `json:"errors"`
+		Count  int                     `json:"count"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 3, response.Count)
+	if assert.Len(t, response.Errors, 3) {
+		assert.Contains(t, response.Errors[0].Message, "status 502")
+		assert.Contains(t, response.Errors[2].Message, "status 500")
+	}
+}
+
+func TestServer_handleReconcileReport(t *testing.T) {
+	// Create mock upstream serving a changed post
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"userId": 1, "id": 1, "title": "Edited upstream", "body": "Test body"}`))
+	}))
+	defer upstream.Close()
+
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 100, 0).Return(makePosts(1, 1), nil)
+
+	ingestor := ingestion.NewService(config.IngestionConfig{
+		APIEndpoint:         upstream.URL,
+		Timeout:             30 * time.Second,
+		ReconcileSampleRate: 1,
+	}, mockStorage)
+	s := NewServer(config.ServerConfig{}, mockStorage, WithIngestor(ingestor))
+
+	// Test no report before the first reconciliation
+	req := httptest.NewRequest(http.MethodGet, "/reconcile/report", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	_, err := ingestor.Reconcile(context.Background())
+	assert.NoError(t, err)
+
+	// Test the report lists the mismatch
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report models.ReconcileReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Sampled)
+	assert.Equal(t, []models.ReconcileMismatch{{ID: 1, Fields: []string{"title"}}}, report.Mismatches)
+}
+
+func TestServer_handlePosts_Category(t *testing.T) {
+	// Create mock storage holding posts in the requested category
+	posts := makePosts(1, 2)
+	for i := range posts {
+		posts[i].Category = "food"
+	}
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPostsByCategory", mock.Anything, "food", 10, 0).Return(posts, nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage, WithCategories([]string{"food", "news", "uncategorized"}))
+
+	// Test a valid category
+	req := httptest.NewRequest(http.MethodGet, "/posts?category=food", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"count":2`)
+	assert.Contains(t, rec.Body.String(), `"category":"food"`)
+	mockStorage.AssertExpectations(t)
+
+	// Test invalid requests
+	for _, query := range []string{
+		"category=sports",
+		"category=food&ingestedFrom=2024-01-15T00:00:00Z&ingestedTo=2024-01-16T00:00:00Z",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/posts?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestServer_handlePosts_MaxPageLimit(t *testing.T) {
+	// Create mock storage expecting the capped limit
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 100, 0).Return(makePosts(1, 100), nil).Once()
+
+	s := NewServer(config.ServerConfig{MaxPageLimit: 100}, mockStorage)
+
+	// Test an oversized limit is reduced to the maximum
+	req := httptest.NewRequest(http.MethodGet, "/posts?limit=1000000000", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Posts []models.TransformedPost `json:"posts"`
+		Limit int                      `json:"limit"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body.Posts, 100)
+	assert.Equal(t, 100, body.Limit)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handlePosts_DefaultLimit(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 25, 0).Return(makePosts(1, 25), nil).Once()
+	mockStorage.On("GetPosts", mock.Anything, 5, 0).Return(makePosts(1, 5), nil).Once()
+	mockStorage.On("GetPosts", mock.Anything, 50, 0).Return(makePosts(1, 50), nil).Once()
+
+	s := NewServer(config.ServerConfig{DefaultLimit: 25, MaxPageLimit: 50}, mockStorage)
+
+	// Test the configured default applies when limit is omitted, and an
+	// explicit limit still wins up to the maximum
+	for query, want := range map[string]int{"": 25, "?limit=5": 5, "?limit=500": 50} {
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts"+query, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), fmt.Sprintf(`"limit":%d`, want), query)
+	}
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handlePosts_RunID(t *testing.T) {
+	posts := makePosts(1, 2)
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPostsByRunID", mock.Anything, "run-1", 10, 0).Return(posts, nil).Once()
+
+	s := NewServer(config.ServerConfig{}, mockStorage, WithCategories([]string{"news"}))
+
+	// Test the filter reads the run's posts
+	req := httptest.NewRequest(http.MethodGet, "/posts?runId=run-1", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Posts []models.TransformedPost `json:"posts"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body.Posts, 2)
+	mockStorage.AssertExpectations(t)
+
+	// Test it can't be combined with the other filters
+	for _, query := range []string{"runId=run-1&category=news", "runId=run-1&ingestedFrom=2024-01-01T00:00:00Z&ingestedTo=2024-01-02T00:00:00Z"} {
+		req := httptest.NewRequest(http.MethodGet, "/posts?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestServer_FieldNaming(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 2), nil)
+	mockStorage.On("GetPostByID", mock.Anything, 1).Return(&makePosts(1, 1)[0], nil)
+
+	s := NewServer(config.ServerConfig{FieldNaming: "snake_case"}, mockStorage)
+
+	// Test listed and single posts use the configured convention
+	for _, path := range []string{"/posts", "/posts/1"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"user_id":1`, path)
+		assert.Contains(t, rec.Body.String(), `"ingested_at"`, path)
+		assert.NotContains(t, rec.Body.String(), `"userId"`, path)
+	}
+}
+
+func TestServer_handlePosts_MaxOffset(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 100).Return(makePosts(101, 10), nil).Once()
+	mockStorage.On("ExportPosts", mock.Anything, time.Time{}).Return(makePosts(1, 105), nil).Once()
+
+	s := NewServer(config.ServerConfig{MaxOffset: 100}, mockStorage)
+
+	// Test an offset at the maximum is served
+	req := httptest.NewRequest(http.MethodGet, "/posts?offset=100", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Test a deeper offset is rejected, before storage is read
+	req = httptest.NewRequest(http.MethodGet, "/posts?offset=101", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "exceeds the maximum of 100")
+	assert.Contains(t, rec.Body.String(), "format=ndjson")
+
+	// Test the single-pass stream it points at accepts the offset
+	req = httptest.NewRequest(http.MethodGet, "/posts?offset=101&format=ndjson", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 4, strings.Count(rec.Body.String(), "\n"))
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_handlePostByID_Source(t *testing.T) {
+	// Create mock storage keyed by source and ID
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPostByID", mock.MatchedBy(func(ctx context.Context) bool {
+		return storage.SourceFrom(ctx) == "source_a"
+	}), 1).Return(&makePosts(1, 1)[0], nil).Once()
+	mockStorage.On("GetPostByID", mock.MatchedBy(func(ctx context.Context) bool {
+		return storage.SourceFrom(ctx) == ""
+	}), 1).Return((*models.TransformedPost)(nil), storage.ErrSourceRequired).Once()
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	// Test the source query parameter addresses the post
+	req := httptest.NewRequest(http.MethodGet, "/posts/1?source=source_a", nil)
+	rec := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Test a missing source is the client's error
+	req = httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	rec = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestServer_PostsRouting(t *testing.T) {
+	mockStorage := new(MockStorage)
+	mockStorage.On("GetPosts", mock.Anything, 10, 0).Return(makePosts(1, 2), nil)
+	mockStorage.On("GetPostByID", mock.Anything, 5).Return(&makePosts(5, 1)[0], nil)
+
+	s := NewServer(config.ServerConfig{}, mockStorage)
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"/posts", http.StatusOK, `"count":2`},
+		{"/posts/", http.StatusOK, `"count":2`},
+		{"/posts/5", http.StatusOK, `"id":5`},
+		{"/posts/abc", http.StatusBadRequest, "Invalid post ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+	mockStorage.AssertNumberOfCalls(t, "GetPosts", 2)
+	mockStorage.AssertNumberOfCalls(t, "GetPostByID", 1)
+}
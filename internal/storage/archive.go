@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// S3Archiver writes each ingestion run's posts to S3 as a Parquet file,
+// partitioned by date for data lake queries
+type S3Archiver struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+	now    func() time.Time
+}
+
+// NewRunArchiver creates the archiver writing to cfg.ArchiveBucket
+func NewRunArchiver(cfg config.StorageConfig) (*S3Archiver, error) {
+	awsConfig := &aws.Config{Region: aws.String(cfg.Region)}
+	if cfg.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(cfg.Endpoint)
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+	return NewS3Archiver(s3.New(sess), cfg.ArchiveBucket, cfg.ArchivePrefix), nil
+}
+
+// NewS3Archiver creates an archiver writing under prefix in bucket
+func NewS3Archiver(client s3iface.S3API, bucket, prefix string) *S3Archiver {
+	return &S3Archiver{client: client, bucket: bucket, prefix: prefix, now: time.Now}
+}
+
+// ArchiveRun writes posts to <prefix>/date=<YYYY-MM-DD>/<runID>.parquet
+func (a *S3Archiver) ArchiveRun(ctx context.Context, runID string, posts []models.TransformedPost) error {
+	key := path.Join(a.prefix, "date="+a.now().UTC().Format("2006-01-02"), runID+".parquet")
+	_, err := a.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(encodeParquet(posts)),
+		ContentType: aws.String("application/vnd.apache.parquet"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
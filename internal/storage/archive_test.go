@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestS3Archiver_ArchiveRun(t *testing.T) {
+	mockS3 := NewMockS3()
+	archiver := NewS3Archiver(mockS3, "lake", "posts/")
+	archiver.now = func() time.Time { return time.Date(2026, 10, 14, 23, 30, 0, 0, time.UTC) }
+
+	created := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	first := []models.TransformedPost{newTestPost(1, "one"), newTestPost(2, "two"), newTestPost(3, "three")}
+	first[1].CreatedAt = &created
+	first[1].User = map[string]any{"name": "Leanne Graham"}
+	second := []models.TransformedPost{newTestPost(4, "four")}
+
+	ctx := context.Background()
+	require.NoError(t, archiver.ArchiveRun(ctx, "run-1", first))
+	require.NoError(t, archiver.ArchiveRun(ctx, "run-2", second))
+
+	// Test each run is its own file under the day's partition
+	require.Len(t, mockS3.objects, 2)
+	for key, rows := range map[string]int64{
+		"lake/posts/date=2026-10-14/run-1.parquet": 3,
+		"lake/posts/date=2026-10-14/run-2.parquet": 1,
+	} {
+		data, ok := mockS3.objects[key]
+		require.True(t, ok, key)
+
+		numRows, columns := readParquetFooter(t, data)
+		assert.Equal(t, rows, numRows, key)
+		assert.Equal(t, []string{"schema", "user_id", "id", "title", "body", "created_at", "ingested_at",
+			"source", "original_title", "category", "body_ref", "body_encoding", "version", "run_id",
+			"deleted", "deleted_at", "user"}, columns)
+	}
+}
+
+// readParquetFooter decodes the row count and schema element names from a
+// Parquet file's Thrift compact FileMetaData
+func readParquetFooter(t *testing.T, data []byte) (int64, []string) {
+	require.GreaterOrEqual(t, len(data), 12)
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := &thriftReader{data: data[len(data)-8-size : len(data)-8]}
+
+	var numRows int64
+	var names []string
+	r.readStruct(func(id int16, kind byte) bool {
+		switch id {
+		case 2: // schema
+			_, count := r.listHeader()
+			for i := 0; i < count; i++ {
+				r.readStruct(func(id int16, kind byte) bool {
+					if id != 4 {
+						return false
+					}
+					names = append(names, string(r.bytes()))
+					return true
+				})
+			}
+			return true
+		case 3: // num_rows
+			numRows = r.int()
+			return true
+		}
+		return false
+	})
+	return numRows, names
+}
+
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) int() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) bytes() []byte {
+	n := int(r.uvarint())
+	r.pos += n
+	return r.data[r.pos-n : r.pos]
+}
+
+func (r *thriftReader) listHeader() (byte, int) {
+	header := r.data[r.pos]
+	r.pos++
+	count := int(header >> 4)
+	if count == 15 {
+		count = int(r.uvarint())
+	}
+	return header & 0x0f, count
+}
+
+// readStruct calls visit for each field, skipping those it doesn't consume
+func (r *thriftReader) readStruct(visit func(id int16, kind byte) bool) {
+	var last int16
+	for {
+		header := r.data[r.pos]
+		r.pos++
+		if header == 0 {
+			return
+		}
+		kind := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.int())
+		}
+		if !visit(last, kind) {
+			r.skip(kind)
+		}
+	}
+}
+
+func (r *thriftReader) skip(kind byte) {
+	switch kind {
+	case 3:
+		r.pos++
+	case 4, 5, 6:
+		r.uvarint()
+	case 7:
+		r.pos += 8
+	case 8:
+		r.bytes()
+	case 9, 10:
+		elem, count := r.listHeader()
+		for i := 0; i < count; i++ {
+			r.skip(elem)
+		}
+	case 12:
+		r.readStruct(func(int16, byte) bool { return false })
+	}
+}
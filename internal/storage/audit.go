@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// defaultActor is recorded for writes made outside an API request
+const defaultActor = "ingestion"
+
+type auditActorKey struct{}
+
+type auditActor struct {
+	actor     string
+	requestID string
+}
+
+// WithAuditActor returns a context attributing writes made under it to actor
+func WithAuditActor(ctx context.Context, actor, requestID string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, auditActor{actor: actor, requestID: requestID})
+}
+
+// AuditActor returns the actor and request ID writes under ctx are attributed to
+func AuditActor(ctx context.Context) (string, string) {
+	if a, ok := ctx.Value(auditActorKey{}).(auditActor); ok {
+		return a.actor, a.requestID
+	}
+	return defaultActor, ""
+}
+
+// AuditSink persists audit records
+type AuditSink interface {
+	WriteAudit(ctx context.Context, record models.AuditRecord) error
+}
+
+// NewAuditSink creates the audit sink selected by cfg.AuditSink: "file"
+// appends JSON lines to AuditFile, "dynamodb" writes to AuditTable
+func NewAuditSink(cfg config.StorageConfig) (AuditSink, error) {
+	switch cfg.AuditSink {
+	case "file":
+		if cfg.AuditFile == "" {
+			return nil, fmt.Errorf("AUDIT_FILE is required for the file audit sink")
+		}
+		file, err := os.OpenFile(cfg.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit file: %w", err)
+		}
+		return NewJSONAuditSink(file), nil
+	case "dynamodb":
+		awsConfig := &aws.Config{Region: aws.String(cfg.Region)}
+		if cfg.Endpoint != "" {
+			awsConfig.Endpoint = aws.String(cfg.Endpoint)
+		}
+		sess, err := session.NewSession(awsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS session: %w", err)
+		}
+		return NewDynamoDBAuditSink(dynamodb.New(sess), cfg.AuditTable), nil
+	default:
+		return nil, fmt.Errorf("unsupported audit sink: %s", cfg.AuditSink)
+	}
+}
+
+// JSONAuditSink writes each record as a JSON line
+type JSONAuditSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONAuditSink creates a sink writing to out
+func NewJSONAuditSink(out io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{encoder: json.NewEncoder(out)}
+}
+
+// WriteAudit writes one JSON line
+func (s *JSONAuditSink) WriteAudit(ctx context.Context, record models.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(record)
+}
+
+// DynamoDBAuditSink writes records to a table keyed by a unique record ID
+type DynamoDBAuditSink struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+}
+
+// NewDynamoDBAuditSink creates a sink writing to table
+func NewDynamoDBAuditSink(client dynamodbiface.DynamoDBAPI, table string) *DynamoDBAuditSink {
+	return &DynamoDBAuditSink{client: client, table: table}
+}
+
+// WriteAudit stores the record under an ID derived from its timestamp
+func (s *DynamoDBAuditSink) WriteAudit(ctx context.Context, record models.AuditRecord) error {
+	item, err := dynamodbattribute.MarshalMap(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	item["id"] = &dynamodb.AttributeValue{S: aws.String(strconv.FormatInt(record.Time.UnixNano(), 10) + "-" + record.Operation)}
+
+	_, err = s.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item:      item,
+	})
+	return err
+}
+
+// AuditedStorage records every write to an AuditSink. Reads pass straight
+// through to the wrapped Storage.
+type AuditedStorage struct {
+	Storage
+	sink AuditSink
+	now  func() time.Time
+}
+
+// NewAuditedStorage wraps next, auditing its writes to sink
+func NewAuditedStorage(next Storage, sink AuditSink) *AuditedStorage {
+	return &AuditedStorage{Storage: next, sink: sink, now: time.Now}
+}
+
+// audit records an operation on ids, including its error if it failed. A
+// write that can't be audited is reported as failed.
+func (a *AuditedStorage) audit(ctx context.Context, operation string, ids []int, opErr error) error {
+	actor, requestID := AuditActor(ctx)
+	record := models.AuditRecord{
+		Time:      a.now().UTC(),
+		Actor:     actor,
+		RequestID: requestID,
+		Operation: operation,
+		IDs:       ids,
+	}
+	if opErr != nil {
+		record.Error = opErr.Error()
+	}
+
+	// Record the write even if the caller gave up on it
+	if err := a.sink.WriteAudit(context.WithoutCancel(ctx), record); err != nil {
+		if opErr != nil {
+			return opErr
+		}
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return opErr
+}
+
+// StorePosts stores posts and audits their IDs
+func (a *AuditedStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return a.audit(ctx, "store_posts", ids, a.Storage.StorePosts(ctx, posts))
+}
+
+// StoreComments stores comments and audits their IDs
+func (a *AuditedStorage) StoreComments(ctx context.Context, comments []models.Comment) error {
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	return a.audit(ctx, "store_comments", ids, a.Storage.StoreComments(ctx, comments))
+}
+
+// DeletePost deletes a post and audits it
+func (a *AuditedStorage) DeletePost(ctx context.Context, id int) error {
+	return a.audit(ctx, "delete_post", []int{id}, a.Storage.DeletePost(ctx, id))
+}
+
+// DeletePosts deletes posts and audits the requested IDs
+func (a *AuditedStorage) DeletePosts(ctx context.Context, ids []int) (int, error) {
+	deleted, err := a.Storage.DeletePosts(ctx, ids)
+	return deleted, a.audit(ctx, "delete_posts", ids, err)
+}
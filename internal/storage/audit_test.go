@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// memoryAuditSink keeps audit records in memory
+type memoryAuditSink struct {
+	records []models.AuditRecord
+	err     error
+}
+
+func (m *memoryAuditSink) WriteAudit(ctx context.Context, record models.AuditRecord) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.records = append(m.records, record)
+	return nil
+}
+
+func TestAuditedStorage_Writes(t *testing.T) {
+	sink := &memoryAuditSink{}
+	audited := NewAuditedStorage(NewStdoutSink(io.Discard), sink)
+	auditedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	audited.now = func() time.Time { return auditedAt }
+
+	// Test a store made by ingestion
+	err := audited.StorePosts(context.Background(), []models.TransformedPost{newTestPost(1, "a"), newTestPost(2, "b")})
+
+	assert.NoError(t, err)
+
+	// Test a delete made through the API, which the backend rejects
+	ctx := WithAuditActor(context.Background(), "api_key:1a2b3c4d", "req-42")
+	err = audited.DeletePost(ctx, 7)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Test a bulk delete
+	_, err = audited.DeletePosts(ctx, []int{3, 4})
+
+	assert.NoError(t, err)
+
+	assert.Equal(t, []models.AuditRecord{
+		{Time: auditedAt, Actor: "ingestion", Operation: "store_posts", IDs: []int{1, 2}},
+		{Time: auditedAt, Actor: "api_key:1a2b3c4d", RequestID: "req-42", Operation: "delete_post", IDs: []int{7}, Error: ErrNotFound.Error()},
+		{Time: auditedAt, Actor: "api_key:1a2b3c4d", RequestID: "req-42", Operation: "delete_posts", IDs: []int{3, 4}},
+	}, sink.records)
+
+	// Test reads are not audited
+	_, err = audited.GetPosts(ctx, 10, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, sink.records, 3)
+}
+
+func TestAuditedStorage_SinkFailure(t *testing.T) {
+	audited := NewAuditedStorage(NewStdoutSink(io.Discard), &memoryAuditSink{err: errors.New("disk full")})
+
+	// Test an unaudited write is reported as failed
+	err := audited.StorePosts(context.Background(), []models.TransformedPost{newTestPost(1, "a")})
+
+	assert.ErrorContains(t, err, "failed to write audit record")
+
+	// Test the operation's own error takes precedence
+	err = audited.DeletePost(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestJSONAuditSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewJSONAuditSink(&out)
+
+	err := sink.WriteAudit(context.Background(), models.AuditRecord{Actor: "ingestion", Operation: "store_posts", IDs: []int{1}})
+	assert.NoError(t, err)
+
+	var record models.AuditRecord
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "store_posts", record.Operation)
+	assert.Equal(t, []int{1}, record.IDs)
+}
+
+func TestDynamoDBAuditSink(t *testing.T) {
+	mockDB := NewMockDynamoDB()
+	sink := NewDynamoDBAuditSink(mockDB, "posts_audit")
+
+	err := sink.WriteAudit(context.Background(), models.AuditRecord{
+		Time:      time.Unix(0, 42),
+		Actor:     "ingestion",
+		Operation: "delete_post",
+		IDs:       []int{5},
+	})
+
+	assert.NoError(t, err)
+	item := mockDB.tables["posts_audit"]["42-delete_post"]
+	if assert.NotNil(t, item) {
+		assert.Equal(t, "ingestion", aws.StringValue(item["actor"].S))
+	}
+}
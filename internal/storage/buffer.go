@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// BufferedStorage accumulates stored posts and writes them to the wrapped
+// Storage in bulk, once size posts are buffered or every interval, whichever
+// comes first. Buffered posts aren't visible to reads until flushed, and
+// are reported to the OnFlush callback once written. A failed flush keeps
+// its posts for the next one; storing a post that is still buffered
+// replaces it, so retried writes don't pile up.
+type BufferedStorage struct {
+	Storage
+	size         int
+	compositeKey bool // Posts are keyed by source and ID, not ID alone
+
+	mu      sync.Mutex // Held while flushing so flushes don't interleave
+	pending []models.TransformedPost
+	index   map[bufferKey]int // Post key -> position in pending
+	onFlush func(posts []models.TransformedPost)
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// bufferKey identifies a buffered post; source is only set with composite keys
+type bufferKey struct {
+	source string
+	id     int
+}
+
+// NewBufferedStorage wraps next, flushing once size posts are buffered and,
+// if interval is positive, every interval. Close flushes what remains. With
+// compositeKey, posts from different sources sharing an ID are buffered
+// separately.
+func NewBufferedStorage(next Storage, size int, interval time.Duration, compositeKey bool) *BufferedStorage {
+	b := &BufferedStorage{
+		Storage:      next,
+		size:         size,
+		compositeKey: compositeKey,
+		index:        make(map[bufferKey]int),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+
+	if interval > 0 {
+		go b.flushEvery(interval)
+	} else {
+		close(b.stopped)
+	}
+	return b
+}
+
+// flushEvery flushes on a timer until Close
+func (b *BufferedStorage) flushEvery(interval time.Duration) {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.Flush(context.Background()) // Failed posts stay buffered for the next tick
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// StorePosts buffers posts, flushing if that fills the buffer. The error, if
+// any, is the flush's.
+func (b *BufferedStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, post := range posts {
+		key := b.key(post.Source, post.ID)
+		if i, ok := b.index[key]; ok {
+			b.pending[i] = post
+			continue
+		}
+		b.index[key] = len(b.pending)
+		b.pending = append(b.pending, post)
+	}
+
+	if len(b.pending) < b.size {
+		return nil
+	}
+	return b.flush(ctx)
+}
+
+func (b *BufferedStorage) key(source string, id int) bufferKey {
+	if b.compositeKey {
+		return bufferKey{source: source, id: id}
+	}
+	return bufferKey{id: id}
+}
+
+// OnFlush sets fn to be called with the posts each flush writes, so callers
+// can tell when buffered posts are durably stored. It runs with the buffer
+// locked, so it must not store posts.
+func (b *BufferedStorage) OnFlush(fn func(posts []models.TransformedPost)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onFlush = fn
+}
+
+// Flush writes the buffered posts to the wrapped storage
+func (b *BufferedStorage) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(ctx)
+}
+
+// flush writes the buffered posts; callers must hold mu
+func (b *BufferedStorage) flush(ctx context.Context) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	err := b.Storage.StorePosts(ctx, b.pending)
+	var skipped *SkippedPostsError
+	if err != nil && !errors.As(err, &skipped) {
+		return err
+	}
+
+	// Skipped posts would fail again, so they're dropped with the rest
+	written := b.pending
+	if skipped != nil {
+		written = b.withoutSkipped(written, skipped.Posts)
+	}
+	b.pending = nil
+	b.index = make(map[bufferKey]int)
+
+	if b.onFlush != nil && len(written) > 0 {
+		b.onFlush(written)
+	}
+	return err
+}
+
+// withoutSkipped returns posts except those storage skipped
+func (b *BufferedStorage) withoutSkipped(posts []models.TransformedPost, skipped []SkippedPost) []models.TransformedPost {
+	keys := make(map[bufferKey]bool, len(skipped))
+	for _, skip := range skipped {
+		keys[b.key(skip.Source, skip.ID)] = true
+	}
+	kept := make([]models.TransformedPost, 0, len(posts))
+	for _, post := range posts {
+		if !keys[b.key(post.Source, post.ID)] {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
+
+// Close stops the flush timer, flushes the remaining posts and closes the
+// wrapped storage
+func (b *BufferedStorage) Close() error {
+	b.once.Do(func() { close(b.stop) })
+	<-b.stopped
+
+	flushErr := b.Flush(context.Background())
+	return errors.Join(flushErr, b.Storage.Close())
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// recordingStorage records each StorePosts call, failing while err is set
+type recordingStorage struct {
+	*StdoutSink
+
+	mu     sync.Mutex
+	writes [][]int // IDs of each write
+	err    error
+	closed bool
+}
+
+func newRecordingStorage() *recordingStorage {
+	return &recordingStorage{StdoutSink: NewStdoutSink(io.Discard)}
+}
+
+func (r *recordingStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	r.writes = append(r.writes, ids)
+	return nil
+}
+
+func (r *recordingStorage) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *recordingStorage) recorded() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]int(nil), r.writes...)
+}
+
+func testPosts(ids ...int) []models.TransformedPost {
+	posts := make([]models.TransformedPost, len(ids))
+	for i, id := range ids {
+		posts[i] = newTestPost(id, "body")
+	}
+	return posts
+}
+
+func TestBufferedStorage_FlushOnSize(t *testing.T) {
+	next := newRecordingStorage()
+	buffered := NewBufferedStorage(next, 3, 0, false)
+	ctx := context.Background()
+
+	// Test posts are held until the buffer fills
+	require.NoError(t, buffered.StorePosts(ctx, testPosts(1, 2)))
+	assert.Empty(t, next.recorded())
+
+	require.NoError(t, buffered.StorePosts(ctx, testPosts(3, 4)))
+	assert.Equal(t, [][]int{{1, 2, 3, 4}}, next.recorded())
+
+	// Test the buffer starts over after a flush
+	require.NoError(t, buffered.StorePosts(ctx, testPosts(5)))
+	assert.Len(t, next.recorded(), 1)
+}
+
+func TestBufferedStorage_FlushOnTime(t *testing.T) {
+	next := newRecordingStorage()
+	buffered := NewBufferedStorage(next, 100, 20*time.Millisecond, false)
+	defer buffered.Close()
+
+	require.NoError(t, buffered.StorePosts(context.Background(), testPosts(1, 2)))
+
+	// Test the timer writes a partly filled buffer
+	assert.Eventually(t, func() bool { return len(next.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []int{1, 2}, next.recorded()[0])
+}
+
+func TestBufferedStorage_FlushOnShutdown(t *testing.T) {
+	next := newRecordingStorage()
+	buffered := NewBufferedStorage(next, 100, time.Hour, false)
+
+	require.NoError(t, buffered.StorePosts(context.Background(), testPosts(1, 2)))
+	assert.Empty(t, next.recorded())
+
+	// Test Close writes what remains and closes the wrapped storage
+	require.NoError(t, buffered.Close())
+
+	assert.Equal(t, [][]int{{1, 2}}, next.recorded())
+	assert.True(t, next.closed)
+}
+
+func TestBufferedStorage_FailedFlushKeepsPosts(t *testing.T) {
+	next := newRecordingStorage()
+	next.err = assert.AnError
+	buffered := NewBufferedStorage(next, 2, 0, false)
+	ctx := context.Background()
+
+	// Test a failed flush reports the error and keeps the posts
+	assert.ErrorIs(t, buffered.StorePosts(ctx, testPosts(1, 2)), assert.AnError)
+
+	// Test a retried write replaces the buffered posts rather than adding to them
+	next.mu.Lock()
+	next.err = nil
+	next.mu.Unlock()
+	require.NoError(t, buffered.StorePosts(ctx, testPosts(1, 2)))
+
+	assert.Equal(t, [][]int{{1, 2}}, next.recorded())
+}
+
+func TestBufferedStorage_OnFlush(t *testing.T) {
+	next := newRecordingStorage()
+	next.err = assert.AnError
+	buffered := NewBufferedStorage(next, 100, 0, false)
+	ctx := context.Background()
+
+	var flushed []int
+	buffered.OnFlush(func(posts []models.TransformedPost) {
+		for _, post := range posts {
+			flushed = append(flushed, post.ID)
+		}
+	})
+
+	// Test buffered posts aren't reported until a flush writes them
+	require.NoError(t, buffered.StorePosts(ctx, testPosts(1, 2)))
+	assert.ErrorIs(t, buffered.Flush(ctx), assert.AnError)
+	assert.Empty(t, flushed)
+
+	next.mu.Lock()
+	next.err = nil
+	next.mu.Unlock()
+	require.NoError(t, buffered.Flush(ctx))
+
+	assert.Equal(t, []int{1, 2}, flushed)
+}
+
+func TestBufferedStorage_CompositeKey(t *testing.T) {
+	ctx := context.Background()
+	posts := testPosts(1, 1)
+	posts[1].Source = "other_api"
+
+	// Test posts sharing an ID replace each other when keyed by ID alone
+	next := newRecordingStorage()
+	buffered := NewBufferedStorage(next, 100, 0, false)
+	require.NoError(t, buffered.StorePosts(ctx, posts))
+	require.NoError(t, buffered.Flush(ctx))
+	assert.Equal(t, [][]int{{1}}, next.recorded())
+
+	// Test they're both kept when keyed by source and ID
+	next = newRecordingStorage()
+	buffered = NewBufferedStorage(next, 100, 0, true)
+	require.NoError(t, buffered.StorePosts(ctx, posts))
+	require.NoError(t, buffered.Flush(ctx))
+	assert.Equal(t, [][]int{{1, 1}}, next.recorded())
+}
+
+func TestBufferedStorage_CompositeKey_Skipped(t *testing.T) {
+	next := newRecordingStorage()
+	next.err = &SkippedPostsError{Posts: []SkippedPost{{ID: 1, Source: "other_api", Err: assert.AnError}}}
+	buffered := NewBufferedStorage(next, 100, 0, true)
+	ctx := context.Background()
+
+	var flushed []string
+	buffered.OnFlush(func(posts []models.TransformedPost) {
+		for _, post := range posts {
+			flushed = append(flushed, post.Source)
+		}
+	})
+
+	// Test only the skipped source's post is left out of the flush
+	posts := testPosts(1, 1)
+	posts[1].Source = "other_api"
+	require.NoError(t, buffered.StorePosts(ctx, posts))
+	assert.Error(t, buffered.Flush(ctx))
+
+	assert.Equal(t, []string{"placeholder_api"}, flushed)
+}
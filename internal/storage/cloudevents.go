@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsFormat      = "cloudevents"
+)
+
+// cloudEvent is a CloudEvents 1.0 envelope in the structured JSON format
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            any       `json:"data"`
+}
+
+// cloudEvents builds envelopes for a sink's posts and comments
+type cloudEvents struct {
+	source      string
+	postType    string
+	commentType string
+}
+
+// post wraps a post. The ID is derived from the post and its ingestion time
+// so a redelivered event keeps its ID and consumers can deduplicate it.
+func (c *cloudEvents) post(post models.TransformedPost) cloudEvent {
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              fmt.Sprintf("post-%d-%d", post.ID, post.IngestedAt.UnixNano()),
+		Source:          c.source,
+		Type:            c.postType,
+		Subject:         fmt.Sprintf("posts/%d", post.ID),
+		Time:            post.IngestedAt,
+		DataContentType: "application/json",
+		Data:            post,
+	}
+}
+
+// comment wraps a comment, identified like post
+func (c *cloudEvents) comment(comment models.Comment) cloudEvent {
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              fmt.Sprintf("comment-%d-%d", comment.ID, comment.IngestedAt.UnixNano()),
+		Source:          c.source,
+		Type:            c.commentType,
+		Subject:         fmt.Sprintf("posts/%d/comments/%d", comment.PostID, comment.ID),
+		Time:            comment.IngestedAt,
+		DataContentType: "application/json",
+		Data:            comment,
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// gzipEncoding marks a body stored as base64-encoded gzip
+const gzipEncoding = "gzip"
+
+// bodyCodec compresses post bodies on write and restores them on read.
+// Backends share it so compressed posts stay readable whichever one stored them.
+type bodyCodec struct {
+	threshold int // Body size in bytes above which bodies are compressed
+}
+
+// newBodyCodec returns a codec for the storage config, or nil when compression is disabled
+func newBodyCodec(cfg config.StorageConfig) *bodyCodec {
+	if !cfg.CompressBodies {
+		return nil
+	}
+	return &bodyCodec{threshold: cfg.CompressThreshold}
+}
+
+// encode compresses the body in place when it exceeds the threshold.
+// Bodies that do not shrink are left as they are.
+func (c *bodyCodec) encode(post *models.TransformedPost) error {
+	if c == nil || post.BodyEncoding != "" || len(post.Body) <= c.threshold {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(post.Body)); err != nil {
+		return fmt.Errorf("failed to compress body of post %d: %w", post.ID, err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress body of post %d: %w", post.ID, err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(encoded) >= len(post.Body) {
+		return nil
+	}
+
+	post.Body = encoded
+	post.BodyEncoding = gzipEncoding
+	return nil
+}
+
+// decodeBody restores a compressed body in place. It runs regardless of
+// whether compression is currently enabled so previously compressed posts
+// remain readable after the option is turned off.
+func decodeBody(post *models.TransformedPost) error {
+	switch post.BodyEncoding {
+	case "":
+		return nil
+	case gzipEncoding:
+	default:
+		return fmt.Errorf("unsupported body encoding for post %d: %s", post.ID, post.BodyEncoding)
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(post.Body)
+	if err != nil {
+		return fmt.Errorf("failed to decode body of post %d: %w", post.ID, err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return fmt.Errorf("failed to decompress body of post %d: %w", post.ID, err)
+	}
+	defer zr.Close()
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("failed to decompress body of post %d: %w", post.ID, err)
+	}
+
+	post.Body = string(body)
+	post.BodyEncoding = ""
+	return nil
+}
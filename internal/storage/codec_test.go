@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyCodec_RoundTrip(t *testing.T) {
+	codec := &bodyCodec{threshold: 32}
+
+	tests := []struct {
+		name       string
+		body       string
+		compressed bool
+	}{
+		{"below threshold", "short body", false},
+		{"repetitive", strings.Repeat("lorem ipsum dolor sit amet ", 200), true},
+		{"unicode", strings.Repeat("héllo wörld ✓ 日本語 ", 100), true},
+		{"multiline", strings.Repeat("line one\nline two\r\n\ttabbed\n", 50), true},
+		{"incompressible", "q8Zr1xLw0pTn4KvYb7HcOe2mGdJs9AaUf5Ri3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := newTestPost(1, tt.body)
+
+			// Test encode compresses only when worthwhile
+			err := codec.encode(&post)
+
+			assert.NoError(t, err)
+			if tt.compressed {
+				assert.Equal(t, gzipEncoding, post.BodyEncoding)
+				assert.Less(t, len(post.Body), len(tt.body))
+			} else {
+				assert.Empty(t, post.BodyEncoding)
+				assert.Equal(t, tt.body, post.Body)
+			}
+
+			// Test decodeBody restores the original body
+			err = decodeBody(&post)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.body, post.Body)
+			assert.Empty(t, post.BodyEncoding)
+		})
+	}
+}
+
+func TestBodyCodec_Disabled(t *testing.T) {
+	var codec *bodyCodec
+	body := strings.Repeat("x", 4096)
+	post := newTestPost(1, body)
+
+	err := codec.encode(&post)
+
+	assert.NoError(t, err)
+	assert.Equal(t, body, post.Body)
+	assert.Empty(t, post.BodyEncoding)
+}
+
+func TestDecodeBody_Invalid(t *testing.T) {
+	// Test unknown encoding
+	post := newTestPost(1, "abc")
+	post.BodyEncoding = "zstd"
+
+	assert.Error(t, decodeBody(&post))
+
+	// Test corrupt payload
+	post = newTestPost(1, "not base64!")
+	post.BodyEncoding = gzipEncoding
+
+	assert.Error(t, decodeBody(&post))
+}
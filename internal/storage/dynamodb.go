@@ -2,22 +2,74 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/cyderes/data-ingestion-service/internal/config"
 	"github.com/cyderes/data-ingestion-service/internal/models"
 )
 
+// maxPrealloc bounds the posts slice allocated up front, so a large limit
+// only costs memory for the posts actually found
+const maxPrealloc = 1000
+
+// exportPageSize is the number of items ExportPosts reads per Scan call
+const exportPageSize = 100
+
+// DynamoDB batch API limits
+const (
+	batchWriteSize = 25
+	batchGetSize   = 100
+)
+
+// The ingestion time index needs a partition key shared by every post, so
+// each item carries a constant record_type alongside a numeric ingested_ts
+// (ingested_at is RFC3339 text, which doesn't sort chronologically once
+// fractional seconds vary).
+const (
+	ingestedAtIndex = "ingested_at-index"
+	postRecordType  = "post"
+)
+
+// categoryIndex is a sparse index over categorized posts, ordered by ingestion
+const categoryIndex = "category-index"
+
 // DynamoDBStorage implements Storage interface using AWS DynamoDB
 type DynamoDBStorage struct {
-	client    *dynamodb.DynamoDB
-	tableName string
+	client       dynamodbiface.DynamoDBAPI
+	tableName    string
+	sourceTables map[string]string // Source name -> table for writes
+	softDelete   bool
+	versionPosts bool
+	compositeKey bool // Posts are keyed by source (partition) and id (sort)
+	scanSegments int  // Parallel Scan segments for GetPosts; 1 or less scans sequentially
+
+	// Large body offloading
+	s3Client         s3iface.S3API
+	offloadBucket    string
+	offloadThreshold int
+
+	codec *bodyCodec
+
+	// marshal encodes items; nil uses dynamodbattribute.MarshalMap
+	marshal func(in interface{}) (map[string]*dynamodb.AttributeValue, error)
 }
 
 // NewDynamoDBStorage creates a new DynamoDB storage instance
@@ -38,128 +90,1257 @@ func NewDynamoDBStorage(cfg config.StorageConfig) (*DynamoDBStorage, error) {
 
 	client := dynamodb.New(sess)
 	storage := &DynamoDBStorage{
-		client:    client,
-		tableName: cfg.TableName,
+		client:       client,
+		tableName:    cfg.TableName,
+		sourceTables: cfg.SourceTables,
+		softDelete:   cfg.SoftDelete,
+		versionPosts: cfg.VersionPosts,
+		compositeKey: cfg.CompositeKey,
+		scanSegments: cfg.ScanSegments,
+		codec:        newBodyCodec(cfg),
 	}
 
-	// Create table if it doesn't exist (for local testing)
-	if err := storage.ensureTable(); err != nil {
-		return nil, fmt.Errorf("failed to ensure table exists: %w", err)
+	if cfg.OffloadLargeBodies {
+		if cfg.OffloadBucket == "" {
+			return nil, fmt.Errorf("OFFLOAD_BUCKET is required when large body offloading is enabled")
+		}
+		storage.s3Client = s3.New(sess)
+		storage.offloadBucket = cfg.OffloadBucket
+		storage.offloadThreshold = cfg.OffloadThreshold
+	}
+
+	// Create tables if they don't exist (for local testing)
+	for _, table := range storage.tables() {
+		if err := storage.ensureTable(table); err != nil {
+			return nil, fmt.Errorf("failed to ensure table %s exists: %w", table, err)
+		}
+	}
+	if err := storage.ensureCommentsTable(); err != nil {
+		return nil, fmt.Errorf("failed to ensure table %s exists: %w", storage.commentsTable(), err)
 	}
 
 	return storage, nil
 }
 
-// ensureTable creates the DynamoDB table if it doesn't exist
-func (d *DynamoDBStorage) ensureTable() error {
+// indexPollInterval is how often ensureTable checks on an index it added
+var indexPollInterval = 10 * time.Second
+
+// ensureTable creates the DynamoDB table if it doesn't exist, and adds the
+// post indexes to one created before they were introduced
+func (d *DynamoDBStorage) ensureTable(tableName string) error {
 	// Check if table exists
-	_, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{
-		TableName: aws.String(d.tableName),
+	output, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
 	})
 
 	if err == nil {
-		return nil // Table already exists
+		return d.ensureIndexes(tableName, output.Table)
 	}
 
 	// Create table
+	keySchema := []*dynamodb.KeySchemaElement{
+		{
+			AttributeName: aws.String("id"),
+			KeyType:       aws.String("HASH"),
+		},
+	}
+	if d.compositeKey {
+		keySchema = []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String("source"),
+				KeyType:       aws.String("HASH"),
+			},
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       aws.String("RANGE"),
+			},
+		}
+	}
 	input := &dynamodb.CreateTableInput{
-		TableName: aws.String(d.tableName),
-		KeySchema: []*dynamodb.KeySchemaElement{
+		TableName: aws.String(tableName),
+		KeySchema: keySchema,
+		AttributeDefinitions: append([]*dynamodb.AttributeDefinition{
 			{
 				AttributeName: aws.String("id"),
+				AttributeType: aws.String("N"),
+			},
+		}, indexAttributes...),
+		GlobalSecondaryIndexes: postIndexes(),
+		BillingMode:            aws.String("PAY_PER_REQUEST"),
+	}
+	if d.compositeKey {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String("source"),
+			AttributeType: aws.String("S"),
+		})
+	}
+
+	_, err = d.client.CreateTable(input)
+	if err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+
+	// Wait for table to be created
+	return d.client.WaitUntilTableExists(&dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	})
+}
+
+// indexAttributes defines the key attributes of the post indexes
+var indexAttributes = []*dynamodb.AttributeDefinition{
+	{
+		AttributeName: aws.String("record_type"),
+		AttributeType: aws.String("S"),
+	},
+	{
+		AttributeName: aws.String("ingested_ts"),
+		AttributeType: aws.String("N"),
+	},
+	{
+		AttributeName: aws.String("category"),
+		AttributeType: aws.String("S"),
+	},
+}
+
+// postIndexes returns the global secondary indexes of a post table
+func postIndexes() []*dynamodb.GlobalSecondaryIndex {
+	return []*dynamodb.GlobalSecondaryIndex{
+		{
+			IndexName: aws.String(ingestedAtIndex),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{
+					AttributeName: aws.String("record_type"),
+					KeyType:       aws.String("HASH"),
+				},
+				{
+					AttributeName: aws.String("ingested_ts"),
+					KeyType:       aws.String("RANGE"),
+				},
+			},
+			Projection: &dynamodb.Projection{
+				ProjectionType: aws.String("ALL"),
+			},
+		},
+		{
+			IndexName: aws.String(categoryIndex),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{
+					AttributeName: aws.String("category"),
+					KeyType:       aws.String("HASH"),
+				},
+				{
+					AttributeName: aws.String("ingested_ts"),
+					KeyType:       aws.String("RANGE"),
+				},
+			},
+			Projection: &dynamodb.Projection{
+				ProjectionType: aws.String("ALL"),
+			},
+		},
+	}
+}
+
+// ensureIndexes adds the post indexes an existing table lacks. DynamoDB
+// creates one index per UpdateTable call and backfills it from the table's
+// items, so each is waited on until active before the next is added; on a
+// large table this can hold up startup for a while.
+func (d *DynamoDBStorage) ensureIndexes(tableName string, table *dynamodb.TableDescription) error {
+	if table == nil {
+		table = &dynamodb.TableDescription{}
+	}
+	existing := make(map[string]bool)
+	for _, index := range table.GlobalSecondaryIndexes {
+		existing[aws.StringValue(index.IndexName)] = true
+	}
+
+	for _, index := range postIndexes() {
+		name := aws.StringValue(index.IndexName)
+		if existing[name] {
+			continue
+		}
+
+		create := &dynamodb.CreateGlobalSecondaryIndexAction{
+			IndexName:  index.IndexName,
+			KeySchema:  index.KeySchema,
+			Projection: index.Projection,
+		}
+		if throughput := table.ProvisionedThroughput; throughput != nil && aws.Int64Value(throughput.ReadCapacityUnits) > 0 {
+			// A provisioned table's indexes need their own capacity
+			create.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+				ReadCapacityUnits:  throughput.ReadCapacityUnits,
+				WriteCapacityUnits: throughput.WriteCapacityUnits,
+			}
+		}
+		_, err := d.client.UpdateTable(&dynamodb.UpdateTableInput{
+			TableName:                   aws.String(tableName),
+			AttributeDefinitions:        indexAttributes,
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{Create: create}},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add index %s: %w", name, err)
+		}
+		if err := d.waitForIndex(tableName, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForIndex waits until the named index of the table is active
+func (d *DynamoDBStorage) waitForIndex(tableName, indexName string) error {
+	for {
+		output, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{
+			TableName: aws.String(tableName),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe table: %w", err)
+		}
+		for _, index := range output.Table.GlobalSecondaryIndexes {
+			if aws.StringValue(index.IndexName) == indexName && aws.StringValue(index.IndexStatus) == dynamodb.IndexStatusActive {
+				return nil
+			}
+		}
+		time.Sleep(indexPollInterval)
+	}
+}
+
+// ensureCommentsTable creates the comments table if it doesn't exist. Comments
+// are keyed by post so a post's comments are read with a single query.
+func (d *DynamoDBStorage) ensureCommentsTable() error {
+	table := d.commentsTable()
+	if _, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)}); err == nil {
+		return nil // Table already exists
+	}
+
+	_, err := d.client.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String(table),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String("postId"),
 				KeyType:       aws.String("HASH"),
 			},
+			{
+				AttributeName: aws.String("id"),
+				KeyType:       aws.String("RANGE"),
+			},
 		},
 		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String("postId"),
+				AttributeType: aws.String("N"),
+			},
 			{
 				AttributeName: aws.String("id"),
 				AttributeType: aws.String("N"),
 			},
 		},
 		BillingMode: aws.String("PAY_PER_REQUEST"),
-	}
-
-	_, err = d.client.CreateTable(input)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
 
-	// Wait for table to be created
 	return d.client.WaitUntilTableExists(&dynamodb.DescribeTableInput{
-		TableName: aws.String(d.tableName),
+		TableName: aws.String(table),
 	})
 }
 
+// postKey returns the primary key of the post with id from source
+func (d *DynamoDBStorage) postKey(source string, id int) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{
+		"id": {N: aws.String(strconv.Itoa(id))},
+	}
+	if d.compositeKey {
+		key["source"] = &dynamodb.AttributeValue{S: aws.String(source)}
+	}
+	return key
+}
+
+// keySource returns the source ctx addresses posts from, which composite
+// keys require
+func (d *DynamoDBStorage) keySource(ctx context.Context) (string, error) {
+	source := SourceFrom(ctx)
+	if d.compositeKey && source == "" {
+		return "", ErrSourceRequired
+	}
+	return source, nil
+}
+
+// commentsTable returns the table holding comments
+func (d *DynamoDBStorage) commentsTable() string {
+	return d.tableName + "_comments"
+}
+
+// tables returns every table holding posts, without duplicates
+func (d *DynamoDBStorage) tables() []string {
+	tables := []string{d.tableName}
+	for _, table := range d.sourceTables {
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// tableFor returns the table that stores posts from the given source
+func (d *DynamoDBStorage) tableFor(source string) string {
+	if table, ok := d.sourceTables[source]; ok {
+		return table
+	}
+	return d.tableName
+}
+
+// lookupTables returns the tables a post addressed under ctx may be in: its
+// source's table when ctx names one, otherwise every post table
+func (d *DynamoDBStorage) lookupTables(ctx context.Context) []string {
+	if source := SourceFrom(ctx); source != "" {
+		return []string{d.tableFor(source)}
+	}
+	return d.tables()
+}
+
+// pageFunc reads the page of a Scan or Query starting after startKey
+type pageFunc func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error)
+
+// nextTableKey stands in for the LastEvaluatedKey when a table is exhausted
+// but more remain, so collectItems keeps paging
+var nextTableKey = map[string]*dynamodb.AttributeValue{"": {NULL: aws.Bool(true)}}
+
+// eachTable pages through pages(table) for every post table in turn, so
+// results run table by table
+func (d *DynamoDBStorage) eachTable(pages func(table string) pageFunc) pageFunc {
+	tables := d.tables()
+	current := 0
+	next := pages(tables[current])
+	return func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error) {
+		if len(startKey) == 1 && startKey[""] != nil {
+			startKey = nil // The previous table was exhausted
+		}
+		result, err := next(startKey, pageLimit)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.lastKey) == 0 && current < len(tables)-1 {
+			current++
+			next = pages(tables[current])
+			result.lastKey = nextTableKey
+		}
+		return result, nil
+	}
+}
+
 // StorePosts stores posts in DynamoDB
 func (d *DynamoDBStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	var skipped []SkippedPost
 	for _, post := range posts {
-		item, err := dynamodbattribute.MarshalMap(post)
+		if err := d.codec.encode(&post); err != nil {
+			return err
+		}
+		if err := d.offloadBody(ctx, &post); err != nil {
+			return err
+		}
+
+		item, err := d.marshalItem(post)
 		if err != nil {
-			return fmt.Errorf("failed to marshal post %d: %w", post.ID, err)
+			// One bad post shouldn't cost the rest of the batch
+			skipped = append(skipped, SkippedPost{ID: post.ID, Source: post.Source, Field: d.failingField(post), Err: err})
+			continue
 		}
 
-		_, err = d.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
-			TableName: aws.String(d.tableName),
-			Item:      item,
-		})
+		// Index attributes
+		item["record_type"] = &dynamodb.AttributeValue{S: aws.String(postRecordType)}
+		item["ingested_ts"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(post.IngestedAt.UnixNano(), 10))}
+
+		_, err = d.client.UpdateItemWithContext(ctx, d.storeInput(post, item))
+		var aerr awserr.Error
+		if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return fmt.Errorf("failed to store post %d: %w", post.ID, ErrVersionConflict)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to store post %d: %w", post.ID, err)
 		}
 	}
 
+	if len(skipped) > 0 {
+		return &SkippedPostsError{Posts: skipped}
+	}
 	return nil
 }
 
+func (d *DynamoDBStorage) marshalItem(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	if d.marshal != nil {
+		return d.marshal(in)
+	}
+	return dynamodbattribute.MarshalMap(in)
+}
+
+// failingField marshals the post's fields one at a time and returns the JSON
+// name of the first that fails, or "" if each marshals on its own
+func (d *DynamoDBStorage) failingField(post models.TransformedPost) string {
+	return d.firstFailingField(reflect.ValueOf(post))
+}
+
+func (d *DynamoDBStorage) firstFailingField(v reflect.Value) string {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if name := d.firstFailingField(v.Field(i)); name != "" {
+				return name
+			}
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if _, err := d.marshalItem(map[string]interface{}{name: v.Field(i).Interface()}); err != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// postAttributes are the attributes a post item can have, so a store can
+// remove those the new post leaves out
+var postAttributes = attributeNames(reflect.TypeOf(models.TransformedPost{}))
+
+// attributeNames returns the JSON names of a struct's fields, flattening
+// embedded structs as marshalling does
+func attributeNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, attributeNames(field.Type)...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		if name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// storeInput builds the single UpdateItem that stores a post: it sets the
+// item's attributes and removes the ones it leaves out, as replacing the
+// item would, but keeps first_ingested_ts from the post's first store so
+// re-storing it doesn't move it in ExportPosts snapshots
+func (d *DynamoDBStorage) storeInput(post models.TransformedPost, item map[string]*dynamodb.AttributeValue) *dynamodb.UpdateItemInput {
+	key := d.postKey(post.Source, post.ID)
+	names := expressionNames{}
+	values := map[string]*dynamodb.AttributeValue{}
+	input := &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(d.tableFor(post.Source)),
+		Key:                       key,
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+
+	var sets, removes []string
+	if d.versionPosts {
+		sets = append(sets, d.setVersion(input, names, post))
+		delete(item, "version")
+	}
+	first := names.alias("first_ingested_ts")
+	sets = append(sets, first+" = if_not_exists("+first+", :ingested_ts)")
+
+	attributes := make([]string, 0, len(item))
+	for name := range item {
+		attributes = append(attributes, name)
+	}
+	sort.Strings(attributes)
+	for _, name := range attributes {
+		if key[name] == nil {
+			sets = append(sets, names.alias(name)+" = :"+name)
+			values[":"+name] = item[name]
+		}
+	}
+	for _, name := range postAttributes {
+		if item[name] == nil && key[name] == nil && !(d.versionPosts && name == "version") {
+			removes = append(removes, names.alias(name))
+		}
+	}
+
+	update := "SET " + strings.Join(sets, ", ")
+	if len(removes) > 0 {
+		update += " REMOVE " + strings.Join(removes, ", ")
+	}
+	input.UpdateExpression = aws.String(update)
+	return input
+}
+
+// setVersion returns the assignment that makes the store write the version
+// after the one the post is based on, conditional on that still being the
+// stored version. A post with Version 0, such as a freshly ingested one, is
+// based on whatever is stored, so the stored version is incremented in
+// place.
+func (d *DynamoDBStorage) setVersion(input *dynamodb.UpdateItemInput, names expressionNames, post models.TransformedPost) string {
+	version := names.alias("version")
+	values := input.ExpressionAttributeValues
+	if post.Version == 0 {
+		// New, or stored before versioning was enabled
+		values[":no_version"] = &dynamodb.AttributeValue{N: aws.String("0")}
+		values[":version_step"] = &dynamodb.AttributeValue{N: aws.String("1")}
+		return version + " = if_not_exists(" + version + ", :no_version) + :version_step"
+	}
+
+	input.ConditionExpression = aws.String(version + " = :base_version")
+	values[":base_version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(post.Version))}
+	values[":next_version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(post.Version + 1))}
+	return version + " = :next_version"
+}
+
 // GetPosts retrieves posts from DynamoDB with pagination
 func (d *DynamoDBStorage) GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(d.tableName),
-		Limit:     aws.Int64(int64(limit)),
+	if d.scanSegments > 1 {
+		return d.parallelScan(ctx, limit, offset)
 	}
+	return d.collectPosts(ctx, limit, offset, d.eachTable(func(table string) pageFunc {
+		return d.scanPages(ctx, table, nil)
+	}))
+}
 
-	result, err := d.client.ScanWithContext(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan posts: %w", err)
+// parallelScan scans the post tables as scanSegments segments concurrently.
+// Each segment collects up to offset+limit visible posts; concatenated in
+// segment order they form a stable sequence, so consecutive pages neither
+// repeat nor miss posts.
+func (d *DynamoDBStorage) parallelScan(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
+	segments := make([][]models.TransformedPost, d.scanSegments)
+	errs := make([]error, d.scanSegments)
+
+	var wg sync.WaitGroup
+	for i := range segments {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+			segments[segment], errs[segment] = d.collectItems(ctx, offset+limit, 0, d.eachTable(func(table string) pageFunc {
+				return d.scanPages(ctx, table, &segment)
+			}))
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
-	var posts []models.TransformedPost
-	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &posts)
+	var merged []models.TransformedPost
+	for _, posts := range segments {
+		merged = append(merged, posts...)
+	}
+	if offset >= len(merged) {
+		return []models.TransformedPost{}, nil
+	}
+	posts := merged[offset:min(offset+limit, len(merged))]
+
+	if err := d.loadBodies(ctx, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// scanPages pages through a Scan of a post table, restricted to one
+// segment of a parallel scan when segment is non-nil
+func (d *DynamoDBStorage) scanPages(ctx context.Context, table string, segment *int) pageFunc {
+	return func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error) {
+		input := &dynamodb.ScanInput{
+			TableName:         aws.String(table),
+			Limit:             aws.Int64(pageLimit),
+			ExclusiveStartKey: startKey,
+		}
+		if segment != nil {
+			input.Segment = aws.Int64(int64(*segment))
+			input.TotalSegments = aws.Int64(int64(d.scanSegments))
+		}
+
+		result, err := d.client.ScanWithContext(ctx, input)
+		if err != nil {
+			if segment != nil {
+				return nil, fmt.Errorf("failed to scan posts segment %d: %w", *segment, err)
+			}
+			return nil, fmt.Errorf("failed to scan posts: %w", err)
+		}
+		return &page{items: result.Items, lastKey: result.LastEvaluatedKey}, nil
+	}
+}
+
+// GetPostsByIngestionRange retrieves posts ingested between from and to
+// (inclusive), oldest first within each post table, using the ingestion
+// time index
+func (d *DynamoDBStorage) GetPostsByIngestionRange(ctx context.Context, from, to time.Time, limit int, offset int) ([]models.TransformedPost, error) {
+	return d.collectPosts(ctx, limit, offset, d.eachTable(func(table string) pageFunc {
+		return func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error) {
+			result, err := d.client.QueryWithContext(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(table),
+				IndexName:              aws.String(ingestedAtIndex),
+				KeyConditionExpression: aws.String("record_type = :type AND ingested_ts BETWEEN :from AND :to"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":type": {S: aws.String(postRecordType)},
+					":from": {N: aws.String(strconv.FormatInt(from.UnixNano(), 10))},
+					":to":   {N: aws.String(strconv.FormatInt(to.UnixNano(), 10))},
+				},
+				Limit:             aws.Int64(pageLimit),
+				ExclusiveStartKey: startKey,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to query posts by ingestion time: %w", err)
+			}
+			return &page{items: result.Items, lastKey: result.LastEvaluatedKey}, nil
+		}
+	}))
+}
+
+// GetPostsByCategory retrieves posts in the given category, oldest first
+// within each post table, using the category index
+func (d *DynamoDBStorage) GetPostsByCategory(ctx context.Context, category string, limit int, offset int) ([]models.TransformedPost, error) {
+	return d.collectPosts(ctx, limit, offset, d.eachTable(func(table string) pageFunc {
+		return func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error) {
+			result, err := d.client.QueryWithContext(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(table),
+				IndexName:              aws.String(categoryIndex),
+				KeyConditionExpression: aws.String("category = :category"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":category": {S: aws.String(category)},
+				},
+				Limit:             aws.Int64(pageLimit),
+				ExclusiveStartKey: startKey,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to query posts by category: %w", err)
+			}
+			return &page{items: result.Items, lastKey: result.LastEvaluatedKey}, nil
+		}
+	}))
+}
+
+// GetPostsByRunID retrieves the posts stored by one ingestion run. Run IDs
+// aren't indexed, so this scans the post tables, filtering as it goes.
+func (d *DynamoDBStorage) GetPostsByRunID(ctx context.Context, runID string, limit int, offset int) ([]models.TransformedPost, error) {
+	return d.collectPosts(ctx, limit, offset, d.eachTable(func(table string) pageFunc {
+		return func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error) {
+			names := expressionNames{}
+			result, err := d.client.ScanWithContext(ctx, &dynamodb.ScanInput{
+				TableName:                aws.String(table),
+				FilterExpression:         names.equals("run_id", ":run_id"),
+				ExpressionAttributeNames: names,
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":run_id": {S: aws.String(runID)},
+				},
+				Limit:             aws.Int64(pageLimit),
+				ExclusiveStartKey: startKey,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan posts by run ID: %w", err)
+			}
+			return &page{items: result.Items, lastKey: result.LastEvaluatedKey}, nil
+		}
+	}))
+}
+
+// ExportPosts calls fn with every post, table by table. Rather than an
+// offset, each page resumes from the last one's LastEvaluatedKey, so posts
+// stored or deleted mid-export don't shift later pages onto posts already
+// exported or past ones not yet. Unless asOf is zero, posts first stored
+// after it are left out; a post re-stored since keeps the time it was first
+// stored, so it is still exported.
+func (d *DynamoDBStorage) ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error {
+	includeDeleted := IncludesDeleted(ctx)
+	next := d.eachTable(func(table string) pageFunc {
+		return d.scanPages(ctx, table, nil)
+	})
+
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := next(startKey, exportPageSize)
+		if err != nil {
+			return err
+		}
+
+		posts := make([]models.TransformedPost, 0, len(result.items))
+		for _, item := range result.items {
+			if !asOf.IsZero() && firstIngestedAfter(item, asOf) {
+				continue
+			}
+			var post models.TransformedPost
+			if err := dynamodbattribute.UnmarshalMap(item, &post); err != nil {
+				return fmt.Errorf("failed to unmarshal posts: %w", err)
+			}
+			if post.Deleted && !includeDeleted {
+				continue
+			}
+			posts = append(posts, post)
+		}
+		if err := d.loadBodies(ctx, posts); err != nil {
+			return err
+		}
+		for _, post := range posts {
+			if err := fn(post); err != nil {
+				return err
+			}
+		}
+
+		if len(result.lastKey) == 0 {
+			return nil
+		}
+		startKey = result.lastKey
+	}
+}
+
+// firstIngestedAfter reports whether a post item was first stored after t,
+// falling back to ingested_ts for items stored before first_ingested_ts was
+func firstIngestedAfter(item map[string]*dynamodb.AttributeValue, t time.Time) bool {
+	first := item["first_ingested_ts"]
+	if first == nil {
+		first = item["ingested_ts"]
+	}
+	if first == nil {
+		return false
+	}
+	ts, err := strconv.ParseInt(aws.StringValue(first.N), 10, 64)
+	return err == nil && ts > t.UnixNano()
+}
+
+// GetLatestPost returns the most recently ingested post across all post
+// tables, or nil if there are none, using a descending query on the
+// ingestion time index of each
+func (d *DynamoDBStorage) GetLatestPost(ctx context.Context) (*models.TransformedPost, error) {
+	var latest *models.TransformedPost
+	for _, table := range d.tables() {
+		posts, err := d.collectPosts(ctx, 1, 0, func(startKey map[string]*dynamodb.AttributeValue, pageLimit int64) (*page, error) {
+			result, err := d.client.QueryWithContext(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(table),
+				IndexName:              aws.String(ingestedAtIndex),
+				KeyConditionExpression: aws.String("record_type = :type"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":type": {S: aws.String(postRecordType)},
+				},
+				ScanIndexForward:  aws.Bool(false),
+				Limit:             aws.Int64(pageLimit),
+				ExclusiveStartKey: startKey,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to query latest post in %s: %w", table, err)
+			}
+			return &page{items: result.Items, lastKey: result.LastEvaluatedKey}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(posts) > 0 && (latest == nil || posts[0].IngestedAt.After(latest.IngestedAt)) {
+			latest = &posts[0]
+		}
+	}
+
+	return latest, nil
+}
+
+// GetUserIDs returns the sorted, distinct user IDs across all post tables
+func (d *DynamoDBStorage) GetUserIDs(ctx context.Context) ([]int, error) {
+	seen := make(map[int]bool)
+
+	for _, table := range d.tables() {
+		names := expressionNames{}
+		input := &dynamodb.ScanInput{
+			TableName:                aws.String(table),
+			ProjectionExpression:     names.projection("userId", "deleted"),
+			ExpressionAttributeNames: names,
+		}
+		for {
+			result, err := d.client.ScanWithContext(ctx, input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan user IDs: %w", err)
+			}
+
+			for _, item := range result.Items {
+				var record struct {
+					UserID  int  `json:"userId"`
+					Deleted bool `json:"deleted"`
+				}
+				if err := dynamodbattribute.UnmarshalMap(item, &record); err != nil {
+					return nil, fmt.Errorf("failed to unmarshal post: %w", err)
+				}
+				if record.Deleted && !IncludesDeleted(ctx) {
+					continue
+				}
+				seen[record.UserID] = true
+			}
+
+			if len(result.LastEvaluatedKey) == 0 {
+				break
+			}
+			input.ExclusiveStartKey = result.LastEvaluatedKey
+		}
+	}
+
+	userIDs := make([]int, 0, len(seen))
+	for id := range seen {
+		userIDs = append(userIDs, id)
+	}
+	sort.Ints(userIDs)
+	return userIDs, nil
+}
+
+// page is one page of a Scan or Query
+type page struct {
+	items   []map[string]*dynamodb.AttributeValue
+	lastKey map[string]*dynamodb.AttributeValue
+}
+
+// collectPosts pages through a Scan or Query, skipping the first offset
+// visible posts and returning at most limit. DynamoDB has no offsets, and a
+// single call may stop early (e.g. at 1MB), so LastEvaluatedKey is followed
+// until enough posts are collected or the results are exhausted.
+func (d *DynamoDBStorage) collectPosts(ctx context.Context, limit int, offset int, next func(map[string]*dynamodb.AttributeValue, int64) (*page, error)) ([]models.TransformedPost, error) {
+	posts, err := d.collectItems(ctx, limit, offset, next)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal posts: %w", err)
+		return nil, err
+	}
+	if err := d.loadBodies(ctx, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// collectItems is collectPosts without restoring offloaded or compressed bodies
+func (d *DynamoDBStorage) collectItems(ctx context.Context, limit int, offset int, next func(map[string]*dynamodb.AttributeValue, int64) (*page, error)) ([]models.TransformedPost, error) {
+	includeDeleted := IncludesDeleted(ctx)
+	posts := make([]models.TransformedPost, 0, min(limit, maxPrealloc))
+	skipped := 0
+
+	var startKey map[string]*dynamodb.AttributeValue
+	for {
+		result, err := next(startKey, int64(offset-skipped+limit-len(posts)))
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []models.TransformedPost
+		err = dynamodbattribute.UnmarshalListOfMaps(result.items, &batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal posts: %w", err)
+		}
+
+		for _, post := range batch {
+			if post.Deleted && !includeDeleted {
+				continue
+			}
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			if len(posts) < limit {
+				posts = append(posts, post)
+			}
+		}
+
+		if len(posts) >= limit || len(result.lastKey) == 0 {
+			break
+		}
+		startKey = result.lastKey
 	}
 
 	return posts, nil
 }
 
-// GetPostByID retrieves a specific post by ID
-func (d *DynamoDBStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(d.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"id": {
-				N: aws.String(strconv.Itoa(id)),
-			},
-		},
+// loadBodies restores the offloaded and compressed bodies of posts
+func (d *DynamoDBStorage) loadBodies(ctx context.Context, posts []models.TransformedPost) error {
+	for i := range posts {
+		if err := d.loadBody(ctx, &posts[i]); err != nil {
+			return err
+		}
+		if err := decodeBody(&posts[i]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	result, err := d.client.GetItemWithContext(ctx, input)
+// GetPostByID retrieves a specific post by ID, from its source's table
+// when ctx names a source and otherwise from the first post table holding it
+func (d *DynamoDBStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
+	source, err := d.keySource(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get post %d: %w", id, err)
+		return nil, err
 	}
 
-	if result.Item == nil {
+	var item map[string]*dynamodb.AttributeValue
+	for _, table := range d.lookupTables(ctx) {
+		result, err := d.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(table),
+			Key:       d.postKey(source, id),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get post %d: %w", id, err)
+		}
+		if result.Item != nil {
+			item = result.Item
+			break
+		}
+	}
+
+	if item == nil {
 		return nil, nil // Post not found
 	}
 
 	var post models.TransformedPost
-	err = dynamodbattribute.UnmarshalMap(result.Item, &post)
+	err = dynamodbattribute.UnmarshalMap(item, &post)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal post: %w", err)
 	}
 
+	if post.Deleted && !IncludesDeleted(ctx) {
+		return nil, nil // Soft-deleted posts are hidden
+	}
+
+	if err := d.loadBody(ctx, &post); err != nil {
+		return nil, err
+	}
+	if err := decodeBody(&post); err != nil {
+		return nil, err
+	}
+
 	return &post, nil
 }
 
+// StoreComments stores comments in the comments table, keyed by post
+func (d *DynamoDBStorage) StoreComments(ctx context.Context, comments []models.Comment) error {
+	for start := 0; start < len(comments); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(comments) {
+			end = len(comments)
+		}
+
+		requests := make([]*dynamodb.WriteRequest, 0, end-start)
+		for _, comment := range comments[start:end] {
+			item, err := dynamodbattribute.MarshalMap(comment)
+			if err != nil {
+				return fmt.Errorf("failed to marshal comment %d: %w", comment.ID, err)
+			}
+			requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+		}
+
+		if err := d.batchWrite(ctx, d.commentsTable(), requests); err != nil {
+			return fmt.Errorf("failed to store comments: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetComments retrieves a post's comments ordered by comment ID
+func (d *DynamoDBStorage) GetComments(ctx context.Context, postID int) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		result, err := d.client.QueryWithContext(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(d.commentsTable()),
+			KeyConditionExpression: aws.String("postId = :postId"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":postId": {N: aws.String(strconv.Itoa(postID))},
+			},
+			ExclusiveStartKey: startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query comments of post %d: %w", postID, err)
+		}
+
+		var batch []models.Comment
+		if err := dynamodbattribute.UnmarshalListOfMaps(result.Items, &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal comments: %w", err)
+		}
+		comments = append(comments, batch...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = result.LastEvaluatedKey
+	}
+
+	return comments, nil
+}
+
+// DeletePost removes a post, or marks it deleted when soft deletes are
+// enabled. Without a source in ctx, it is deleted from every post table
+// holding it.
+func (d *DynamoDBStorage) DeletePost(ctx context.Context, id int) error {
+	if _, err := d.keySource(ctx); err != nil {
+		return err
+	}
+
+	found := false
+	for _, table := range d.lookupTables(ctx) {
+		err := d.deleteFrom(ctx, table, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// deleteFrom deletes the post with id from table
+func (d *DynamoDBStorage) deleteFrom(ctx context.Context, table string, id int) error {
+	key := d.postKey(SourceFrom(ctx), id)
+
+	var err error
+	if d.softDelete {
+		_, err = d.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(table),
+			Key:                 key,
+			ConditionExpression: aws.String("attribute_exists(id)"),
+			UpdateExpression:    aws.String("SET deleted = :deleted, deleted_at = :deleted_at"),
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":deleted":    {BOOL: aws.Bool(true)},
+				":deleted_at": {S: aws.String(time.Now().UTC().Format(time.RFC3339Nano))},
+			},
+		})
+	} else {
+		_, err = d.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName:           aws.String(table),
+			Key:                 key,
+			ConditionExpression: aws.String("attribute_exists(id)"),
+		})
+	}
+
+	var aerr awserr.Error
+	if errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete post %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// DeletePosts deletes the given posts in batches and returns how many existed
+func (d *DynamoDBStorage) DeletePosts(ctx context.Context, ids []int) (int, error) {
+	ids = uniqueIDs(ids) // Batch requests reject duplicate keys
+
+	if d.softDelete {
+		// Batch writes can't update items, so mark them one at a time
+		deleted := 0
+		for _, id := range ids {
+			err := d.DeletePost(ctx, id)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		return deleted, nil
+	}
+
+	source, err := d.keySource(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, table := range d.lookupTables(ctx) {
+		n, err := d.deleteBatchFrom(ctx, table, source, ids)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// deleteBatchFrom deletes the posts with ids from table in batches and
+// returns how many existed there
+func (d *DynamoDBStorage) deleteBatchFrom(ctx context.Context, table, source string, ids []int) (int, error) {
+	// BatchWriteItem doesn't report missing items, so look them up first
+	existing, err := d.existingIDs(ctx, table, source, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	for start := 0; start < len(existing); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(existing) {
+			end = len(existing)
+		}
+
+		requests := make([]*dynamodb.WriteRequest, 0, end-start)
+		for _, id := range existing[start:end] {
+			requests = append(requests, &dynamodb.WriteRequest{
+				DeleteRequest: &dynamodb.DeleteRequest{
+					Key: d.postKey(source, id),
+				},
+			})
+		}
+
+		if err := d.batchWrite(ctx, table, requests); err != nil {
+			return start, fmt.Errorf("failed to delete posts: %w", err)
+		}
+	}
+
+	return len(existing), nil
+}
+
+// existingIDs returns the subset of ids from source present in table
+func (d *DynamoDBStorage) existingIDs(ctx context.Context, table, source string, ids []int) ([]int, error) {
+	var existing []int
+
+	for start := 0; start < len(ids); start += batchGetSize {
+		end := start + batchGetSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		keys := make([]map[string]*dynamodb.AttributeValue, 0, end-start)
+		for _, id := range ids[start:end] {
+			keys = append(keys, d.postKey(source, id))
+		}
+
+		names := expressionNames{}
+		request := map[string]*dynamodb.KeysAndAttributes{
+			table: {
+				Keys:                     keys,
+				ProjectionExpression:     names.projection("id"),
+				ExpressionAttributeNames: names,
+			},
+		}
+		for len(request) > 0 {
+			result, err := d.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: request,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up posts: %w", err)
+			}
+
+			for _, item := range result.Responses[table] {
+				id, err := strconv.Atoi(aws.StringValue(item["id"].N))
+				if err != nil {
+					return nil, fmt.Errorf("invalid post id: %w", err)
+				}
+				existing = append(existing, id)
+			}
+			request = result.UnprocessedKeys
+		}
+	}
+
+	return existing, nil
+}
+
+// batchWrite sends write requests to table, resubmitting any the service left
+// unprocessed
+func (d *DynamoDBStorage) batchWrite(ctx context.Context, table string, requests []*dynamodb.WriteRequest) error {
+	pending := map[string][]*dynamodb.WriteRequest{table: requests}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			// Unprocessed items usually mean throttling, so back off
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			}
+		}
+
+		result, err := d.client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: pending,
+		})
+		if err != nil {
+			return err
+		}
+		pending = result.UnprocessedItems
+	}
+
+	return nil
+}
+
+// uniqueIDs returns ids with duplicates removed, preserving order
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// offloadBody uploads an oversized body to S3 and replaces it with a reference
+func (d *DynamoDBStorage) offloadBody(ctx context.Context, post *models.TransformedPost) error {
+	if d.s3Client == nil || len(post.Body) <= d.offloadThreshold {
+		return nil
+	}
+
+	// Posts are only unique per source and table, so the key names both
+	key := fmt.Sprintf("%s/posts/%s/%d", d.tableFor(post.Source), url.PathEscape(post.Source), post.ID)
+	_, err := d.s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(d.offloadBucket),
+		Key:         aws.String(key),
+		Body:        strings.NewReader(post.Body),
+		ContentType: aws.String("text/plain; charset=utf-8"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to offload body of post %d: %w", post.ID, err)
+	}
+
+	post.BodyRef = fmt.Sprintf("s3://%s/%s", d.offloadBucket, key)
+	post.Body = ""
+	return nil
+}
+
+// loadBody restores an offloaded body from S3
+func (d *DynamoDBStorage) loadBody(ctx context.Context, post *models.TransformedPost) error {
+	if post.BodyRef == "" {
+		return nil
+	}
+	if d.s3Client == nil {
+		return fmt.Errorf("post %d has an offloaded body but offloading is disabled", post.ID)
+	}
+
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(post.BodyRef, "s3://"), "/")
+	if !ok {
+		return fmt.Errorf("invalid body reference for post %d: %s", post.ID, post.BodyRef)
+	}
+
+	result, err := d.s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load body of post %d: %w", post.ID, err)
+	}
+	defer result.Body.Close()
+
+	body, err := io.ReadAll(result.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read body of post %d: %w", post.ID, err)
+	}
+
+	post.Body = string(body)
+	post.BodyRef = ""
+	return nil
+}
+
 // UpdateIngestionStatus updates the ingestion status
 func (d *DynamoDBStorage) UpdateIngestionStatus(ctx context.Context, status models.IngestionStatus) error {
 	// Store in a separate table or use a fixed key
@@ -211,6 +1392,71 @@ func (d *DynamoDBStorage) GetIngestionStatus(ctx context.Context) (*models.Inges
 	return &status, nil
 }
 
+// metricsSnapshotKind marks the metrics snapshot items kept alongside the
+// status record
+const metricsSnapshotKind = "metrics_snapshot"
+
+// RecordMetricsSnapshot stores a cycle's metrics snapshot in the status
+// table, keyed by its time
+func (d *DynamoDBStorage) RecordMetricsSnapshot(ctx context.Context, snapshot models.MetricsSnapshot) error {
+	item, err := dynamodbattribute.MarshalMap(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics snapshot: %w", err)
+	}
+	item["id"] = &dynamodb.AttributeValue{S: aws.String("metrics#" + snapshot.Time.UTC().Format(time.RFC3339Nano))}
+	item["kind"] = &dynamodb.AttributeValue{S: aws.String(metricsSnapshotKind)}
+
+	_, err = d.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.tableName + "_status"),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store metrics snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetMetricsHistory returns up to limit metrics snapshots, newest first
+func (d *DynamoDBStorage) GetMetricsHistory(ctx context.Context, limit int) ([]models.MetricsSnapshot, error) {
+	names := expressionNames{}
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(d.tableName + "_status"),
+		FilterExpression:         names.equals("kind", ":kind"),
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":kind": {S: aws.String(metricsSnapshotKind)},
+		},
+	}
+
+	var snapshots []models.MetricsSnapshot
+	for {
+		result, err := d.client.ScanWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan metrics snapshots: %w", err)
+		}
+		for _, item := range result.Items {
+			var snapshot models.MetricsSnapshot
+			if err := dynamodbattribute.UnmarshalMap(item, &snapshot); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metrics snapshot: %w", err)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+	if limit > 0 && len(snapshots) > limit {
+		snapshots = snapshots[:limit]
+	}
+	return snapshots, nil
+}
+
 // Close closes the DynamoDB connection
 func (d *DynamoDBStorage) Close() error {
 	// DynamoDB client doesn't need explicit closing
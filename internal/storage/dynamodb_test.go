@@ -0,0 +1,1514 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// MockDynamoDB is an in-memory implementation of the DynamoDB calls used by DynamoDBStorage
+type MockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	tables map[string]map[string]map[string]*dynamodb.AttributeValue
+
+	batchWriteSizes []int // Number of requests in each BatchWriteItem call
+	scanPageSize    int   // Simulates DynamoDB's 1MB page limit when set
+	scanCalls       int
+	scanMu          sync.Mutex    // Scans may run concurrently in parallel scan mode
+	scannedSegments map[int64]int // Scan calls per parallel scan segment
+
+	getItemCalls int
+
+	compositeKey bool                         // Posts are keyed by source and id
+	created      []*dynamodb.CreateTableInput // Tables created by ensureTable
+
+	existing map[string]*dynamodb.TableDescription // Tables that exist before ensureTable runs
+	updated  []*dynamodb.UpdateTableInput          // Index additions by ensureTable
+}
+
+func NewMockDynamoDB() *MockDynamoDB {
+	return &MockDynamoDB{
+		tables: make(map[string]map[string]map[string]*dynamodb.AttributeValue),
+	}
+}
+
+func itemKey(item map[string]*dynamodb.AttributeValue) string {
+	id := item["id"]
+	if id.N != nil {
+		return *id.N
+	}
+	return aws.StringValue(id.S)
+}
+
+// key returns the map key of an item or primary key
+func (m *MockDynamoDB) key(item map[string]*dynamodb.AttributeValue) string {
+	if m.compositeKey && item["source"] != nil {
+		return aws.StringValue(item["source"].S) + "#" + itemKey(item)
+	}
+	return itemKey(item)
+}
+
+// primaryKey returns the primary key attributes of a post item
+func (m *MockDynamoDB) primaryKey(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{"id": item["id"]}
+	if m.compositeKey {
+		key["source"] = item["source"]
+	}
+	return key
+}
+
+// DescribeTable reports every table not in existing missing, so ensureTable
+// creates it
+func (m *MockDynamoDB) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	if table, ok := m.existing[aws.StringValue(input.TableName)]; ok {
+		return &dynamodb.DescribeTableOutput{Table: table}, nil
+	}
+	return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "table not found", nil)
+}
+
+// UpdateTable adds the indexes created by the update to the existing table,
+// active at once
+func (m *MockDynamoDB) UpdateTable(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	m.updated = append(m.updated, input)
+	table := m.existing[aws.StringValue(input.TableName)]
+	for _, update := range input.GlobalSecondaryIndexUpdates {
+		table.GlobalSecondaryIndexes = append(table.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   update.Create.IndexName,
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+		})
+	}
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+func (m *MockDynamoDB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	m.created = append(m.created, input)
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (m *MockDynamoDB) WaitUntilTableExists(input *dynamodb.DescribeTableInput) error {
+	return nil
+}
+
+func (m *MockDynamoDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	table := aws.StringValue(input.TableName)
+	if m.tables[table] == nil {
+		m.tables[table] = make(map[string]map[string]*dynamodb.AttributeValue)
+	}
+	m.tables[table][m.key(input.Item)] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (m *MockDynamoDB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	m.getItemCalls++
+	item := m.tables[aws.StringValue(input.TableName)][m.key(input.Key)]
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (m *MockDynamoDB) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	table := m.tables[aws.StringValue(input.TableName)]
+	key := m.key(input.Key)
+	if _, ok := table[key]; !ok && input.ConditionExpression != nil {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "conditional check failed", nil)
+	}
+	delete(table, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// UpdateItemWithContext supports the SET and REMOVE expressions and the
+// conditions used by StorePosts and DeletePosts
+func (m *MockDynamoDB) UpdateItemWithContext(ctx aws.Context, input *dynamodb.UpdateItemInput, opts ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	item, ok := m.tables[aws.StringValue(input.TableName)][m.key(input.Key)]
+	var holds bool
+	switch aws.StringValue(input.ConditionExpression) {
+	case "":
+		holds = true
+	case "attribute_exists(id)":
+		holds = ok
+	case "#version = :base_version":
+		holds = ok && item["version"] != nil && aws.StringValue(item["version"].N) == aws.StringValue(input.ExpressionAttributeValues[":base_version"].N)
+	}
+	if !holds {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "conditional check failed", nil)
+	}
+	if !ok {
+		item = make(map[string]*dynamodb.AttributeValue)
+		for name, value := range input.Key {
+			item[name] = value
+		}
+		m.PutItemWithContext(ctx, &dynamodb.PutItemInput{TableName: input.TableName, Item: item})
+	}
+
+	name := func(placeholder string) string {
+		placeholder = strings.TrimSpace(placeholder)
+		if alias, ok := input.ExpressionAttributeNames[placeholder]; ok {
+			return aws.StringValue(alias)
+		}
+		return placeholder
+	}
+	// operand resolves a value placeholder or if_not_exists(#a, :v) against
+	// the item as it was before the update
+	before := make(map[string]*dynamodb.AttributeValue, len(item))
+	for name, value := range item {
+		before[name] = value
+	}
+	operand := func(expr string) *dynamodb.AttributeValue {
+		expr = strings.TrimSpace(expr)
+		if args, ok := strings.CutPrefix(expr, "if_not_exists("); ok {
+			attribute, fallback, _ := strings.Cut(strings.TrimSuffix(args, ")"), ",")
+			if value := before[name(attribute)]; value != nil {
+				return value
+			}
+			return input.ExpressionAttributeValues[strings.TrimSpace(fallback)]
+		}
+		return input.ExpressionAttributeValues[expr]
+	}
+
+	update, removes, _ := strings.Cut(strings.TrimPrefix(aws.StringValue(input.UpdateExpression), "SET "), " REMOVE ")
+	for _, assignment := range splitTopLevel(update) {
+		attribute, expr, _ := strings.Cut(assignment, "=")
+		left, right, sum := strings.Cut(expr, "+")
+		value := operand(left)
+		if sum {
+			a, _ := strconv.Atoi(aws.StringValue(value.N))
+			b, _ := strconv.Atoi(aws.StringValue(operand(right).N))
+			value = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(a + b))}
+		}
+		item[name(attribute)] = value
+	}
+	if removes != "" {
+		for _, attribute := range strings.Split(removes, ",") {
+			delete(item, name(attribute))
+		}
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// splitTopLevel splits a comma separated expression, leaving commas inside
+// function calls alone
+func splitTopLevel(expr string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+func (m *MockDynamoDB) BatchGetItemWithContext(ctx aws.Context, input *dynamodb.BatchGetItemInput, opts ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	responses := make(map[string][]map[string]*dynamodb.AttributeValue)
+	for table, request := range input.RequestItems {
+		for _, key := range request.Keys {
+			if item, ok := m.tables[table][m.key(key)]; ok {
+				responses[table] = append(responses[table], item)
+			}
+		}
+	}
+	return &dynamodb.BatchGetItemOutput{Responses: responses}, nil
+}
+
+func (m *MockDynamoDB) BatchWriteItemWithContext(ctx aws.Context, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	for table, requests := range input.RequestItems {
+		if len(requests) > 25 {
+			return nil, awserr.New("ValidationException", "too many items", nil)
+		}
+		m.batchWriteSizes = append(m.batchWriteSizes, len(requests))
+
+		for _, request := range requests {
+			if request.DeleteRequest != nil {
+				delete(m.tables[table], m.key(request.DeleteRequest.Key))
+			}
+			if request.PutRequest != nil {
+				m.PutItemWithContext(ctx, &dynamodb.PutItemInput{TableName: aws.String(table), Item: request.PutRequest.Item})
+			}
+		}
+	}
+	return &dynamodb.BatchWriteItemOutput{}, nil
+}
+
+// QueryWithContext supports the post indexes and the comments table
+func (m *MockDynamoDB) QueryWithContext(ctx aws.Context, input *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	if input.IndexName == nil {
+		return m.queryComments(input), nil
+	}
+
+	// Partition key attribute and placeholder of each supported index
+	partitions := map[string][2]string{
+		ingestedAtIndex: {"record_type", ":type"},
+		categoryIndex:   {"category", ":category"},
+	}
+	partition, ok := partitions[aws.StringValue(input.IndexName)]
+	if !ok {
+		return nil, awserr.New("ValidationException", "unsupported index", nil)
+	}
+
+	values := input.ExpressionAttributeValues
+	timestamp := func(av *dynamodb.AttributeValue) int64 {
+		ts, _ := strconv.ParseInt(aws.StringValue(av.N), 10, 64)
+		return ts
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, item := range m.tables[aws.StringValue(input.TableName)] {
+		if item[partition[0]] == nil || aws.StringValue(item[partition[0]].S) != aws.StringValue(values[partition[1]].S) {
+			continue
+		}
+		ts := timestamp(item["ingested_ts"])
+		if from, ok := values[":from"]; ok && ts < timestamp(from) {
+			continue
+		}
+		if to, ok := values[":to"]; ok && ts > timestamp(to) {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		less := timestamp(items[i]["ingested_ts"]) < timestamp(items[j]["ingested_ts"])
+		if input.ScanIndexForward != nil && !*input.ScanIndexForward {
+			return !less
+		}
+		return less
+	})
+
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		for start < len(items) && m.key(items[start]) != m.key(input.ExclusiveStartKey) {
+			start++
+		}
+		start++
+	}
+
+	output := &dynamodb.QueryOutput{}
+	for i := start; i < len(items); i++ {
+		if input.Limit != nil && int64(len(output.Items)) >= *input.Limit {
+			output.LastEvaluatedKey = m.primaryKey(items[i-1])
+			break
+		}
+		output.Items = append(output.Items, items[i])
+	}
+
+	return output, nil
+}
+
+// queryComments returns one post's comments in ID order, a page at a time
+func (m *MockDynamoDB) queryComments(input *dynamodb.QueryInput) *dynamodb.QueryOutput {
+	postID := aws.StringValue(input.ExpressionAttributeValues[":postId"].N)
+	number := func(av *dynamodb.AttributeValue) int {
+		n, _ := strconv.Atoi(aws.StringValue(av.N))
+		return n
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, item := range m.tables[aws.StringValue(input.TableName)] {
+		if aws.StringValue(item["postId"].N) == postID {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return number(items[i]["id"]) < number(items[j]["id"]) })
+
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		for start < len(items) && number(items[start]["id"]) <= number(input.ExclusiveStartKey["id"]) {
+			start++
+		}
+	}
+
+	output := &dynamodb.QueryOutput{}
+	for i := start; i < len(items); i++ {
+		if m.scanPageSize > 0 && len(output.Items) == m.scanPageSize {
+			output.LastEvaluatedKey = map[string]*dynamodb.AttributeValue{"postId": items[i-1]["postId"], "id": items[i-1]["id"]}
+			break
+		}
+		output.Items = append(output.Items, items[i])
+	}
+	return output
+}
+
+func (m *MockDynamoDB) ScanWithContext(ctx aws.Context, input *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+	m.scanCalls++
+	for _, expr := range []*string{input.ProjectionExpression, input.FilterExpression} {
+		if err := checkReservedWords(expr); err != nil {
+			return nil, err
+		}
+	}
+	table := m.tables[aws.StringValue(input.TableName)]
+
+	// Order keys numerically so pages are deterministic, assigning each
+	// item to segment id % TotalSegments in parallel scans
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		if input.TotalSegments != nil {
+			id, _ := strconv.ParseInt(key, 10, 64)
+			if id%*input.TotalSegments != *input.Segment {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	if input.Segment != nil {
+		if m.scannedSegments == nil {
+			m.scannedSegments = make(map[int64]int)
+		}
+		m.scannedSegments[*input.Segment]++
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	start := 0
+	if input.ExclusiveStartKey != nil {
+		for start < len(keys) && keys[start] != m.key(input.ExclusiveStartKey) {
+			start++
+		}
+		start++
+	}
+
+	pageSize := len(keys)
+	if input.Limit != nil && int(*input.Limit) < pageSize {
+		pageSize = int(*input.Limit)
+	}
+	if m.scanPageSize > 0 && m.scanPageSize < pageSize {
+		pageSize = m.scanPageSize
+	}
+
+	// Like DynamoDB, the filter applies after a page is read
+	output := &dynamodb.ScanOutput{}
+	for i, evaluated := start, 0; i < len(keys) && evaluated < pageSize; i++ {
+		evaluated++
+		if matchesFilter(table[keys[i]], input) {
+			output.Items = append(output.Items, table[keys[i]])
+		}
+		if evaluated == pageSize && i < len(keys)-1 {
+			output.LastEvaluatedKey = m.primaryKey(table[keys[i]])
+		}
+	}
+
+	return output, nil
+}
+
+// reservedWords are the DynamoDB reserved words among the service's attributes
+var reservedWords = map[string]bool{"comment": true, "data": true, "name": true, "source": true, "status": true}
+
+// checkReservedWords rejects an expression naming a reserved word without a
+// placeholder, as DynamoDB does
+func checkReservedWords(expr *string) error {
+	tokens := strings.FieldsFunc(aws.StringValue(expr), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '#' && r != ':'
+	})
+	for _, token := range tokens {
+		if reservedWords[strings.ToLower(token)] {
+			return awserr.New("ValidationException", "Attribute name is a reserved keyword; reserved keyword: "+token, nil)
+		}
+	}
+	return nil
+}
+
+// matchesFilter evaluates a "name = :value" FilterExpression, if any
+func matchesFilter(item map[string]*dynamodb.AttributeValue, input *dynamodb.ScanInput) bool {
+	if input.FilterExpression == nil {
+		return true
+	}
+	name, placeholder, _ := strings.Cut(aws.StringValue(input.FilterExpression), " = ")
+	if alias, ok := input.ExpressionAttributeNames[name]; ok {
+		name = aws.StringValue(alias)
+	}
+	return item[name] != nil && item[name].String() == input.ExpressionAttributeValues[placeholder].String()
+}
+
+// MockS3 is an in-memory implementation of the S3 calls used for body offloading
+type MockS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func NewMockS3() *MockS3 {
+	return &MockS3{objects: make(map[string][]byte)}
+}
+
+func (m *MockS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *MockS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	body := m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)]
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func newTestPost(id int, body string) models.TransformedPost {
+	return models.TransformedPost{
+		Post:       models.Post{UserID: 1, ID: id, Title: "Test Post", Body: body},
+		IngestedAt: time.Now().UTC(),
+		Source:     "placeholder_api",
+	}
+}
+
+func TestDynamoDBStorage_FlatPostLayout(t *testing.T) {
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{client: mockDB, tableName: "posts"}
+
+	ctx := context.Background()
+	require.NoError(t, store.StorePosts(ctx, []models.TransformedPost{newTestPost(1, "flat body")}))
+
+	// Test the embedded post's fields are stored at the top level of the item
+	item := mockDB.tables["posts"]["1"]
+	assert.Equal(t, "Test Post", aws.StringValue(item["title"].S))
+	assert.Equal(t, "flat body", aws.StringValue(item["body"].S))
+	assert.Equal(t, "1", aws.StringValue(item["userId"].N))
+	assert.NotContains(t, item, "Post")
+
+	// Test GetPostByID returns them at the top level too
+	post, err := store.GetPostByID(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, post)
+	data, err := json.Marshal(post)
+	require.NoError(t, err)
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "Test Post", fields["title"])
+	assert.Equal(t, "flat body", fields["body"])
+	assert.NotContains(t, fields, "Post")
+}
+
+func TestDynamoDBStorage_OffloadLargeBodies(t *testing.T) {
+	// Create storage with mocked clients
+	mockDB := NewMockDynamoDB()
+	mockS3 := NewMockS3()
+	store := &DynamoDBStorage{
+		client:           mockDB,
+		tableName:        "posts",
+		s3Client:         mockS3,
+		offloadBucket:    "bodies",
+		offloadThreshold: 16,
+	}
+
+	largeBody := strings.Repeat("x", 64)
+	posts := []models.TransformedPost{
+		newTestPost(1, "short body"),
+		newTestPost(2, largeBody),
+	}
+
+	// Test StorePosts offloads only the large body
+	ctx := context.Background()
+	err := store.StorePosts(ctx, posts)
+
+	assert.NoError(t, err)
+	assert.Len(t, mockS3.objects, 1)
+	assert.Equal(t, largeBody, string(mockS3.objects["bodies/posts/posts/placeholder_api/2"]))
+
+	stored := mockDB.tables["posts"]["2"]
+	assert.Equal(t, "", aws.StringValue(stored["body"].S))
+	assert.Equal(t, "s3://bodies/posts/posts/placeholder_api/2", aws.StringValue(stored["body_ref"].S))
+	assert.Equal(t, largeBody, posts[1].Body, "caller's posts must not be modified")
+
+	// Test GetPostByID reassembles the body
+	post, err := store.GetPostByID(ctx, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, largeBody, post.Body)
+	assert.Empty(t, post.BodyRef)
+
+	// Test GetPosts reassembles the body
+	all, err := store.GetPosts(ctx, 10, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, "short body", all[0].Body)
+	assert.Equal(t, largeBody, all[1].Body)
+}
+
+func TestDynamoDBStorage_OffloadDisabled(t *testing.T) {
+	// Create storage without an S3 client
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	largeBody := strings.Repeat("x", 64)
+
+	// Test StorePosts keeps the body inline
+	ctx := context.Background()
+	err := store.StorePosts(ctx, []models.TransformedPost{newTestPost(1, largeBody)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, largeBody, aws.StringValue(mockDB.tables["posts"]["1"]["body"].S))
+}
+
+func TestDynamoDBStorage_SoftDelete(t *testing.T) {
+	// Create storage with soft deletes enabled
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:     mockDB,
+		tableName:  "posts",
+		softDelete: true,
+	}
+
+	ctx := context.Background()
+	err := store.StorePosts(ctx, []models.TransformedPost{
+		newTestPost(1, "first"),
+		newTestPost(2, "second"),
+	})
+	assert.NoError(t, err)
+
+	// Test DeletePost keeps the item but marks it
+	err = store.DeletePost(ctx, 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, mockDB.tables["posts"], 2)
+
+	// Soft-deleted posts are hidden by default
+	post, err := store.GetPostByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, post)
+
+	posts, err := store.GetPosts(ctx, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, 2, posts[0].ID)
+
+	// And surfaced when requested
+	post, err = store.GetPostByID(WithDeleted(ctx), 1)
+	assert.NoError(t, err)
+	if assert.NotNil(t, post) {
+		assert.True(t, post.Deleted)
+		assert.NotNil(t, post.DeletedAt)
+	}
+
+	posts, err = store.GetPosts(WithDeleted(ctx), 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+}
+
+func TestDynamoDBStorage_DeletePost_NotFound(t *testing.T) {
+	for _, softDelete := range []bool{false, true} {
+		store := &DynamoDBStorage{
+			client:     NewMockDynamoDB(),
+			tableName:  "posts",
+			softDelete: softDelete,
+		}
+
+		err := store.DeletePost(context.Background(), 42)
+
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+}
+
+func TestDynamoDBStorage_DeletePosts(t *testing.T) {
+	// Create storage holding posts 1-40
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	ctx := context.Background()
+	var posts []models.TransformedPost
+	for id := 1; id <= 40; id++ {
+		posts = append(posts, newTestPost(id, "body"))
+	}
+	assert.NoError(t, store.StorePosts(ctx, posts))
+
+	// Delete a range that partly doesn't exist
+	var ids []int
+	for id := 31; id <= 60; id++ {
+		ids = append(ids, id)
+	}
+	for id := 1; id <= 20; id++ {
+		ids = append(ids, id)
+	}
+
+	deleted, err := store.DeletePosts(ctx, ids)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 30, deleted)
+	assert.Equal(t, []int{25, 5}, mockDB.batchWriteSizes)
+	assert.Len(t, mockDB.tables["posts"], 10)
+}
+
+func TestDynamoDBStorage_GetPosts_FollowsLastEvaluatedKey(t *testing.T) {
+	// Create storage whose scans return at most 3 items per page
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 3
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	ctx := context.Background()
+	var posts []models.TransformedPost
+	for id := 1; id <= 10; id++ {
+		posts = append(posts, newTestPost(id, "body"))
+	}
+	assert.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test GetPosts keeps scanning past the first page
+	result, err := store.GetPosts(ctx, 5, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, mockDB.scanCalls)
+	if assert.Len(t, result, 5) {
+		assert.Equal(t, 3, result[0].ID)
+		assert.Equal(t, 7, result[4].ID)
+	}
+
+	// Test the table being exhausted before the limit
+	mockDB.scanCalls = 0
+	result, err = store.GetPosts(ctx, 10, 8)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+}
+
+func TestDynamoDBStorage_StorePosts_SourceTables(t *testing.T) {
+	// Create storage routing each source to its own table
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+		sourceTables: map[string]string{
+			"alpha": "posts_alpha",
+			"beta":  "posts_beta",
+		},
+	}
+
+	alpha := newTestPost(1, "from alpha")
+	alpha.Source = "alpha"
+	beta := newTestPost(2, "from beta")
+	beta.Source = "beta"
+	other := newTestPost(3, "unrouted")
+	other.Source = "gamma"
+
+	// Test StorePosts routes by source
+	err := store.StorePosts(context.Background(), []models.TransformedPost{alpha, beta, other})
+
+	assert.NoError(t, err)
+	assert.Len(t, mockDB.tables["posts_alpha"], 1)
+	assert.Contains(t, mockDB.tables["posts_alpha"], "1")
+	assert.Len(t, mockDB.tables["posts_beta"], 1)
+	assert.Contains(t, mockDB.tables["posts_beta"], "2")
+	assert.Len(t, mockDB.tables["posts"], 1)
+	assert.Contains(t, mockDB.tables["posts"], "3")
+}
+
+func TestDynamoDBStorage_SourceTables_ReadBack(t *testing.T) {
+	// Create storage routing one source to its own table, scanning a post
+	// per page so reads must move from table to table
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 1
+	store := &DynamoDBStorage{
+		client:       mockDB,
+		tableName:    "posts",
+		sourceTables: map[string]string{"alpha": "posts_alpha"},
+	}
+
+	ctx := context.Background()
+	var posts []models.TransformedPost
+	for id, source := range map[int]string{1: "gamma", 2: "alpha", 3: "alpha", 4: "gamma"} {
+		post := newTestPost(id, "body")
+		post.Source = source
+		post.Category = "news"
+		posts = append(posts, post)
+	}
+	require.NoError(t, store.StorePosts(ctx, posts))
+	require.Len(t, mockDB.tables["posts_alpha"], 2)
+
+	ids := func(posts []models.TransformedPost) []int {
+		var ids []int
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	// Test GetPosts reads every table, paging across them
+	all, err := store.GetPosts(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 4, 2, 3}, ids(all))
+	page, err := store.GetPosts(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 2}, ids(page))
+
+	// Test the parallel scan and the category query do too
+	store.scanSegments = 2
+	all, err = store.GetPosts(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 2, 3, 4}, ids(all))
+	store.scanSegments = 0
+	byCategory, err := store.GetPostsByCategory(ctx, "news", 10, 0)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 2, 3, 4}, ids(byCategory))
+
+	// Test GetPostByID finds a routed post with or without its source
+	post, err := store.GetPostByID(ctx, 2)
+	require.NoError(t, err)
+	if assert.NotNil(t, post) {
+		assert.Equal(t, "alpha", post.Source)
+	}
+	post, err = store.GetPostByID(WithSource(ctx, "alpha"), 3)
+	require.NoError(t, err)
+	assert.NotNil(t, post)
+	post, err = store.GetPostByID(WithSource(ctx, "gamma"), 3)
+	require.NoError(t, err)
+	assert.Nil(t, post)
+
+	// Test deletes reach the routed table
+	assert.NoError(t, store.DeletePost(ctx, 2))
+	assert.ErrorIs(t, store.DeletePost(ctx, 2), ErrNotFound)
+	deleted, err := store.DeletePosts(ctx, []int{1, 3, 99})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Empty(t, mockDB.tables["posts_alpha"])
+	assert.Len(t, mockDB.tables["posts"], 1)
+}
+
+func TestDynamoDBStorage_GetPostsByIngestionRange(t *testing.T) {
+	// Create storage holding posts ingested an hour apart
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	var posts []models.TransformedPost
+	for i := 0; i < 5; i++ {
+		post := newTestPost(i+1, "body")
+		post.IngestedAt = base.Add(time.Duration(i) * time.Hour)
+		posts = append(posts, post)
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test a window covering the middle three posts
+	result, err := store.GetPostsByIngestionRange(ctx, base.Add(time.Hour), base.Add(3*time.Hour), 10, 0)
+
+	assert.NoError(t, err)
+	if assert.Len(t, result, 3) {
+		assert.Equal(t, 2, result[0].ID)
+		assert.Equal(t, 3, result[1].ID)
+		assert.Equal(t, 4, result[2].ID)
+	}
+
+	// Test limit and offset within the window
+	result, err = store.GetPostsByIngestionRange(ctx, base.Add(time.Hour), base.Add(3*time.Hour), 1, 1)
+
+	assert.NoError(t, err)
+	if assert.Len(t, result, 1) {
+		assert.Equal(t, 3, result[0].ID)
+	}
+}
+
+func TestDynamoDBStorage_GetUserIDs(t *testing.T) {
+	// Create storage with posts from repeated users across tables and pages
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 2
+	store := &DynamoDBStorage{
+		client:       mockDB,
+		tableName:    "posts",
+		sourceTables: map[string]string{"alpha": "posts_alpha"},
+		softDelete:   true,
+	}
+
+	ctx := context.Background()
+	var posts []models.TransformedPost
+	for id, userID := range []int{7, 3, 7, 1, 3} {
+		post := newTestPost(id+1, "body")
+		post.UserID = userID
+		posts = append(posts, post)
+	}
+	alpha := newTestPost(10, "from alpha")
+	alpha.UserID = 9
+	alpha.Source = "alpha"
+	posts = append(posts, alpha)
+	deleted := newTestPost(11, "deleted")
+	deleted.UserID = 4
+	posts = append(posts, deleted)
+	assert.NoError(t, store.StorePosts(ctx, posts))
+	assert.NoError(t, store.DeletePost(ctx, 11))
+
+	// Test duplicates collapse and results are sorted
+	userIDs, err := store.GetUserIDs(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3, 7, 9}, userIDs)
+
+	// Test deleted posts are included on request
+	userIDs, err = store.GetUserIDs(WithDeleted(ctx))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3, 4, 7, 9}, userIDs)
+}
+
+func TestDynamoDBStorage_GetPostsByCategory(t *testing.T) {
+	// Create storage holding posts in two categories and one uncategorized
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	var posts []models.TransformedPost
+	for i, category := range []string{"food", "news", "food", "", "food"} {
+		post := newTestPost(i+1, "body")
+		post.Category = category
+		post.IngestedAt = base.Add(time.Duration(i) * time.Minute)
+		posts = append(posts, post)
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test only posts in the category are returned
+	result, err := store.GetPostsByCategory(ctx, "food", 10, 0)
+
+	assert.NoError(t, err)
+	if assert.Len(t, result, 3) {
+		assert.Equal(t, 1, result[0].ID)
+		assert.Equal(t, 3, result[1].ID)
+		assert.Equal(t, 5, result[2].ID)
+	}
+	for _, post := range result {
+		assert.Equal(t, "food", post.Category)
+	}
+
+	// Test an unknown category
+	result, err = store.GetPostsByCategory(ctx, "sports", 10, 0)
+
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestDynamoDBStorage_CompressBodies(t *testing.T) {
+	// Create storage with compression and offloading enabled
+	mockDB := NewMockDynamoDB()
+	mockS3 := NewMockS3()
+	store := &DynamoDBStorage{
+		client:           mockDB,
+		tableName:        "posts",
+		s3Client:         mockS3,
+		offloadBucket:    "bodies",
+		offloadThreshold: 128,
+		codec:            &bodyCodec{threshold: 64},
+	}
+
+	largeBody := strings.Repeat("repetitive body text ", 50)
+	hugeBody := strings.Repeat("abcdefghijklmnopqrstuvwxyz0123456789", 2000)
+	posts := []models.TransformedPost{
+		newTestPost(1, "short body"),
+		newTestPost(2, largeBody),
+		newTestPost(3, hugeBody),
+	}
+
+	// Test StorePosts compresses only the large bodies
+	ctx := context.Background()
+	err := store.StorePosts(ctx, posts)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "short body", aws.StringValue(mockDB.tables["posts"]["1"]["body"].S))
+	assert.Nil(t, mockDB.tables["posts"]["1"]["body_encoding"])
+
+	stored := mockDB.tables["posts"]["2"]
+	assert.Equal(t, gzipEncoding, aws.StringValue(stored["body_encoding"].S))
+	assert.Less(t, len(aws.StringValue(stored["body"].S)), len(largeBody))
+	assert.Equal(t, largeBody, posts[1].Body, "caller's posts must not be modified")
+
+	// The huge body is still over the offload threshold once compressed
+	assert.Len(t, mockS3.objects, 1)
+	assert.Equal(t, gzipEncoding, aws.StringValue(mockDB.tables["posts"]["3"]["body_encoding"].S))
+
+	// Test GetPostByID decompresses the body
+	post, err := store.GetPostByID(ctx, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, largeBody, post.Body)
+	assert.Empty(t, post.BodyEncoding)
+
+	// Test GetPosts decompresses bodies, including ones offloaded after compression
+	all, err := store.GetPosts(ctx, 10, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "short body", all[0].Body)
+	assert.Equal(t, largeBody, all[1].Body)
+	assert.Equal(t, hugeBody, all[2].Body)
+	assert.Empty(t, all[2].BodyEncoding)
+
+	// Test compressed posts remain readable once compression is disabled
+	store.codec = nil
+	post, err = store.GetPostByID(ctx, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, largeBody, post.Body)
+}
+
+func TestDynamoDBStorage_Comments(t *testing.T) {
+	// Create storage with a mock that pages results
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 10
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	var comments []models.Comment
+	for id := 1; id <= 30; id++ {
+		comments = append(comments, models.Comment{PostID: 1 + id%2, ID: id, Name: "Comment", Email: "a@example.com", Body: "Body " + strconv.Itoa(id)})
+	}
+
+	// Test StoreComments writes to the comments table in batches
+	ctx := context.Background()
+	err := store.StoreComments(ctx, comments)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{25, 5}, mockDB.batchWriteSizes)
+	assert.Len(t, mockDB.tables["posts_comments"], 30)
+	assert.Empty(t, mockDB.tables["posts"], "comments must not be stored with posts")
+
+	// Test GetComments returns only the post's comments, across pages, in ID order
+	got, err := store.GetComments(ctx, 2)
+
+	assert.NoError(t, err)
+	if assert.Len(t, got, 15) {
+		for i, comment := range got {
+			assert.Equal(t, 2, comment.PostID)
+			assert.Equal(t, 2*i+1, comment.ID)
+		}
+		assert.Equal(t, "Body 1", got[0].Body)
+	}
+
+	// Test a post without comments
+	got, err = store.GetComments(ctx, 99)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+}
+
+func TestDynamoDBStorage_GetLatestPost(t *testing.T) {
+	// Create storage routing one source to its own table
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:       mockDB,
+		tableName:    "posts",
+		sourceTables: map[string]string{"beta": "posts_beta"},
+		softDelete:   true,
+	}
+
+	// Test an empty store
+	ctx := context.Background()
+	latest, err := store.GetLatestPost(ctx)
+
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	// Seed posts ingested an hour apart, the newest in the source table
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	var posts []models.TransformedPost
+	for i := 0; i < 5; i++ {
+		post := newTestPost(i+1, "body")
+		post.IngestedAt = base.Add(time.Duration(i) * time.Hour)
+		posts = append(posts, post)
+	}
+	posts[3].Source = "beta"
+	assert.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test the newest post is returned, skipping a soft-deleted one
+	assert.NoError(t, store.DeletePost(ctx, 5))
+	latest, err = store.GetLatestPost(ctx)
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, latest) {
+		assert.Equal(t, 4, latest.ID)
+		assert.Equal(t, base.Add(3*time.Hour), latest.IngestedAt)
+	}
+}
+
+func TestDynamoDBStorage_VersionPosts(t *testing.T) {
+	// Create storage with versioning enabled
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:       mockDB,
+		tableName:    "posts",
+		versionPosts: true,
+	}
+	ctx := context.Background()
+
+	// Test each store of the same ID increments the version
+	for want := 1; want <= 3; want++ {
+		err := store.StorePosts(ctx, []models.TransformedPost{newTestPost(1, "body")})
+		assert.NoError(t, err)
+
+		post, err := store.GetPostByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, want, post.Version)
+	}
+
+	// Test a write based on a stale version is rejected
+	stale := newTestPost(1, "stale body")
+	stale.Version = 2
+	err := store.StorePosts(ctx, []models.TransformedPost{stale})
+
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	post, err := store.GetPostByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "body", post.Body)
+	assert.Equal(t, 3, post.Version)
+
+	// Test a write based on the current version succeeds
+	current := newTestPost(1, "updated body")
+	current.Version = 3
+	err = store.StorePosts(ctx, []models.TransformedPost{current})
+
+	assert.NoError(t, err)
+	post, err = store.GetPostByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "updated body", post.Body)
+	assert.Equal(t, 4, post.Version)
+}
+
+func TestDynamoDBStorage_StorePosts_Unversioned(t *testing.T) {
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{client: mockDB, tableName: "posts"}
+
+	// Test posts carry no version when versioning is disabled
+	err := store.StorePosts(context.Background(), []models.TransformedPost{newTestPost(1, "body")})
+
+	assert.NoError(t, err)
+	assert.Nil(t, mockDB.tables["posts"]["1"]["version"])
+}
+
+func TestDynamoDBStorage_GetPosts_ParallelScan(t *testing.T) {
+	// Create storage scanning in three segments, with small pages so each
+	// segment needs several calls
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 2
+	store := &DynamoDBStorage{
+		client:       mockDB,
+		tableName:    "posts",
+		scanSegments: 3,
+	}
+	ctx := context.Background()
+
+	var posts []models.TransformedPost
+	for id := 1; id <= 10; id++ {
+		posts = append(posts, newTestPost(id, "body"))
+	}
+	assert.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test paging through the combined segments returns every post once
+	seen := make(map[int]int)
+	for offset := 0; ; offset += 4 {
+		page, err := store.GetPosts(ctx, 4, offset)
+		assert.NoError(t, err)
+		for _, post := range page {
+			seen[post.ID]++
+		}
+		if len(page) < 4 {
+			break
+		}
+	}
+
+	assert.Len(t, seen, 10)
+	for id := 1; id <= 10; id++ {
+		assert.Equal(t, 1, seen[id], "post %d", id)
+	}
+	assert.Len(t, mockDB.scannedSegments, 3, "every segment should be scanned")
+
+	// Test a page past the end is empty
+	page, err := store.GetPosts(ctx, 4, 20)
+	assert.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func TestDynamoDBStorage_StorePosts_SkipsUnmarshallablePosts(t *testing.T) {
+	// Create storage whose marshaller rejects one category, standing in for a
+	// value of a type DynamoDB can't represent
+	mockDB := NewMockDynamoDB()
+	errUnsupported := errors.New("unsupported type")
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+		marshal: func(in interface{}) (map[string]*dynamodb.AttributeValue, error) {
+			switch v := in.(type) {
+			case models.TransformedPost:
+				if v.Category == "bad" {
+					return nil, errUnsupported
+				}
+			case map[string]interface{}:
+				if v["category"] == "bad" {
+					return nil, errUnsupported
+				}
+			}
+			return dynamodbattribute.MarshalMap(in)
+		},
+	}
+
+	bad := newTestPost(2, "body")
+	bad.Category = "bad"
+	posts := []models.TransformedPost{newTestPost(1, "body"), bad, newTestPost(3, "body")}
+
+	// Test StorePosts stores the rest of the batch and reports the skip
+	err := store.StorePosts(context.Background(), posts)
+
+	var skipped *SkippedPostsError
+	require.ErrorAs(t, err, &skipped)
+	require.Len(t, skipped.Posts, 1)
+	assert.Equal(t, 2, skipped.Posts[0].ID)
+	assert.Equal(t, "category", skipped.Posts[0].Field)
+	assert.ErrorIs(t, skipped.Posts[0].Err, errUnsupported)
+
+	assert.Contains(t, mockDB.tables["posts"], "1")
+	assert.NotContains(t, mockDB.tables["posts"], "2")
+	assert.Contains(t, mockDB.tables["posts"], "3")
+}
+
+func TestExpressionNames_FilterOnReservedWord(t *testing.T) {
+	// Store posts from two sources
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+	imported := newTestPost(2, "body")
+	imported.Source = "import"
+	ctx := context.Background()
+	require.NoError(t, store.StorePosts(ctx, []models.TransformedPost{newTestPost(1, "body"), imported}))
+
+	values := map[string]*dynamodb.AttributeValue{":source": {S: aws.String("import")}}
+
+	// Test filtering on source directly is rejected as a reserved word
+	_, err := mockDB.ScanWithContext(ctx, &dynamodb.ScanInput{
+		TableName:                 aws.String("posts"),
+		FilterExpression:          aws.String("source = :source"),
+		ExpressionAttributeValues: values,
+	})
+	assert.ErrorContains(t, err, "reserved keyword")
+
+	// Test the aliased filter is accepted and matches
+	names := expressionNames{}
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String("posts"),
+		ProjectionExpression:      names.projection("id", "source"),
+		FilterExpression:          names.equals("source", ":source"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+	assert.Equal(t, "#id, #source", aws.StringValue(input.ProjectionExpression))
+	assert.Equal(t, "#source = :source", aws.StringValue(input.FilterExpression))
+	require.NoError(t, input.Validate())
+
+	result, err := mockDB.ScanWithContext(ctx, input)
+
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "2", aws.StringValue(result.Items[0]["id"].N))
+}
+
+func TestDynamoDBStorage_GetPosts_StopsAtLimit(t *testing.T) {
+	// Create storage whose scans return at most 20 items per page
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 20
+	store := &DynamoDBStorage{
+		client:    mockDB,
+		tableName: "posts",
+	}
+
+	ctx := context.Background()
+	var posts []models.TransformedPost
+	for id := 1; id <= 200; id++ {
+		posts = append(posts, newTestPost(id, "body"))
+	}
+	require.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test exactly limit posts are collected, without scanning the rest of the table
+	result, err := store.GetPosts(ctx, 50, 0)
+
+	require.NoError(t, err)
+	require.Len(t, result, 50)
+	assert.Equal(t, 1, result[0].ID)
+	assert.Equal(t, 50, result[49].ID)
+	assert.Equal(t, 3, mockDB.scanCalls)
+}
+
+func TestDynamoDBStorage_OffloadBody_SharedID(t *testing.T) {
+	// Create storage offloading bodies of posts keyed by source and id
+	mockDB := NewMockDynamoDB()
+	mockDB.compositeKey = true
+	mockS3 := NewMockS3()
+	store := &DynamoDBStorage{
+		client:           mockDB,
+		tableName:        "posts",
+		compositeKey:     true,
+		s3Client:         mockS3,
+		offloadBucket:    "bodies",
+		offloadThreshold: 16,
+	}
+
+	fromA := newTestPost(1, strings.Repeat("a", 64))
+	fromA.Source = "source_a"
+	fromB := newTestPost(1, strings.Repeat("b", 64))
+	fromB.Source = "source_b"
+
+	// Test each source's body is offloaded and read back separately
+	ctx := context.Background()
+	require.NoError(t, store.StorePosts(ctx, []models.TransformedPost{fromA, fromB}))
+	assert.Len(t, mockS3.objects, 2)
+
+	for _, want := range []models.TransformedPost{fromA, fromB} {
+		post, err := store.GetPostByID(WithSource(ctx, want.Source), 1)
+		require.NoError(t, err)
+		require.NotNil(t, post)
+		assert.Equal(t, want.Body, post.Body, want.Source)
+	}
+}
+
+func TestDynamoDBStorage_CompositeKey(t *testing.T) {
+	// Create storage keying posts by source and id
+	mockDB := NewMockDynamoDB()
+	mockDB.compositeKey = true
+	store := &DynamoDBStorage{
+		client:       mockDB,
+		tableName:    "posts",
+		compositeKey: true,
+	}
+
+	fromA := newTestPost(1, "from a")
+	fromA.Source = "source_a"
+	fromB := newTestPost(1, "from b")
+	fromB.Source = "source_b"
+
+	// Test posts from two sources with the same ID coexist
+	ctx := context.Background()
+	require.NoError(t, store.StorePosts(ctx, []models.TransformedPost{fromA, fromB}))
+	assert.Len(t, mockDB.tables["posts"], 2)
+
+	post, err := store.GetPostByID(WithSource(ctx, "source_a"), 1)
+	require.NoError(t, err)
+	require.NotNil(t, post)
+	assert.Equal(t, "from a", post.Body)
+
+	post, err = store.GetPostByID(WithSource(ctx, "source_b"), 1)
+	require.NoError(t, err)
+	require.NotNil(t, post)
+	assert.Equal(t, "from b", post.Body)
+
+	// Test addressing a post by ID alone is refused
+	_, err = store.GetPostByID(ctx, 1)
+	assert.ErrorIs(t, err, ErrSourceRequired)
+	assert.ErrorIs(t, store.DeletePost(ctx, 1), ErrSourceRequired)
+
+	// Test deletes only touch the addressed source
+	deleted, err := store.DeletePosts(WithSource(ctx, "source_a"), []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	post, err = store.GetPostByID(WithSource(ctx, "source_b"), 1)
+	require.NoError(t, err)
+	assert.NotNil(t, post)
+}
+
+func TestDynamoDBStorage_GetPostsByRunID(t *testing.T) {
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 2
+	store := &DynamoDBStorage{client: mockDB, tableName: "posts"}
+	ctx := context.Background()
+
+	// Store two runs' posts, interleaved across scan pages
+	var posts []models.TransformedPost
+	for id := 1; id <= 6; id++ {
+		post := newTestPost(id, "body")
+		post.RunID = "run-" + strconv.Itoa(id%2)
+		posts = append(posts, post)
+	}
+	require.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test only the run's posts are returned, paged like the other reads
+	found, err := store.GetPostsByRunID(ctx, "run-1", 10, 0)
+	require.NoError(t, err)
+	ids := make([]int, len(found))
+	for i, post := range found {
+		ids[i] = post.ID
+		assert.Equal(t, "run-1", post.RunID)
+	}
+	assert.Equal(t, []int{1, 3, 5}, ids)
+
+	found, err = store.GetPostsByRunID(ctx, "run-1", 1, 1)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, 3, found[0].ID)
+}
+
+func TestDynamoDBStorage_ExportPosts_AsOf(t *testing.T) {
+	mockDB := NewMockDynamoDB()
+	mockDB.scanPageSize = 2
+	store := &DynamoDBStorage{client: mockDB, tableName: "posts"}
+	ctx := context.Background()
+
+	snapshot := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	posts := testPosts(1, 2, 3, 4)
+	for i := range posts {
+		posts[i].IngestedAt = snapshot.Add(-time.Hour)
+	}
+	require.NoError(t, store.StorePosts(ctx, posts))
+
+	// Test a post re-stored mid-export with a later IngestedAt is still
+	// exported, and one stored for the first time after the snapshot isn't
+	var ids []int
+	err := store.ExportPosts(ctx, snapshot, func(post models.TransformedPost) error {
+		if post.ID == 1 {
+			later := testPosts(3, 5)
+			for i := range later {
+				later[i].IngestedAt = snapshot.Add(time.Hour)
+			}
+			require.NoError(t, store.StorePosts(ctx, later))
+		}
+		ids = append(ids, post.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4}, ids)
+
+	// Test every post is exported without a snapshot
+	ids = nil
+	require.NoError(t, store.ExportPosts(ctx, time.Time{}, func(post models.TransformedPost) error {
+		ids = append(ids, post.ID)
+		return nil
+	}))
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ids)
+}
+
+func TestDynamoDBStorage_StorePosts_Restore(t *testing.T) {
+	mockDB := NewMockDynamoDB()
+	store := &DynamoDBStorage{client: mockDB, tableName: "posts"}
+	ctx := context.Background()
+
+	first := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	post := testPosts(1)[0]
+	post.IngestedAt = first
+	post.Category = "news"
+	require.NoError(t, store.StorePosts(ctx, []models.TransformedPost{post}))
+
+	post.IngestedAt = first.Add(time.Hour)
+	post.Category = ""
+	require.NoError(t, store.StorePosts(ctx, []models.TransformedPost{post}))
+
+	// Test the re-store keeps the first ingestion time and drops the
+	// attributes the post no longer has, without reading the item first
+	item := mockDB.tables["posts"]["1"]
+	assert.Equal(t, strconv.FormatInt(first.UnixNano(), 10), aws.StringValue(item["first_ingested_ts"].N))
+	assert.Equal(t, strconv.FormatInt(first.Add(time.Hour).UnixNano(), 10), aws.StringValue(item["ingested_ts"].N))
+	assert.Nil(t, item["category"])
+	assert.Zero(t, mockDB.getItemCalls)
+}
+
+func TestDynamoDBStorage_MetricsHistory(t *testing.T) {
+	store := &DynamoDBStorage{client: NewMockDynamoDB(), tableName: "posts"}
+	ctx := context.Background()
+
+	// Record snapshots out of order, next to the status record
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for _, minutes := range []int{10, 30, 20} {
+		snapshot := models.MetricsSnapshot{Time: base.Add(time.Duration(minutes) * time.Minute), RecordsIn: minutes, RecordsOut: minutes}
+		require.NoError(t, store.RecordMetricsSnapshot(ctx, snapshot))
+	}
+	require.NoError(t, store.UpdateIngestionStatus(ctx, models.IngestionStatus{Status: "success"}))
+
+	// Test the newest snapshots come first, without the status record
+	snapshots, err := store.GetMetricsHistory(ctx, 2)
+
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, 30, snapshots[0].RecordsIn)
+	assert.Equal(t, 20, snapshots[1].RecordsIn)
+	assert.True(t, snapshots[0].Time.Equal(base.Add(30*time.Minute)))
+
+	// Test the status record is unaffected
+	status, err := store.GetIngestionStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "success", status.Status)
+}
+
+func TestDynamoDBStorage_ensureTable_AddsMissingIndexes(t *testing.T) {
+	// A table created before the category index was introduced
+	mockDB := NewMockDynamoDB()
+	mockDB.existing = map[string]*dynamodb.TableDescription{
+		"posts": {GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{IndexName: aws.String(ingestedAtIndex), IndexStatus: aws.String(dynamodb.IndexStatusActive)},
+		}},
+	}
+	store := &DynamoDBStorage{client: mockDB, tableName: "posts"}
+
+	require.NoError(t, store.ensureTable("posts"))
+	assert.Empty(t, mockDB.created)
+	require.Len(t, mockDB.updated, 1)
+	require.Len(t, mockDB.updated[0].GlobalSecondaryIndexUpdates, 1)
+	assert.Equal(t, categoryIndex, aws.StringValue(mockDB.updated[0].GlobalSecondaryIndexUpdates[0].Create.IndexName))
+
+	// Test a table with every index is left alone
+	require.NoError(t, store.ensureTable("posts"))
+	assert.Len(t, mockDB.updated, 1)
+}
+
+func TestDynamoDBStorage_ensureTable_CompositeKey(t *testing.T) {
+	for _, composite := range []bool{false, true} {
+		mockDB := NewMockDynamoDB()
+		store := &DynamoDBStorage{client: mockDB, tableName: "posts", compositeKey: composite}
+
+		require.NoError(t, store.ensureTable("posts"))
+		require.Len(t, mockDB.created, 1)
+
+		var schema []string
+		for _, element := range mockDB.created[0].KeySchema {
+			schema = append(schema, aws.StringValue(element.AttributeName)+":"+aws.StringValue(element.KeyType))
+		}
+		if composite {
+			assert.Equal(t, []string{"source:HASH", "id:RANGE"}, schema)
+		} else {
+			assert.Equal(t, []string{"id:HASH"}, schema)
+		}
+	}
+}
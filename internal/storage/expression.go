@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// expressionNames collects the attribute name placeholders of a DynamoDB
+// request. Names like source and status are reserved words that can't
+// appear in an expression as-is, so every name goes through a placeholder
+// rather than only the reserved ones, sparing callers the reserved word
+// list.
+type expressionNames map[string]*string
+
+// alias registers name and returns its placeholder
+func (n expressionNames) alias(name string) string {
+	placeholder := "#" + name
+	n[placeholder] = aws.String(name)
+	return placeholder
+}
+
+// projection returns a ProjectionExpression selecting the given attributes
+func (n expressionNames) projection(names ...string) *string {
+	aliases := make([]string, len(names))
+	for i, name := range names {
+		aliases[i] = n.alias(name)
+	}
+	return aws.String(strings.Join(aliases, ", "))
+}
+
+// equals returns a FilterExpression matching items whose attribute equals
+// the value bound to placeholder
+func (n expressionNames) equals(name, placeholder string) *string {
+	return aws.String(n.alias(name) + " = " + placeholder)
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// LimitedStorage bounds the number of concurrent operations on a Storage.
+// Reads and writes share one pool of slots, but writes may hold at most
+// their weighted share of it, so a large ingestion burst always leaves
+// slots free for API reads.
+type LimitedStorage struct {
+	next   Storage
+	slots  chan struct{} // Held by every operation
+	writes chan struct{} // Additionally held by writes
+}
+
+// NewLimitedStorage wraps next, allowing maxConcurrent operations at once of
+// which writes may take writeWeight/(readWeight+writeWeight). Writes always
+// get at least one slot, and reads keep at least one unless readWeight is 0.
+func NewLimitedStorage(next Storage, maxConcurrent, readWeight, writeWeight int) *LimitedStorage {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+
+	writeSlots := maxConcurrent
+	if readWeight > 0 && maxConcurrent > 1 {
+		writeSlots = maxConcurrent * writeWeight / (readWeight + writeWeight)
+		if writeSlots < 1 {
+			writeSlots = 1
+		}
+		if writeSlots > maxConcurrent-1 {
+			writeSlots = maxConcurrent - 1
+		}
+	}
+
+	return &LimitedStorage{
+		next:   next,
+		slots:  make(chan struct{}, maxConcurrent),
+		writes: make(chan struct{}, writeSlots),
+	}
+}
+
+// read waits for a slot, returning a function releasing it
+func (l *LimitedStorage) read(ctx context.Context) (func(), error) {
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// write waits for a write slot and then a shared slot, returning a function
+// releasing both
+func (l *LimitedStorage) write(ctx context.Context) (func(), error) {
+	select {
+	case l.writes <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	release, err := l.read(ctx)
+	if err != nil {
+		<-l.writes
+		return nil, err
+	}
+	return func() {
+		release()
+		<-l.writes
+	}, nil
+}
+
+// StorePosts stores posts once a write slot is free
+func (l *LimitedStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	release, err := l.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.StorePosts(ctx, posts)
+}
+
+// GetPosts retrieves posts once a slot is free
+func (l *LimitedStorage) GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetPosts(ctx, limit, offset)
+}
+
+// GetPostsByIngestionRange retrieves posts once a slot is free
+func (l *LimitedStorage) GetPostsByIngestionRange(ctx context.Context, from, to time.Time, limit int, offset int) ([]models.TransformedPost, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetPostsByIngestionRange(ctx, from, to, limit, offset)
+}
+
+// GetPostsByCategory retrieves posts once a slot is free
+func (l *LimitedStorage) GetPostsByCategory(ctx context.Context, category string, limit int, offset int) ([]models.TransformedPost, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetPostsByCategory(ctx, category, limit, offset)
+}
+
+// GetPostsByRunID retrieves posts once a slot is free
+func (l *LimitedStorage) GetPostsByRunID(ctx context.Context, runID string, limit int, offset int) ([]models.TransformedPost, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetPostsByRunID(ctx, runID, limit, offset)
+}
+
+// ExportPosts exports posts once a slot is free, holding it throughout
+func (l *LimitedStorage) ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error {
+	release, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.ExportPosts(ctx, asOf, fn)
+}
+
+// GetPostByID retrieves a post once a slot is free
+func (l *LimitedStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetPostByID(ctx, id)
+}
+
+// GetLatestPost retrieves the latest post once a slot is free
+func (l *LimitedStorage) GetLatestPost(ctx context.Context) (*models.TransformedPost, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetLatestPost(ctx)
+}
+
+// GetUserIDs retrieves user IDs once a slot is free
+func (l *LimitedStorage) GetUserIDs(ctx context.Context) ([]int, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetUserIDs(ctx)
+}
+
+// StoreComments stores comments once a write slot is free
+func (l *LimitedStorage) StoreComments(ctx context.Context, comments []models.Comment) error {
+	release, err := l.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.StoreComments(ctx, comments)
+}
+
+// GetComments retrieves comments once a slot is free
+func (l *LimitedStorage) GetComments(ctx context.Context, postID int) ([]models.Comment, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetComments(ctx, postID)
+}
+
+// DeletePost deletes a post once a write slot is free
+func (l *LimitedStorage) DeletePost(ctx context.Context, id int) error {
+	release, err := l.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.DeletePost(ctx, id)
+}
+
+// DeletePosts deletes posts once a write slot is free
+func (l *LimitedStorage) DeletePosts(ctx context.Context, ids []int) (int, error) {
+	release, err := l.write(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return l.next.DeletePosts(ctx, ids)
+}
+
+// UpdateIngestionStatus updates the status once a write slot is free
+func (l *LimitedStorage) UpdateIngestionStatus(ctx context.Context, status models.IngestionStatus) error {
+	release, err := l.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.UpdateIngestionStatus(ctx, status)
+}
+
+// GetIngestionStatus retrieves the status once a slot is free
+func (l *LimitedStorage) GetIngestionStatus(ctx context.Context) (*models.IngestionStatus, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetIngestionStatus(ctx)
+}
+
+// RecordMetricsSnapshot stores the snapshot once a write slot is free
+func (l *LimitedStorage) RecordMetricsSnapshot(ctx context.Context, snapshot models.MetricsSnapshot) error {
+	release, err := l.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.RecordMetricsSnapshot(ctx, snapshot)
+}
+
+// GetMetricsHistory retrieves the snapshots once a slot is free
+func (l *LimitedStorage) GetMetricsHistory(ctx context.Context, limit int) ([]models.MetricsSnapshot, error) {
+	release, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.GetMetricsHistory(ctx, limit)
+}
+
+// Close closes the wrapped storage
+func (l *LimitedStorage) Close() error {
+	return l.next.Close()
+}
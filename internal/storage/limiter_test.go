@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// blockingStorage holds every write until release is closed
+type blockingStorage struct {
+	*StdoutSink
+	release chan struct{}
+
+	mu       sync.Mutex
+	inflight int
+	peak     int
+}
+
+func (b *blockingStorage) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	b.mu.Lock()
+	b.inflight++
+	b.peak = max(b.peak, b.inflight)
+	b.mu.Unlock()
+
+	<-b.release
+
+	b.mu.Lock()
+	b.inflight--
+	b.mu.Unlock()
+	return nil
+}
+
+func TestNewLimitedStorage_Shares(t *testing.T) {
+	tests := []struct {
+		name                    string
+		max, reads, writes      int
+		wantSlots, wantWriteCap int
+	}{
+		{"even", 4, 1, 1, 4, 2},
+		{"write heavy", 4, 1, 3, 4, 3},
+		{"write share rounds up to one", 10, 99, 1, 10, 1},
+		{"reads keep a slot", 2, 1, 99, 2, 1},
+		{"no read weight", 3, 0, 1, 3, 3},
+		{"single slot", 0, 1, 1, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limited := NewLimitedStorage(nil, tt.max, tt.reads, tt.writes)
+
+			assert.Equal(t, tt.wantSlots, cap(limited.slots))
+			assert.Equal(t, tt.wantWriteCap, cap(limited.writes))
+		})
+	}
+}
+
+func TestLimitedStorage_WriteBurstDoesNotBlockReads(t *testing.T) {
+	backend := &blockingStorage{StdoutSink: NewStdoutSink(io.Discard), release: make(chan struct{})}
+	limited := NewLimitedStorage(backend, 4, 1, 1)
+
+	// Start a burst of writes far larger than the write share
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			assert.NoError(t, limited.StorePosts(context.Background(), []models.TransformedPost{newTestPost(id, "body")}))
+		}(i)
+	}
+
+	assert.Eventually(t, func() bool {
+		backend.mu.Lock()
+		defer backend.mu.Unlock()
+		return backend.inflight == 2
+	}, time.Second, time.Millisecond)
+
+	// Test reads proceed while writes hold their full share
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 5; i++ {
+		_, err := limited.GetPosts(ctx, 10, 0)
+		assert.NoError(t, err)
+	}
+
+	// Test further writes wait until a write slot frees up
+	_, err := limited.write(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(backend.release)
+	wg.Wait()
+
+	assert.Equal(t, 2, backend.peak, "writes must never exceed their share")
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// Migrate copies every post, including soft-deleted ones, from src to dst in
+// batches of batchSize. src is read in a single export pass, as paging it by
+// offset would rescan every post before each page. progress, if non-nil, is
+// called with the running total after each batch. It returns the number of
+// posts copied.
+func Migrate(ctx context.Context, src, dst Storage, batchSize int, progress func(copied int)) (int, error) {
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("invalid migration batch size: %d", batchSize)
+	}
+	ctx = WithDeleted(ctx)
+
+	copied := 0
+	batch := make([]models.TransformedPost, 0, batchSize)
+	var writeErr error
+	flush := func() error {
+		if err := dst.StorePosts(ctx, batch); err != nil {
+			writeErr = fmt.Errorf("failed to write posts after %d copied: %w", copied, err)
+			return writeErr
+		}
+		copied += len(batch)
+		batch = make([]models.TransformedPost, 0, batchSize) // dst may hold on to the last one
+		if progress != nil {
+			progress(copied)
+		}
+		return nil
+	}
+
+	err := src.ExportPosts(ctx, time.Time{}, func(post models.TransformedPost) error {
+		batch = append(batch, post)
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if writeErr != nil {
+		return copied, writeErr
+	}
+	if err != nil {
+		return copied, fmt.Errorf("failed to read posts after %d copied: %w", copied, err)
+	}
+
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
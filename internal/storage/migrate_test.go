@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// memoryStorage serves posts from a slice, for use as a migration source
+type memoryStorage struct {
+	*StdoutSink
+	posts []models.TransformedPost
+	err   error
+}
+
+func (m *memoryStorage) ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, post := range m.posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMigrate_MemoryToFile(t *testing.T) {
+	src := &memoryStorage{StdoutSink: NewStdoutSink(io.Discard)}
+	for id := 1; id <= 7; id++ {
+		src.posts = append(src.posts, newTestPost(id, "body"))
+	}
+	src.posts[3].Deleted = true
+
+	path := filepath.Join(t.TempDir(), "posts.jsonl")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	dst := NewStdoutSink(file)
+
+	var progress []int
+	copied, err := Migrate(context.Background(), src, dst, 3, func(n int) {
+		progress = append(progress, n)
+	})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	assert.Equal(t, 7, copied)
+	assert.Equal(t, []int{3, 6, 7}, progress)
+
+	file, err = os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var migrated []models.TransformedPost
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var post models.TransformedPost
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &post))
+		migrated = append(migrated, post)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, migrated, 7)
+	for i, post := range migrated {
+		assert.Equal(t, src.posts[i].ID, post.ID)
+		assert.Equal(t, src.posts[i].Title, post.Title)
+		assert.Equal(t, src.posts[i].Body, post.Body)
+		assert.Equal(t, src.posts[i].Deleted, post.Deleted)
+	}
+}
+
+func TestMigrate_SourceError(t *testing.T) {
+	src := &memoryStorage{StdoutSink: NewStdoutSink(io.Discard), err: errors.New("unavailable")}
+
+	copied, err := Migrate(context.Background(), src, NewStdoutSink(io.Discard), 10, nil)
+
+	assert.Zero(t, copied)
+	assert.ErrorContains(t, err, "unavailable")
+}
+
+func TestMigrate_DestinationError(t *testing.T) {
+	src := &memoryStorage{StdoutSink: NewStdoutSink(io.Discard), posts: testPosts(1, 2, 3)}
+	dst := newRecordingStorage()
+	dst.err = errors.New("read-only")
+
+	copied, err := Migrate(context.Background(), src, dst, 2, nil)
+
+	// Test the write failure is reported as such, not as a failed read
+	assert.Zero(t, copied)
+	assert.ErrorContains(t, err, "failed to write posts")
+	assert.ErrorContains(t, err, "read-only")
+}
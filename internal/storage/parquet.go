@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// Parquet physical types, converted types, and encodings from parquet.thrift
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+	parquetJSON            = 19
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+// parquetColumn is a column of the archived post schema. value returns an
+// int64, string, or bool, or nil for a null in an optional column.
+type parquetColumn struct {
+	name      string
+	kind      int32
+	converted int32 // -1 for none
+	optional  bool
+	value     func(post models.TransformedPost) any
+}
+
+// postColumns is the Parquet schema of a transformed post, in the column
+// layout of TransformedPost.Flatten
+var postColumns = []parquetColumn{
+	{"user_id", parquetInt64, -1, false, func(p models.TransformedPost) any { return int64(p.UserID) }},
+	{"id", parquetInt64, -1, false, func(p models.TransformedPost) any { return int64(p.ID) }},
+	{"title", parquetByteArray, parquetUTF8, false, func(p models.TransformedPost) any { return p.Title }},
+	{"body", parquetByteArray, parquetUTF8, false, func(p models.TransformedPost) any { return p.Body }},
+	{"created_at", parquetInt64, parquetTimestampMillis, true, func(p models.TransformedPost) any {
+		if p.CreatedAt == nil {
+			return nil
+		}
+		return p.CreatedAt.UnixMilli()
+	}},
+	{"ingested_at", parquetInt64, parquetTimestampMillis, false, func(p models.TransformedPost) any { return p.IngestedAt.UnixMilli() }},
+	{"source", parquetByteArray, parquetUTF8, false, func(p models.TransformedPost) any { return p.Source }},
+	{"original_title", parquetByteArray, parquetUTF8, true, func(p models.TransformedPost) any { return nullIfEmpty(p.OriginalTitle) }},
+	{"category", parquetByteArray, parquetUTF8, true, func(p models.TransformedPost) any { return nullIfEmpty(p.Category) }},
+	{"body_ref", parquetByteArray, parquetUTF8, true, func(p models.TransformedPost) any { return nullIfEmpty(p.BodyRef) }},
+	{"body_encoding", parquetByteArray, parquetUTF8, true, func(p models.TransformedPost) any { return nullIfEmpty(p.BodyEncoding) }},
+	{"version", parquetInt64, -1, false, func(p models.TransformedPost) any { return int64(p.Version) }},
+	{"run_id", parquetByteArray, parquetUTF8, true, func(p models.TransformedPost) any { return nullIfEmpty(p.RunID) }},
+	{"deleted", parquetBoolean, -1, false, func(p models.TransformedPost) any { return p.Deleted }},
+	{"deleted_at", parquetInt64, parquetTimestampMillis, true, func(p models.TransformedPost) any {
+		if p.DeletedAt == nil {
+			return nil
+		}
+		return p.DeletedAt.UnixMilli()
+	}},
+	{"user", parquetByteArray, parquetJSON, true, func(p models.TransformedPost) any {
+		if p.User == nil {
+			return nil
+		}
+		data, err := json.Marshal(p.User)
+		if err != nil {
+			return nil
+		}
+		return string(data)
+	}},
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// encodeParquet writes posts as an uncompressed Parquet file with a single
+// row group, holding one PLAIN-encoded data page per column
+func encodeParquet(posts []models.TransformedPost) []byte {
+	type chunk struct {
+		offset int64
+		size   int64
+	}
+
+	var out bytes.Buffer
+	out.WriteString("PAR1")
+	chunks := make([]chunk, len(postColumns))
+	for i, column := range postColumns {
+		page := column.encodePage(posts)
+
+		var header thriftWriter
+		header.begin()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5)
+		header.i32(1, int32(len(posts)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunks[i] = chunk{offset: int64(out.Len()), size: int64(header.buf.Len() + len(page))}
+		out.Write(header.buf.Bytes())
+		out.Write(page)
+	}
+
+	var footer thriftWriter
+	footer.begin()
+	footer.i32(1, 1) // Format version
+	footer.list(2, thriftStruct, len(postColumns)+1)
+	footer.begin()
+	footer.binary(4, "schema")
+	footer.i32(5, int32(len(postColumns)))
+	footer.end()
+	for _, column := range postColumns {
+		footer.begin()
+		footer.i32(1, column.kind)
+		if column.optional {
+			footer.i32(3, 1)
+		} else {
+			footer.i32(3, 0)
+		}
+		footer.binary(4, column.name)
+		if column.converted >= 0 {
+			footer.i32(6, column.converted)
+		}
+		footer.end()
+	}
+	footer.i64(3, int64(len(posts)))
+
+	footer.list(4, thriftStruct, 1)
+	footer.begin()
+	footer.list(1, thriftStruct, len(postColumns))
+	var total int64
+	for i, column := range postColumns {
+		footer.begin()
+		footer.i64(2, chunks[i].offset)
+		footer.structField(3)
+		footer.i32(1, column.kind)
+		footer.list(2, thriftI32, 2)
+		footer.varint(zigzag(parquetPlain))
+		footer.varint(zigzag(parquetRLE))
+		footer.list(3, thriftBinary, 1)
+		footer.varint(uint64(len(column.name)))
+		footer.buf.WriteString(column.name)
+		footer.i32(4, 0) // UNCOMPRESSED
+		footer.i64(5, int64(len(posts)))
+		footer.i64(6, chunks[i].size)
+		footer.i64(7, chunks[i].size)
+		footer.i64(9, chunks[i].offset)
+		footer.end()
+		footer.end()
+		total += chunks[i].size
+	}
+	footer.i64(2, total)
+	footer.i64(3, int64(len(posts)))
+	footer.end()
+
+	footer.binary(6, "data-ingestion-service")
+	footer.end()
+
+	out.Write(footer.buf.Bytes())
+	out.Write(binary.LittleEndian.AppendUint32(nil, uint32(footer.buf.Len())))
+	out.WriteString("PAR1")
+	return out.Bytes()
+}
+
+// encodePage returns the column's data page: the definition levels of an
+// optional column, then its non-null values
+func (c parquetColumn) encodePage(posts []models.TransformedPost) []byte {
+	var values bytes.Buffer
+	levels := make([]byte, 0, len(posts))
+	var bits []bool
+	for _, post := range posts {
+		v := c.value(post)
+		if v == nil {
+			levels = append(levels, 0)
+			continue
+		}
+		levels = append(levels, 1)
+
+		switch v := v.(type) {
+		case int64:
+			values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case string:
+			values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			values.WriteString(v)
+		case bool:
+			bits = append(bits, v)
+		}
+	}
+	if bits != nil {
+		packed := make([]byte, (len(bits)+7)/8)
+		for i, bit := range bits {
+			if bit {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		values.Write(packed)
+	}
+
+	if !c.optional {
+		return values.Bytes()
+	}
+	encoded := encodeLevels(levels)
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(encoded)))
+	page = append(page, encoded...)
+	return append(page, values.Bytes()...)
+}
+
+// encodeLevels run-length encodes definition levels of bit width 1
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for start := 0; start < len(levels); {
+		end := start
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+		out = binary.AppendUvarint(out, uint64(end-start)<<1)
+		out = append(out, levels[start])
+		start = end
+	}
+	return out
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Thrift compact protocol structs of Parquet
+// metadata
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // Last field ID written in each open struct
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) field(id int16, kind byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		t.buf.WriteByte(kind)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+// begin starts a struct, at the top level or as a list element
+func (t *thriftWriter) begin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// list starts a list field; its size elements are written after it
+func (t *thriftWriter) list(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.varint(uint64(size))
+	}
+}
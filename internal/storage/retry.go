@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// RetryingStorage retries reads that fail with a transient backend error,
+// such as DynamoDB throttling, with exponential backoff. Writes pass through
+// unchanged.
+type RetryingStorage struct {
+	Storage
+	retries int
+	backoff time.Duration
+}
+
+// NewRetryingStorage wraps next, retrying GetPosts and GetPostByID up to
+// retries times, waiting backoff before the first retry and doubling it after
+func NewRetryingStorage(next Storage, retries int, backoff time.Duration) *RetryingStorage {
+	return &RetryingStorage{Storage: next, retries: retries, backoff: backoff}
+}
+
+// IsTransient reports whether err is a backend error that may succeed when
+// retried, e.g. a throttled or 5xx AWS request
+func IsTransient(err error) bool {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() >= 500 {
+		return true
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	return request.IsErrorThrottle(aerr) || request.IsErrorRetryable(aerr) ||
+		aerr.Code() == dynamodb.ErrCodeInternalServerError
+}
+
+// GetPosts retries transient failures of the wrapped GetPosts
+func (r *RetryingStorage) GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
+	return retryRead(ctx, r, func() ([]models.TransformedPost, error) {
+		return r.Storage.GetPosts(ctx, limit, offset)
+	})
+}
+
+// GetPostByID retries transient failures of the wrapped GetPostByID
+func (r *RetryingStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
+	return retryRead(ctx, r, func() (*models.TransformedPost, error) {
+		return r.Storage.GetPostByID(ctx, id)
+	})
+}
+
+// retryRead calls read until it succeeds, fails with a non-transient error,
+// runs out of retries or ctx is done
+func retryRead[T any](ctx context.Context, r *RetryingStorage, read func() (T, error)) (T, error) {
+	delay := r.backoff
+	for attempt := 0; ; attempt++ {
+		result, err := read()
+		if err == nil || attempt >= r.retries || !IsTransient(err) {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
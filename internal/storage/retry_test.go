@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// flakyStorage fails its first failures reads with err
+type flakyStorage struct {
+	*StdoutSink
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyStorage) read() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *flakyStorage) GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
+	if err := f.read(); err != nil {
+		return nil, err
+	}
+	return []models.TransformedPost{{Post: models.Post{ID: 1}}}, nil
+}
+
+func (f *flakyStorage) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
+	if err := f.read(); err != nil {
+		return nil, err
+	}
+	return &models.TransformedPost{Post: models.Post{ID: id}}, nil
+}
+
+func throttled() error {
+	return awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "slow down", nil)
+}
+
+func TestRetryingStorage_RetriesTransientReads(t *testing.T) {
+	flaky := &flakyStorage{StdoutSink: NewStdoutSink(io.Discard), failures: 1, err: throttled()}
+	store := NewRetryingStorage(flaky, 3, time.Millisecond)
+
+	posts, err := store.GetPosts(context.Background(), 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Equal(t, 2, flaky.calls)
+
+	flaky.calls = 0
+	post, err := store.GetPostByID(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, post.ID)
+	assert.Equal(t, 2, flaky.calls)
+}
+
+func TestRetryingStorage_GivesUp(t *testing.T) {
+	// Test retries are bounded
+	flaky := &flakyStorage{StdoutSink: NewStdoutSink(io.Discard), failures: 10, err: throttled()}
+	_, err := NewRetryingStorage(flaky, 2, time.Millisecond).GetPosts(context.Background(), 10, 0)
+	assert.True(t, IsTransient(err))
+	assert.Equal(t, 3, flaky.calls)
+
+	// Test other errors aren't retried
+	flaky = &flakyStorage{StdoutSink: NewStdoutSink(io.Discard), failures: 1, err: ErrNotFound}
+	_, err = NewRetryingStorage(flaky, 2, time.Millisecond).GetPostByID(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 1, flaky.calls)
+
+	// Test a cancelled request stops waiting to retry
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	flaky = &flakyStorage{StdoutSink: NewStdoutSink(io.Discard), failures: 1, err: throttled()}
+	_, err = NewRetryingStorage(flaky, 2, time.Hour).GetPosts(ctx, 10, 0)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 1, flaky.calls)
+}
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, IsTransient(throttled()))
+	assert.True(t, IsTransient(fmt.Errorf("failed to scan posts: %w", throttled())))
+	assert.True(t, IsTransient(awserr.New(dynamodb.ErrCodeInternalServerError, "oops", nil)))
+	assert.True(t, IsTransient(awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "down", nil), 503, "req-1")))
+	assert.False(t, IsTransient(awserr.New(dynamodb.ErrCodeResourceNotFoundException, "no table", nil)))
+	assert.False(t, IsTransient(ErrNotFound))
+	assert.False(t, IsTransient(nil))
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+// StdoutSink writes each post as a JSON line, for piping into other tools.
+// It keeps nothing, so reads return no posts. Logs go to stderr and are
+// never interleaved with the output.
+type StdoutSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	events  *cloudEvents // Wraps each line in a CloudEvents envelope when set
+	status  *models.IngestionStatus
+	history []models.MetricsSnapshot // The last stdoutHistorySize snapshots, oldest first
+}
+
+// stdoutHistorySize is how many metrics snapshots a StdoutSink keeps
+const stdoutHistorySize = 100
+
+// NewStdoutSink creates a sink writing to out, normally os.Stdout
+func NewStdoutSink(out io.Writer) *StdoutSink {
+	return &StdoutSink{encoder: json.NewEncoder(out)}
+}
+
+// NewCloudEventsSink creates a sink writing each post and comment to out as
+// a CloudEvents 1.0 JSON envelope with the configured source and types
+func NewCloudEventsSink(out io.Writer, cfg config.StorageConfig) *StdoutSink {
+	sink := NewStdoutSink(out)
+	sink.events = &cloudEvents{
+		source:      cfg.EventSource,
+		postType:    cfg.PostEventType,
+		commentType: cfg.CommentEventType,
+	}
+	return sink
+}
+
+// StorePosts writes one JSON line per post
+func (s *StdoutSink) StorePosts(ctx context.Context, posts []models.TransformedPost) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, post := range posts {
+		var line any = post
+		if s.events != nil {
+			line = s.events.post(post)
+		}
+		if err := s.encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to write post %d: %w", post.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetPosts returns no posts; the sink doesn't retain them
+func (s *StdoutSink) GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error) {
+	return []models.TransformedPost{}, nil
+}
+
+// GetPostsByIngestionRange returns no posts; the sink doesn't retain them
+func (s *StdoutSink) GetPostsByIngestionRange(ctx context.Context, from, to time.Time, limit int, offset int) ([]models.TransformedPost, error) {
+	return []models.TransformedPost{}, nil
+}
+
+// GetPostsByCategory returns no posts; the sink doesn't retain them
+func (s *StdoutSink) GetPostsByCategory(ctx context.Context, category string, limit int, offset int) ([]models.TransformedPost, error) {
+	return []models.TransformedPost{}, nil
+}
+
+// GetPostsByRunID returns no posts; the sink doesn't retain them
+func (s *StdoutSink) GetPostsByRunID(ctx context.Context, runID string, limit int, offset int) ([]models.TransformedPost, error) {
+	return []models.TransformedPost{}, nil
+}
+
+// ExportPosts exports no posts; the sink doesn't retain them
+func (s *StdoutSink) ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error {
+	return nil
+}
+
+// GetPostByID never finds a post
+func (s *StdoutSink) GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error) {
+	return nil, nil
+}
+
+// GetLatestPost never finds a post
+func (s *StdoutSink) GetLatestPost(ctx context.Context) (*models.TransformedPost, error) {
+	return nil, nil
+}
+
+// GetUserIDs returns no users; the sink doesn't retain posts
+func (s *StdoutSink) GetUserIDs(ctx context.Context) ([]int, error) {
+	return []int{}, nil
+}
+
+// StoreComments writes one JSON line per comment
+func (s *StdoutSink) StoreComments(ctx context.Context, comments []models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, comment := range comments {
+		var line any = comment
+		if s.events != nil {
+			line = s.events.comment(comment)
+		}
+		if err := s.encoder.Encode(line); err != nil {
+			return fmt.Errorf("failed to write comment %d: %w", comment.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetComments returns no comments; the sink doesn't retain them
+func (s *StdoutSink) GetComments(ctx context.Context, postID int) ([]models.Comment, error) {
+	return []models.Comment{}, nil
+}
+
+// DeletePost always reports ErrNotFound
+func (s *StdoutSink) DeletePost(ctx context.Context, id int) error {
+	return ErrNotFound
+}
+
+// DeletePosts deletes nothing
+func (s *StdoutSink) DeletePosts(ctx context.Context, ids []int) (int, error) {
+	return 0, nil
+}
+
+// UpdateIngestionStatus keeps the latest status in memory
+func (s *StdoutSink) UpdateIngestionStatus(ctx context.Context, status models.IngestionStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = &status
+	return nil
+}
+
+// GetIngestionStatus returns the latest status, or a default one if no
+// cycle has run yet
+func (s *StdoutSink) GetIngestionStatus(ctx context.Context) (*models.IngestionStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status == nil {
+		return &models.IngestionStatus{Status: "never_run"}, nil
+	}
+	status := *s.status
+	return &status, nil
+}
+
+// RecordMetricsSnapshot keeps the snapshot in memory, dropping the oldest
+// once stdoutHistorySize are kept
+func (s *StdoutSink) RecordMetricsSnapshot(ctx context.Context, snapshot models.MetricsSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, snapshot)
+	if len(s.history) > stdoutHistorySize {
+		s.history = s.history[len(s.history)-stdoutHistorySize:]
+	}
+	return nil
+}
+
+// GetMetricsHistory returns up to limit kept snapshots, newest first
+func (s *StdoutSink) GetMetricsHistory(ctx context.Context, limit int) ([]models.MetricsSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.history)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	snapshots := make([]models.MetricsSnapshot, 0, count)
+	for i := len(s.history) - 1; len(snapshots) < count; i-- {
+		snapshots = append(snapshots, s.history[i])
+	}
+	return snapshots, nil
+}
+
+// Close is a no-op
+func (s *StdoutSink) Close() error {
+	return nil
+}
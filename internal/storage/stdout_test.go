@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+	"github.com/cyderes/data-ingestion-service/internal/models"
+)
+
+func TestStdoutSink_StorePosts(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewStdoutSink(&out)
+
+	// Test storing two batches
+	err := sink.StorePosts(context.Background(), []models.TransformedPost{newTestPost(1, "first"), newTestPost(2, "second")})
+	assert.NoError(t, err)
+	err = sink.StorePosts(context.Background(), []models.TransformedPost{newTestPost(3, "third")})
+	assert.NoError(t, err)
+
+	scanner := bufio.NewScanner(&out)
+	var bodies []string
+	for scanner.Scan() {
+		var post models.TransformedPost
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &post))
+		bodies = append(bodies, post.Body)
+	}
+
+	assert.Equal(t, []string{"first", "second", "third"}, bodies)
+}
+
+func TestStdoutSink_Status(t *testing.T) {
+	sink := NewStdoutSink(&bytes.Buffer{})
+
+	status, err := sink.GetIngestionStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "never_run", status.Status)
+
+	err = sink.UpdateIngestionStatus(context.Background(), models.IngestionStatus{Status: "success", RecordsIngested: 3})
+	assert.NoError(t, err)
+
+	status, err = sink.GetIngestionStatus(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "success", status.Status)
+	assert.Equal(t, 3, status.RecordsIngested)
+}
+
+func TestStdoutSink_Comments(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewStdoutSink(&out)
+
+	err := sink.StoreComments(context.Background(), []models.Comment{{PostID: 1, ID: 7, Body: "Nice post"}})
+	assert.NoError(t, err)
+
+	var comment models.Comment
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &comment))
+	assert.Equal(t, 7, comment.ID)
+
+	comments, err := sink.GetComments(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Empty(t, comments)
+}
+
+func TestCloudEventsSink_StorePosts(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewCloudEventsSink(&out, config.StorageConfig{
+		EventSource:      "/ingestion/test",
+		PostEventType:    "com.example.post",
+		CommentEventType: "com.example.comment",
+	})
+	post := newTestPost(7, "body")
+
+	err := sink.StorePosts(context.Background(), []models.TransformedPost{post})
+	assert.NoError(t, err)
+	err = sink.StoreComments(context.Background(), []models.Comment{{PostID: 7, ID: 3, Body: "comment", IngestedAt: post.IngestedAt}})
+	assert.NoError(t, err)
+
+	scanner := bufio.NewScanner(&out)
+
+	// Test the post is wrapped in an envelope with the required attributes
+	assert.True(t, scanner.Scan())
+	var event struct {
+		SpecVersion     string                 `json:"specversion"`
+		ID              string                 `json:"id"`
+		Source          string                 `json:"source"`
+		Type            string                 `json:"type"`
+		Subject         string                 `json:"subject"`
+		Time            time.Time              `json:"time"`
+		DataContentType string                 `json:"datacontenttype"`
+		Data            models.TransformedPost `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+	assert.Equal(t, "1.0", event.SpecVersion)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, "/ingestion/test", event.Source)
+	assert.Equal(t, "com.example.post", event.Type)
+	assert.Equal(t, "posts/7", event.Subject)
+	assert.True(t, post.IngestedAt.Equal(event.Time))
+	assert.Equal(t, "application/json", event.DataContentType)
+	assert.Equal(t, 7, event.Data.ID)
+	assert.Equal(t, "body", event.Data.Body)
+
+	// Test comments are wrapped with their own type
+	assert.True(t, scanner.Scan())
+	var comment map[string]any
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &comment))
+	assert.Equal(t, "com.example.comment", comment["type"])
+	assert.Equal(t, "posts/7/comments/3", comment["subject"])
+	assert.NotEqual(t, event.ID, comment["id"])
+}
+
+func TestCloudEventsSink_StableIDs(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewCloudEventsSink(&out, config.StorageConfig{})
+	post := newTestPost(1, "body")
+
+	// Test redelivering a post reuses its event ID
+	assert.NoError(t, sink.StorePosts(context.Background(), []models.TransformedPost{post, post}))
+
+	var ids []string
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event map[string]any
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event["id"].(string))
+	}
+	if assert.Len(t, ids, 2) {
+		assert.Equal(t, ids[0], ids[1])
+	}
+}
@@ -2,24 +2,78 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/cyderes/data-ingestion-service/internal/config"
 	"github.com/cyderes/data-ingestion-service/internal/models"
 )
 
+// ErrNotFound is returned when an operation targets a post that doesn't exist
+var ErrNotFound = errors.New("post not found")
+
+// ErrVersionConflict is returned when a versioned write is based on a post
+// version that has since been superseded
+var ErrVersionConflict = errors.New("post version conflict")
+
+// ErrSourceRequired is returned when a post is addressed by ID alone but the
+// backend keys posts by source and ID; see WithSource
+var ErrSourceRequired = errors.New("source is required to address a post by ID")
+
+// ErrMisconfigured is returned by NewStorage when the selected type is
+// missing a setting it requires. Retrying can't help, so NewStorageWithRetry
+// gives up at once.
+var ErrMisconfigured = errors.New("storage is misconfigured")
+
+// SkippedPost is a post left out of a write because it couldn't be marshalled
+type SkippedPost struct {
+	ID     int
+	Source string // Needed alongside ID to identify the post under composite keys
+	Field  string // JSON name of the offending field, empty if it couldn't be determined
+	Err    error
+}
+
+// SkippedPostsError is returned by StorePosts when some posts couldn't be
+// marshalled. Every other post in the batch was stored.
+type SkippedPostsError struct {
+	Posts []SkippedPost
+}
+
+func (e *SkippedPostsError) Error() string {
+	return fmt.Sprintf("skipped %d posts that failed to marshal", len(e.Posts))
+}
+
 // Storage interface defines the contract for data storage
 type Storage interface {
 	StorePosts(ctx context.Context, posts []models.TransformedPost) error
 	GetPosts(ctx context.Context, limit int, offset int) ([]models.TransformedPost, error)
+	GetPostsByIngestionRange(ctx context.Context, from, to time.Time, limit int, offset int) ([]models.TransformedPost, error)
+	GetPostsByCategory(ctx context.Context, category string, limit int, offset int) ([]models.TransformedPost, error)
+	GetPostsByRunID(ctx context.Context, runID string, limit int, offset int) ([]models.TransformedPost, error)
+	ExportPosts(ctx context.Context, asOf time.Time, fn func(post models.TransformedPost) error) error
 	GetPostByID(ctx context.Context, id int) (*models.TransformedPost, error)
+	GetLatestPost(ctx context.Context) (*models.TransformedPost, error)
+	GetUserIDs(ctx context.Context) ([]int, error)
+	StoreComments(ctx context.Context, comments []models.Comment) error
+	GetComments(ctx context.Context, postID int) ([]models.Comment, error)
+	DeletePost(ctx context.Context, id int) error
+	DeletePosts(ctx context.Context, ids []int) (int, error)
 	UpdateIngestionStatus(ctx context.Context, status models.IngestionStatus) error
 	GetIngestionStatus(ctx context.Context) (*models.IngestionStatus, error)
+	RecordMetricsSnapshot(ctx context.Context, snapshot models.MetricsSnapshot) error
+	GetMetricsHistory(ctx context.Context, limit int) ([]models.MetricsSnapshot, error)
 	Close() error
 }
 
 // NewStorage creates a new storage instance based on configuration
 func NewStorage(cfg config.StorageConfig) (Storage, error) {
+	if err := validateConfig(cfg); err != nil {
+		return nil, err
+	}
+
 	switch cfg.Type {
 	case "dynamodb":
 		return NewDynamoDBStorage(cfg)
@@ -27,7 +81,93 @@ func NewStorage(cfg config.StorageConfig) (Storage, error) {
 		return NewMongoDBStorage(cfg)
 	case "postgresql":
 		return NewPostgreSQLStorage(cfg)
+	case "stdout":
+		if cfg.SinkFormat == cloudEventsFormat {
+			return NewCloudEventsSink(os.Stdout, cfg), nil
+		}
+		return NewStdoutSink(os.Stdout), nil
 	default:
 		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
 	}
-}
\ No newline at end of file
+}
+
+// validateConfig checks the settings the selected type can't connect without
+// are set, so a missing one fails clearly rather than as a connection error
+func validateConfig(cfg config.StorageConfig) error {
+	var setting, value string
+	switch cfg.Type {
+	case "dynamodb":
+		setting, value = "TABLE_NAME", cfg.TableName
+	case "mongodb":
+		setting, value = "MONGODB_URI", cfg.MongoDBURI
+	case "postgresql":
+		setting, value = "POSTGRES_URI", cfg.PostgresURI
+	default:
+		return nil
+	}
+
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("%w: %s storage requires %s to be set", ErrMisconfigured, cfg.Type, setting)
+	}
+	return nil
+}
+
+// Constructor builds a Storage from configuration, e.g. NewStorage
+type Constructor func(cfg config.StorageConfig) (Storage, error)
+
+// NewStorageWithRetry calls newStorage, retrying up to cfg.InitRetries times
+// with exponential backoff so a backend that starts alongside the service
+// doesn't fail startup
+func NewStorageWithRetry(ctx context.Context, cfg config.StorageConfig, newStorage Constructor) (Storage, error) {
+	delay := cfg.InitBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= cfg.InitRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		store, err := newStorage(cfg)
+		if err == nil {
+			return store, nil
+		}
+		if errors.Is(err, ErrMisconfigured) {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("failed to initialize storage after %d attempts: %w", cfg.InitRetries+1, lastErr)
+}
+
+type includeDeletedKey struct{}
+
+// WithDeleted returns a context under which reads also return soft-deleted posts
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// IncludesDeleted reports whether soft-deleted posts should be returned
+func IncludesDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return include
+}
+
+type sourceKey struct{}
+
+// WithSource returns a context under which lookups and deletes by ID address
+// the post from source. Backends keying posts by source and ID require it.
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the source set by WithSource, or "" if none is
+func SourceFrom(ctx context.Context) string {
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
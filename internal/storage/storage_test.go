@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cyderes/data-ingestion-service/internal/config"
+)
+
+// flakyConstructor fails the first failures calls
+func flakyConstructor(failures int, calls *int) Constructor {
+	return func(cfg config.StorageConfig) (Storage, error) {
+		*calls++
+		if *calls <= failures {
+			return nil, errors.New("connection refused")
+		}
+		return &DynamoDBStorage{tableName: cfg.TableName}, nil
+	}
+}
+
+func TestNewStorageWithRetry_TransientFailures(t *testing.T) {
+	cfg := config.StorageConfig{TableName: "posts", InitRetries: 3, InitBackoff: time.Millisecond}
+
+	calls := 0
+	store, err := NewStorageWithRetry(context.Background(), cfg, flakyConstructor(2, &calls))
+
+	assert.NoError(t, err)
+	assert.NotNil(t, store)
+	assert.Equal(t, 3, calls)
+}
+
+func TestNewStorageWithRetry_GivesUp(t *testing.T) {
+	cfg := config.StorageConfig{InitRetries: 2, InitBackoff: time.Millisecond}
+
+	calls := 0
+	store, err := NewStorageWithRetry(context.Background(), cfg, flakyConstructor(10, &calls))
+
+	assert.Error(t, err)
+	assert.Nil(t, store)
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Equal(t, 3, calls)
+}
+
+func TestNewStorage_MissingSettings(t *testing.T) {
+	tests := []struct {
+		cfg     config.StorageConfig
+		setting string
+	}{
+		{config.StorageConfig{Type: "dynamodb"}, "TABLE_NAME"},
+		{config.StorageConfig{Type: "mongodb", TableName: "posts"}, "MONGODB_URI"},
+		{config.StorageConfig{Type: "postgresql", TableName: "posts", PostgresURI: "  "}, "POSTGRES_URI"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cfg.Type, func(t *testing.T) {
+			// Test the missing setting is named before any connection is tried
+			store, err := NewStorage(tt.cfg)
+
+			assert.ErrorIs(t, err, ErrMisconfigured)
+			assert.Nil(t, store)
+			assert.Contains(t, err.Error(), tt.setting)
+		})
+	}
+}
+
+func TestNewStorageWithRetry_Misconfigured(t *testing.T) {
+	cfg := config.StorageConfig{Type: "mongodb", InitRetries: 3, InitBackoff: time.Hour}
+
+	// Test a configuration error isn't retried
+	_, err := NewStorageWithRetry(context.Background(), cfg, NewStorage)
+
+	assert.ErrorIs(t, err, ErrMisconfigured)
+}
+
+func TestNewStorageWithRetry_Cancelled(t *testing.T) {
+	cfg := config.StorageConfig{InitRetries: 3, InitBackoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	_, err := NewStorageWithRetry(ctx, cfg, flakyConstructor(10, &calls))
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
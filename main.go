@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/cyderes/data-ingestion-service/internal/config"
 	"github.com/cyderes/data-ingestion-service/internal/ingestion"
 	"github.com/cyderes/data-ingestion-service/internal/server"
@@ -15,24 +20,90 @@ import (
 )
 
 func main() {
+	// Flags follow the subcommand, if any
+	args := os.Args[1:]
+	command := ""
+	if len(args) > 0 && args[0] == "migrate" {
+		command, args = args[0], args[1:]
+	}
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadArgs(args)
 	if err != nil {
 		log.Fatal("Failed to load configuration:", err)
 	}
+	for _, warning := range cfg.Warnings {
+		log.Printf("Configuration warning: %v", warning)
+	}
+
+	if command == "migrate" {
+		if err := migrate(cfg); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		return
+	}
 
 	// Initialize storage
-	store, err := storage.NewStorage(cfg.Storage)
+	store, err := storage.NewStorageWithRetry(context.Background(), cfg.Storage, storage.NewStorage)
 	if err != nil {
 		log.Fatal("Failed to initialize storage:", err)
 	}
 	defer store.Close()
+	if cfg.Storage.AuditSink != "" {
+		auditSink, err := storage.NewAuditSink(cfg.Storage)
+		if err != nil {
+			log.Fatal("Failed to initialize audit sink:", err)
+		}
+		store = storage.NewAuditedStorage(store, auditSink)
+	}
+	if cfg.Storage.MaxConcurrency > 0 {
+		store = storage.NewLimitedStorage(store, cfg.Storage.MaxConcurrency, cfg.Storage.ReadWeight, cfg.Storage.WriteWeight)
+	}
+	if cfg.Storage.ReadRetryCount > 0 {
+		// Outside the limiter, so a read waiting to retry doesn't hold a slot
+		store = storage.NewRetryingStorage(store, cfg.Storage.ReadRetryCount, cfg.Storage.ReadRetryBackoff)
+	}
+	var buffered *storage.BufferedStorage
+	if cfg.Storage.WriteBufferSize > 0 {
+		buffered = storage.NewBufferedStorage(store, cfg.Storage.WriteBufferSize, cfg.Storage.WriteBufferInterval, cfg.Storage.CompositeKey)
+		store = buffered
+	}
 
-	// Initialize ingestion service
-	ingestor := ingestion.NewService(cfg.Ingestion, store)
+	// Initialize ingestion service, clearing cached API responses whenever
+	// new posts arrive
+	registry := prometheus.NewRegistry()
+	var httpServer *server.Server
+	ingestionOpts := []ingestion.Option{
+		ingestion.WithMetrics(registry),
+		ingestion.WithAfterIngest(func(int) {
+			httpServer.InvalidateCache()
+		}),
+	}
+	if cfg.Ingestion.TransformScript != "" {
+		// Running without a script meant to drop or rewrite posts would
+		// store them unfiltered
+		script, err := ingestion.LoadScript(cfg.Ingestion.TransformScript, slog.Default())
+		if err != nil {
+			log.Fatal("Failed to load transform script:", err)
+		}
+		ingestionOpts = append(ingestionOpts, ingestion.WithTransformers(script))
+	}
+	if cfg.Storage.ArchiveBucket != "" {
+		archiver, err := storage.NewRunArchiver(cfg.Storage)
+		if err != nil {
+			log.Fatal("Failed to initialize run archiver:", err)
+		}
+		ingestionOpts = append(ingestionOpts, ingestion.WithRunArchiver(archiver))
+	}
+	ingestor := ingestion.NewService(cfg.Ingestion, store, ingestionOpts...)
 
 	// Initialize HTTP server for API endpoints
-	httpServer := server.NewServer(cfg.Server, store)
+	httpServer = server.NewServer(cfg.Server, store,
+		server.WithMetrics(registry),
+		server.WithIngestor(ingestor),
+		server.WithCategories(cfg.Ingestion.Categories()),
+		server.WithHashAlgorithm(cfg.Ingestion.HashAlgorithm),
+	)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -51,16 +122,43 @@ func main() {
 	}()
 
 	// Start ingestion service
+	ingestionDone := make(chan error, 1)
 	go func() {
 		log.Println("Starting data ingestion service")
-		if err := ingestor.Start(ctx); err != nil {
+		err := ingestor.Start(ctx)
+		if err != nil {
 			log.Printf("Ingestion service error: %v", err)
 		}
+		ingestionDone <- err
 	}()
 
-	// Wait for shutdown signal
-	<-sigChan
-	log.Println("Shutdown signal received, gracefully shutting down...")
+	// Wait for shutdown signal, for a fixed-count job to finish, or for the
+	// dead-man switch to trip. Otherwise the API keeps serving after
+	// ingestion stops.
+	var jobErr error
+	ingestionStopped := false
+	done := ingestionDone
+wait:
+	for {
+		select {
+		case <-sigChan:
+			log.Println("Shutdown signal received, gracefully shutting down...")
+			break wait
+		case err := <-done:
+			ingestionStopped = true
+			done = nil
+			if errors.Is(err, ingestion.ErrStale) {
+				jobErr = err
+				log.Printf("Dead-man switch tripped, exiting: %v", err)
+				break wait
+			}
+			if cfg.Ingestion.MaxCycles > 0 {
+				jobErr = err
+				log.Println("Ingestion cycles complete, shutting down...")
+				break wait
+			}
+		}
+	}
 
 	// Create shutdown context with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -72,5 +170,54 @@ func main() {
 	}
 
 	cancel() // Cancel ingestion context
+
+	// Let the cycle in progress finish before flushing and closing storage
+	if !ingestionStopped {
+		select {
+		case <-ingestionDone:
+		case <-shutdownCtx.Done():
+			log.Println("Ingestion did not stop before the shutdown timeout")
+		}
+	}
+	if buffered != nil {
+		if err := buffered.Flush(shutdownCtx); err != nil {
+			log.Printf("Write buffer flush error: %v", err)
+		}
+	}
+	if err := ingestor.PushMetrics(shutdownCtx, registry); err != nil {
+		log.Printf("Metrics push error: %v", err)
+	}
 	log.Println("Shutdown complete")
-}
\ No newline at end of file
+	if jobErr != nil {
+		store.Close() // Deferred calls don't run on os.Exit
+		os.Exit(1)
+	}
+}
+
+// migrate copies every post from the configured storage to the migration target
+func migrate(cfg *config.Config) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	src, err := storage.NewStorageWithRetry(ctx, cfg.Storage, storage.NewStorage)
+	if err != nil {
+		return fmt.Errorf("failed to initialize source storage: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := storage.NewStorageWithRetry(ctx, cfg.MigrateTarget, storage.NewStorage)
+	if err != nil {
+		return fmt.Errorf("failed to initialize target storage: %w", err)
+	}
+	defer dst.Close()
+
+	log.Printf("Migrating posts from %s to %s", cfg.Storage.Type, cfg.MigrateTarget.Type)
+	copied, err := storage.Migrate(ctx, src, dst, cfg.MigrateBatchSize, func(copied int) {
+		log.Printf("Migrated %d posts", copied)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Migration complete: %d posts copied", copied)
+	return nil
+}